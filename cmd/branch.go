@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
@@ -13,40 +15,167 @@ import (
 
 func init() {
 	rootCmd.AddCommand(newBranchCmd)
+	branchesCmd.Flags().Bool("merged", false, "List the branches fully merged into the base branch (safe to delete) instead of opening the TUI")
+	branchesCmd.Flags().Bool("no-merged", false, "List the branches with commits not in the base branch instead of opening the TUI")
+	branchesCmd.Flags().String("into", "", "Base branch for --merged/--no-merged and for p in the TUI (defaults to config base_branch, then the repo's primary branch)")
+	branchesCmd.MarkFlagsMutuallyExclusive("merged", "no-merged")
 	rootCmd.AddCommand(branchesCmd)
 
 	switchBranchCmd.Flags().BoolP("remote", "r", false, "Include remote branches in the branch list")
 	rootCmd.AddCommand(switchBranchCmd)
 
-	featureCmd.Flags().StringP("origin", "o", "", "The branch to pull latest changes from before creating the feature branch (defaults to repo's primary branch)")
+	featureCmd.Flags().StringP("origin", "o", "", "The branch to pull latest changes from before creating the feature branch (defaults to config base_branch, then the repo's primary branch)")
 	featureCmd.Flags().StringP("new", "n", "", "The name of the new feature branch")
 	featureCmd.Flags().BoolP("close", "c", false, "The name of the branch to close after creating the new feature branch")
+	featureCmd.Flags().BoolP("force", "f", false, "When closing, force-delete the feature branch even if git does not consider it merged")
 	rootCmd.AddCommand(featureCmd)
+	rootCmd.AddCommand(syncAllCmd)
+	rootCmd.AddCommand(setUpstreamCmd)
+	copyBranchCmd.Flags().BoolP("remote", "r", false, "Copy the upstream ref instead, e.g. origin/feature")
+	rootCmd.AddCommand(copyBranchCmd)
+	defaultBranchCmd.Flags().Bool("auto", false, "Ask origin for its default branch and use that")
+	rootCmd.AddCommand(defaultBranchCmd)
+}
+
+var defaultBranchCmd = &cobra.Command{
+	Use:   "default-branch [branch]",
+	Short: "Show or change the detected default branch",
+	Long: "Print the branch cgit treats as the repo's primary branch: the one origin/HEAD points at, " +
+		"else the first of main, master and trunk that exists. With a branch name, point origin/HEAD at " +
+		"origin/<branch>; with --auto, ask origin which branch is its default. Config base_branch, when set, " +
+		"still wins wherever cgit needs a base branch.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		auto, _ := cmd.Flags().GetBool("auto")
+		if auto && len(args) > 0 {
+			return fail("setting default branch", errors.New("give a branch name or --auto, not both"))
+		}
+
+		if auto || len(args) > 0 {
+			branch := ""
+			if len(args) > 0 {
+				branch = args[0]
+			}
+			err := repo.SetDefaultBranch(branch)
+			if errors.Is(err, git.ErrNoSuchRemoteBranch) {
+				err = fmt.Errorf("%w; fetch or push it first", err)
+			}
+			if err != nil {
+				return fail("setting default branch", err)
+			}
+		}
+
+		branch, err := repo.DefaultBranch()
+		if err != nil {
+			return fail("detecting default branch", err)
+		}
+		fmt.Printf("Default branch: %s\n", branch)
+		if base := config.Load().BaseBranch; base != "" && base != branch {
+			fmt.Printf("Config base_branch is %s, which cgit uses instead.\n", base)
+		}
+		return nil
+	},
+}
+
+var copyBranchCmd = &cobra.Command{
+	Use:   "copy-branch",
+	Short: "Copy the current branch name to the clipboard",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		remote, _ := cmd.Flags().GetBool("remote")
+
+		name, err := ui.CopyBranchName(repo, remote)
+		if errors.Is(err, git.ErrNoUpstream) {
+			err = fmt.Errorf("%w; push with 'cgit push -u' to set one", err)
+		}
+		if err != nil {
+			return fail("copying branch name", err)
+		}
+		fmt.Printf("Copied %s to the clipboard.\n", name)
+		return nil
+	},
 }
 
 var newBranchCmd = &cobra.Command{
-	Use:     "new-branch",
+	Use:     "new-branch <name>",
 	Aliases: []string{"nb"},
+	Args:    requireArg("branch name"),
 	Short:   "Create and switch to a new branch",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 
 		branchName := args[0]
 		err := repo.CreateBranch(branchName)
-		HandleError("creating branch", err, true)
+		if err != nil {
+			return fail("creating branch", err)
+		}
 
 		err = repo.SwitchBranch(branchName)
-		HandleError("switching branch", err, true)
+		if err != nil {
+			return fail("switching branch", err)
+		}
 
 		fmt.Printf("Successfully created and switched to branch '%s'.\n", branchName)
+		return nil
+	},
+}
+
+var setUpstreamCmd = &cobra.Command{
+	Use:   "set-upstream <remote>/<branch>",
+	Short: "Change the branch the current branch tracks",
+	Long: "Make the current branch track an existing remote branch, e.g. after renaming it or pushing it " +
+		"under a different name. A bare branch name means origin/<branch>.",
+	Args: requireArg("remote branch"),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		branches, err := git.New(".").RemoteBranches()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return branches, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		remoteRef := args[0]
+		if !strings.Contains(remoteRef, "/") {
+			remoteRef = "origin/" + remoteRef
+		}
+
+		err := repo.SetUpstream(remoteRef)
+		if errors.Is(err, git.ErrNoSuchRemoteBranch) {
+			err = fmt.Errorf("%w; fetch first if it is new, or push the branch with 'cgit push -u'", err)
+		}
+		if err != nil {
+			return fail("setting upstream", err)
+		}
+
+		branch, _ := repo.GetCurrentBranch()
+		ahead, behind, err := repo.GetAheadBehind()
+		if err != nil {
+			fmt.Printf("%s now tracks %s.\n", branch, remoteRef)
+			return nil
+		}
+		fmt.Printf("%s now tracks %s (ahead %d, behind %d).\n", branch, remoteRef, ahead, behind)
+		return nil
 	},
 }
 
 var switchBranchCmd = &cobra.Command{
-	Use:     "switch",
+	Use:     "switch [branch]",
 	Aliases: []string{"sw"},
+	Args:    cobra.MaximumNArgs(1),
 	Short:   "Switch to an existing branch",
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// Only the first argument is a branch name.
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
 		repo := git.New(".")
 		remote, err := cmd.Flags().GetBool("remote")
 
@@ -62,23 +191,29 @@ var switchBranchCmd = &cobra.Command{
 
 		return branches, cobra.ShellCompDirectiveNoFileComp
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 		branchName := ""
 
 		remote, err := cmd.Flags().GetBool("remote")
-		HandleError("Getting remote flag", err, true)
+		if err != nil {
+			return fail("Getting remote flag", err)
+		}
 
 		if len(args) == 1 {
 			branchName = args[0]
 			isClean, err := repo.IsClean()
-			HandleError("checking repository status", err, true)
+			if err != nil {
+				return fail("checking repository status", err)
+			}
 
 			if !isClean {
 				fmt.Println("You need to stash or delete your changes before swapping. Press 'd' to delete changes or 's' to enter a stash name")
 				reader := bufio.NewReader(os.Stdin)
 				input, err := reader.ReadString('\n')
-				HandleError("reading stash name", err, true)
+				if err != nil {
+					return fail("reading stash name", err)
+				}
 
 				input = strings.TrimSpace(input)
 				var stashName string
@@ -86,105 +221,261 @@ var switchBranchCmd = &cobra.Command{
 				switch input {
 				case "d":
 					err = repo.FullClean()
-					HandleError("deleting changes", err, true)
+					if err != nil {
+						return fail("deleting changes", err)
+					}
 					fmt.Println("Changes deleted.")
 				case "s":
 					_, err = reader.Discard(0)
-					HandleError("discarding input", err, true)
+					if err != nil {
+						return fail("discarding input", err)
+					}
 
 					fmt.Print("Enter stash name: ")
 					stashName, err = reader.ReadString('\n')
-					HandleError("reading stash name", err, true)
+					if err != nil {
+						return fail("reading stash name", err)
+					}
 
 					stashName = strings.TrimSpace(stashName)
 					if stashName == "" {
 						fmt.Println("No stash name provided. Aborting switch.")
-						return
+						return nil
 					}
 
 					err = repo.Stash(stashName)
-					HandleError("stashing changes", err, true)
+					if err != nil {
+						return fail("stashing changes", err)
+					}
 
 					fmt.Printf("Changes stashed as '%s'.\n", stashName)
 				}
 			}
 
 			err = repo.SwitchBranch(branchName)
-			HandleError("switching branches", err, true)
+			if err != nil {
+				return fail("switching branches", err)
+			}
 			fmt.Printf("Successfully switched to branch '%s'.\n", branchName)
 		} else {
-			_, err := ui.SwitchBranches(repo, remote)
-			HandleError("switching branches", err, true)
+			branch, err := ui.SwitchBranches(repo, remote)
+			if err != nil {
+				return fail("switching branches", err)
+			}
+			if branch != "" {
+				fmt.Printf("Successfully switched to branch '%s'.\n", branch)
+			}
 		}
+		return nil
 	},
+	Annotations: needsTerminal,
 }
 
 var branchesCmd = &cobra.Command{
 	Use:     "branches",
 	Aliases: []string{"br"},
 	Short:   "Browse and manage branches in an interactive TUI",
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: "Browse and manage branches in an interactive TUI. Press p to select every branch merged into the base branch, " +
+		"then x to delete them. --merged and --no-merged print the lists instead.",
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
-		err := ui.StartBranchManager(repo)
-		HandleError("managing branches", err, true)
+
+		into, _ := cmd.Flags().GetString("into")
+		if into == "" {
+			into = baseBranch(repo)
+		}
+
+		merged, _ := cmd.Flags().GetBool("merged")
+		unmerged, _ := cmd.Flags().GetBool("no-merged")
+		switch {
+		case merged:
+			branches, err := repo.MergedBranches(into)
+			if err != nil {
+				return fail("listing merged branches", err)
+			}
+			printBranches(fmt.Sprintf("Merged into %s (safe to delete):", into), fmt.Sprintf("No branches are fully merged into %s.", into), branches)
+		case unmerged:
+			branches, err := repo.UnmergedBranches(into)
+			if err != nil {
+				return fail("listing unmerged branches", err)
+			}
+			printBranches(fmt.Sprintf("Not merged into %s:", into), fmt.Sprintf("Every branch is merged into %s.", into), branches)
+		default:
+			err := ui.StartBranchManager(repo, into)
+			if err != nil {
+				return fail("managing branches", err)
+			}
+		}
+		return nil
+	},
+	Annotations: needsTerminal,
+}
+
+func printBranches(heading, empty string, branches []string) {
+	if len(branches) == 0 {
+		fmt.Println(empty)
+		return
+	}
+	fmt.Println(heading)
+	for _, b := range branches {
+		fmt.Println("  " + b)
+	}
+}
+
+// baseBranch is the branch feature work starts from and merges back into:
+// config base_branch, else the repo's primary branch.
+func baseBranch(repo *git.GitRepo) string {
+	if base := config.Load().BaseBranch; base != "" {
+		return base
+	}
+	return repo.GetDefaultBranch()
+}
+
+var syncAllCmd = &cobra.Command{
+	Use:   "sync-all",
+	Short: "Fast-forward every local branch to its upstream",
+	Long: "Fetch all remotes, then fast-forward each local branch that is behind its upstream, " +
+		"without checking it out. Branches with commits of their own are left untouched and listed as diverged.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		results, err := repo.SyncAllBranches()
+		if err != nil {
+			return fail("syncing branches", err)
+		}
+
+		var advanced, diverged, failed, noUpstream int
+		for _, r := range results {
+			switch r.Outcome {
+			case git.SyncAdvanced:
+				advanced++
+				fmt.Printf("  \033[32m↑\033[0m %s: fast-forwarded %d commit(s) from %s\n", r.Branch, r.Behind, r.Upstream)
+			case git.SyncDiverged:
+				diverged++
+				fmt.Printf("  \033[33m!\033[0m %s: diverged from %s (%d ahead, %d behind), left untouched\n", r.Branch, r.Upstream, r.Ahead, r.Behind)
+			case git.SyncFailed:
+				failed++
+				fmt.Printf("  \033[31m✗\033[0m %s: %v\n", r.Branch, strings.TrimSpace(r.Err.Error()))
+			case git.SyncNoUpstream:
+				noUpstream++
+				fmt.Printf("  - %s: no upstream\n", r.Branch)
+			}
+		}
+		fmt.Printf("%d advanced, %d diverged, %d failed, %d without upstream, %d up to date.\n",
+			advanced, diverged, failed, noUpstream, len(results)-advanced-diverged-failed-noUpstream)
+		return nil
 	},
 }
 
 var featureCmd = &cobra.Command{
 	Use:     "feature",
 	Aliases: []string{"feat"},
-	Short:   "Pull latest from main, create and switch to a new feature branch",
-	Run: func(cmd *cobra.Command, args []string) {
+	Short:   "Pull latest from the base branch, create and switch to a new feature branch",
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 		origin, err := cmd.Flags().GetString("origin")
 		if origin == "" {
-			origin = repo.GetDefaultBranch()
+			origin = baseBranch(repo)
 		}
 		new := cmd.Flags().Changed("new")
 		close := cmd.Flags().Changed("close")
 
 		if !new && !close {
-			HandleError("using feature command", fmt.Errorf("either -new or -close flag must be provided"), true)
+			return fail("using feature command", fmt.Errorf("either -new or -close flag must be provided"))
 		}
 
-		HandleError("getting origin flag", err, true)
+		if err != nil {
+			return fail("getting origin flag", err)
+		}
 
 		if new {
 			branchName, err := cmd.Flags().GetString("new")
-			HandleError("getting new flag", err, true)
+			if err != nil {
+				return fail("getting new flag", err)
+			}
 
 			err = repo.PullLatestRemote(origin)
-			HandleError("pulling latest changes", err, true)
+			if err != nil {
+				return fail("pulling latest changes", err)
+			}
 
 			err = repo.SwitchBranch(origin)
-			HandleError("switching to origin branch", err, true)
+			if err != nil {
+				return fail("switching to origin branch", err)
+			}
 
 			err = repo.CreateBranch(branchName)
-			HandleError("creating feature branch", err, true)
+			if err != nil {
+				return fail("creating feature branch", err)
+			}
 
 			fmt.Println("Successfully created and switched to feature branch", branchName)
 		} else if close {
 			branchName, err := repo.GetCurrentBranch()
-			HandleError("getting close flag", err, true)
+			if err != nil {
+				return fail("getting close flag", err)
+			}
 
 			err = repo.SwitchBranch(origin)
-			HandleError("switching to origin branch", err, true)
+			if err != nil {
+				return fail("switching to origin branch", err)
+			}
 			fmt.Printf("Switching to %s\n", origin)
 
 			err = repo.PullLatestRemote(origin)
-			HandleError("pulling latest changes", err, true)
+			if err != nil {
+				return fail("pulling latest changes", err)
+			}
 
 			err = repo.MergeLocalBranch(branchName)
-			HandleError("closing feature branch", err, true)
+			if err != nil {
+				return fail("closing feature branch", err)
+			}
 			fmt.Printf("Successfully merged %s into %s\n", branchName, origin)
 
-			err = repo.DeleteBranch(branchName)
-			HandleError("deleting feature branch\n", err, true)
-			fmt.Printf("Deleting branch %s\n", branchName)
+			force, _ := cmd.Flags().GetBool("force")
+			deleted, err := deleteFeatureBranch(repo, branchName, force)
+			if err != nil {
+				return fail("deleting feature branch", err)
+			}
+			if deleted {
+				fmt.Printf("Deleted branch %s\n", branchName)
+			} else {
+				fmt.Printf("Kept branch %s\n", branchName)
+			}
 
 			err = repo.Push()
-			HandleError("pushing changes", err, true)
+			if err != nil {
+				return fail("pushing changes", err)
+			}
 			fmt.Println("Successfully pushed changes.")
 		}
+		return nil
 	},
+	Annotations: needsTerminal,
+}
+
+// deleteFeatureBranch deletes a closed feature branch, falling back to a
+// force delete when git reports it unmerged. Squash merges on the remote
+// rewrite the branch's commits, so a branch that was merged upstream can
+// still look unmerged locally.
+func deleteFeatureBranch(repo *git.GitRepo, branchName string, force bool) (bool, error) {
+	if force {
+		return true, repo.ForceDeleteBranch(branchName)
+	}
+
+	err := repo.DeleteBranch(branchName)
+	if !errors.Is(err, git.ErrBranchNotMerged) {
+		return err == nil, err
+	}
+
+	fmt.Printf("Branch '%s' is not fully merged according to git.\n", branchName)
+	fmt.Println("This usually means it was squash- or rebase-merged upstream, so its commits landed under new hashes.")
+	if !isInteractive() {
+		return false, fmt.Errorf("%w; rerun with --force to delete it anyway", err)
+	}
+
+	if !confirm("Force delete it?") {
+		return false, nil
+	}
+	return true, repo.ForceDeleteBranch(branchName)
 }
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
@@ -13,75 +15,170 @@ func init() {
 	rootCmd.AddCommand(commitAndPushCmd)
 	rootCmd.AddCommand(amendCmd)
 	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(exportCmd)
 
+	commitCmd.Flags().BoolP("all", "a", false, "Stage modified and deleted tracked files before committing (untracked files are left out)")
+	for _, c := range []*cobra.Command{commitCmd, commitAndPushCmd, amendCmd} {
+		c.Flags().BoolP("signoff", "s", false, "Add a Signed-off-by trailer (default from commit_signoff in config)")
+	}
 	amendCmd.Flags().BoolP("no-edit", "n", false, "Amend staged changes without changing the commit message")
+	exportCmd.Flags().StringP("output", "o", ".", "Directory to write the patch files to (created if missing)")
 }
 
 var commitCmd = &cobra.Command{
-	Use:   "commit",
-	Args:  cobra.RangeArgs(0, 1),
+	Use:   "commit [message]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "Commit staged changes with a message",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
+		applySignOff(cmd, repo)
+
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fail("Getting all flag", err)
+		}
+
+		if len(args) == 0 && config.Load().CommitUseEditor {
+			err = ui.StartEditorCommit(repo, all)
+			return fail("committing changes", err)
+		}
 
 		if len(args) == 0 {
-			err := ui.StartCommitInput(repo)
-			HandleError("committing changes", err, true)
-			return
+			if all {
+				err = ui.StartCommitAllInput(repo)
+			} else {
+				err = ui.StartCommitInput(repo)
+			}
+			return fail("committing changes", err)
 		}
 
 		commitMsg := args[0]
-		err := repo.Commit(commitMsg)
-		HandleError("committing changes", err, true)
+		if all {
+			err = repo.CommitAll(commitMsg)
+		} else {
+			err = repo.Commit(commitMsg)
+		}
+		if err != nil {
+			return fail("committing changes", explainCommitError(err))
+		}
 
 		fmt.Println("Successfully committed changes.")
+		return nil
 	},
+	Annotations: needsTerminalWithoutArgs,
+}
+
+// applySignOff turns on sign-off for repo when --signoff was passed; the
+// commit_signoff config has already set the default.
+func applySignOff(cmd *cobra.Command, repo *git.GitRepo) {
+	if signOff, _ := cmd.Flags().GetBool("signoff"); signOff {
+		repo.SignOff = true
+	}
+}
+
+// explainCommitError replaces git's "nothing to commit" output with a
+// pointer to staging.
+func explainCommitError(err error) error {
+	if errors.Is(err, git.ErrNothingToCommit) {
+		return fmt.Errorf("%w; stage changes with 'cgit manage' first", git.ErrNothingToCommit)
+	}
+	return err
 }
 
 var commitAndPushCmd = &cobra.Command{
-	Use:     "commit-and-push",
+	Use:     "commit-and-push <message>",
 	Aliases: []string{"cap"},
+	Args:    requireArg("commit message"),
 	Short:   "Commit and push changes",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
+		applySignOff(cmd, repo)
 
 		commitMsg := args[0]
 		err := repo.Commit(commitMsg)
-		HandleError("committing changes", err, true)
+		if err != nil {
+			return fail("committing changes", explainCommitError(err))
+		}
 
 		err = repo.Push()
-		HandleError("pushing changes", err, true)
+		if err != nil {
+			return fail("pushing changes", err)
+		}
 
 		fmt.Println("Successfully committed and pushed changes.")
+		return nil
 	},
+	Annotations: needsTerminal,
 }
 
 var amendCmd = &cobra.Command{
 	Use:   "amend",
 	Short: "Amend the last commit",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
+		applySignOff(cmd, repo)
 
 		noEdit, _ := cmd.Flags().GetBool("no-edit")
 		if noEdit {
 			err := repo.AmendCommit("", true)
-			HandleError("amending commit", err, true)
+			if err != nil {
+				return fail("amending commit", err)
+			}
 			fmt.Println("Successfully amended commit.")
-			return
+			return nil
 		}
 
 		err := ui.StartAmendInput(repo)
-		HandleError("amending commit", err, true)
+		return fail("amending commit", err)
 	},
+	Annotations: needsTerminal,
 }
 
 var undoCmd = &cobra.Command{
 	Use:   "undo",
 	Short: "Soft-reset the last commit, keeping changes staged",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 		err := repo.UndoLastCommit()
-		HandleError("undoing last commit", err, true)
+		if err != nil {
+			return fail("undoing last commit", err)
+		}
 		fmt.Println("Last commit undone. Changes are still staged.")
+		return nil
+	},
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [range]",
+	Short: "Write commits out as .patch files",
+	Long: "Export a commit range as .patch files with git format-patch, one per commit, so they can be shared or applied with git am. " +
+		"The range defaults to @{upstream}..HEAD, the commits a push would send.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		outDir, _ := cmd.Flags().GetString("output")
+
+		rangeSpec := "@{upstream}..HEAD"
+		if len(args) == 1 {
+			rangeSpec = args[0]
+		} else if !repo.HasUpstream() {
+			return fail("exporting commits", fmt.Errorf("%w; pass a range such as HEAD~3..HEAD", git.ErrNoUpstream))
+		}
+
+		files, err := repo.FormatPatch(rangeSpec, outDir)
+		if err != nil {
+			return fail("exporting commits", err)
+		}
+
+		if len(files) == 0 {
+			fmt.Printf("No commits in %s; nothing to export.\n", rangeSpec)
+			return nil
+		}
+		fmt.Printf("Wrote %d patch file(s):\n", len(files))
+		for _, f := range files {
+			fmt.Println("  " + f)
+		}
+		return nil
 	},
 }
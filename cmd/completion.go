@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a completion script for your shell. Branch names complete for
+'cgit switch' once the script is installed.
+
+Bash:
+  $ cgit completion bash > /etc/bash_completion.d/cgit
+  # or, for the current user only:
+  $ cgit completion bash > ~/.local/share/bash-completion/completions/cgit
+
+Zsh:
+  # enable completion once if it isn't already:
+  $ echo "autoload -U compinit; compinit" >> ~/.zshrc
+  $ cgit completion zsh > "${fpath[1]}/_cgit"
+
+Fish:
+  $ cgit completion fish > ~/.config/fish/completions/cgit.fish
+
+PowerShell:
+  PS> cgit completion powershell | Out-String | Invoke-Expression
+  # to load for every session, add the line above to your $PROFILE.
+
+Start a new shell after installing for completions to take effect.`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+		return nil
+	},
+}
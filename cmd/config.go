@@ -16,14 +16,50 @@ var configCmd = &cobra.Command{
 	Short: "Show or edit cgit configuration",
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := config.Load()
-		fmt.Printf("Config file: %s\n\n", config.Path())
-		fmt.Printf("log_limit:    %d\n", cfg.LogLimit)
-		fmt.Printf("rebase_limit: %d\n", cfg.RebaseLimit)
-		fmt.Printf("split_pane:   %v\n", cfg.SplitPane)
+		fmt.Printf("Config file: %s\n", config.Path())
+		if path := config.RepoPath(); path != "" {
+			fmt.Printf("Repo config: %s (overrides the config file)\n", path)
+		}
+		fmt.Println()
+		fmt.Printf("log_limit:           %d\n", cfg.LogLimit)
+		fmt.Printf("rebase_limit:        %d\n", cfg.RebaseLimit)
+		fmt.Printf("split_pane:          %v\n", cfg.SplitPane)
 		if cfg.Editor != "" {
-			fmt.Printf("editor:       %s\n", cfg.Editor)
+			fmt.Printf("editor:              %s\n", cfg.Editor)
+		} else {
+			fmt.Printf("editor:              (uses $EDITOR)\n")
+		}
+		fmt.Printf("theme:               %s\n", cfg.Theme)
+		if cfg.BaseBranch != "" {
+			fmt.Printf("base_branch:         %s\n", cfg.BaseBranch)
+		} else {
+			fmt.Printf("base_branch:         (auto-detect)\n")
+		}
+		fmt.Printf("confirm_destructive: %v\n", cfg.ConfirmDestructive)
+		fmt.Printf("full_file_max_lines: %d\n", cfg.FullFileMaxLines)
+		fmt.Printf("network_retries:     %d\n", cfg.NetworkRetries)
+		fmt.Printf("network_backoff_ms:  %d\n", cfg.NetworkBackoffMS)
+		fmt.Printf("default_view:        %s\n", cfg.DefaultView)
+		fmt.Printf("shell_dashboard:     %v\n", cfg.ShellDashboard)
+		if cfg.DiffAlgorithm != "" {
+			fmt.Printf("diff_algorithm:      %s\n", cfg.DiffAlgorithm)
+		} else {
+			fmt.Printf("diff_algorithm:      (git default)\n")
+		}
+		fmt.Printf("rename_threshold:    %d%%\n", cfg.RenameThreshold)
+		fmt.Printf("shell_pager:         %v\n", cfg.ShellPager)
+		fmt.Printf("wip_pattern:         %s\n", cfg.WIPPattern)
+		fmt.Printf("commit_use_editor:   %v\n", cfg.CommitUseEditor)
+		fmt.Printf("commit_signoff:      %v\n", cfg.CommitSignOff)
+		fmt.Printf("subject_max_length:  %d\n", cfg.SubjectMaxLength)
+		if path := historyFilePath(cfg); cfg.HistoryFile != "" {
+			fmt.Printf("history_file:        %s\n", path)
+		} else if path != "" {
+			fmt.Printf("history_file:        (default: %s)\n", path)
 		} else {
-			fmt.Printf("editor:       (uses $EDITOR)\n")
+			fmt.Printf("history_file:        (none, no home directory)\n")
 		}
+		fmt.Printf("history_size:        %d\n", cfg.HistorySize)
+		fmt.Printf("show_help:           %v\n", cfg.ShowHelp)
 	},
 }
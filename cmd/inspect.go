@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
@@ -10,30 +16,114 @@ func init() {
 	rootCmd.AddCommand(statusCommand)
 	rootCmd.AddCommand(logCmd)
 	rootCmd.AddCommand(conflictsCmd)
+	rootCmd.AddCommand(resolveCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(blameCmd)
+	rootCmd.AddCommand(mineCmd)
+
+	logCmd.Flags().String("author", "", "Only show commits whose author name or email contains this")
+	mineCmd.Flags().String("author", "", "Author name or email to filter by (default: git config user.email)")
+	mineCmd.Flags().IntP("limit", "n", 20, "Number of matching commits to list")
+
+	diffCmd.Flags().Bool("staged", false, "Show changes staged for the next commit instead of unstaged changes")
+	checkCmd.Flags().Bool("pre-push", false, "Also flag outgoing commits whose subject matches wip_pattern, for use as a pre-push hook")
+	resolveCmd.Flags().StringP("strategy", "s", "", "Side to take for every conflict: ours or theirs")
+	resolveCmd.Flags().Bool("all", false, "Resolve every conflicted file")
+	diffCmd.Flags().Bool("tool", false, "Open the diff in the configured git difftool (falls back to the built-in viewer)")
 }
 
 var statusCommand = &cobra.Command{
 	Use:     "status",
 	Aliases: []string{"st"},
 	Short:   "Browse repository status in an interactive TUI",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
-		err := ui.StartStatusViewer(repo)
-		HandleError("showing status", err, true)
+		return handleStatusViewerExit(ui.StartStatusViewer(repo))
 	},
+	Annotations: needsTerminal,
 }
 
 var logCmd = &cobra.Command{
 	Use:     "log",
 	Aliases: []string{"l"},
 	Short:   "Browse commit history in an interactive viewer",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		author, _ := cmd.Flags().GetString("author")
+		for {
+			err := ui.StartLogViewer(repo, func() ([]git.LogLine, error) {
+				return repo.GetLog(100, author)
+			})
+			if !errors.Is(err, git.ErrMergeConflict) {
+				return fail("showing log viewer", err)
+			}
+			// A dropped commit's successors conflicted; resolve, then come back.
+			fmt.Println("Dropping the commit stopped on conflicts.")
+			if err := resolveRebaseConflicts(repo); err != nil {
+				return fail("dropping commit", err)
+			}
+		}
+	},
+	Annotations: needsTerminal,
+}
+
+var mineCmd = &cobra.Command{
+	Use:   "mine",
+	Short: "List your own recent commits and in-flight changes",
+	Long: "List the recent commits on the current branch authored by you (git config user.email), followed by your uncommitted changes. " +
+		"Useful on shared branches. --author looks at someone else's commits instead.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
-		content, err := repo.GetLog(100)
-		HandleError("getting git log", err, true)
 
-		err = ui.StartLogViewer(repo, content)
-		HandleError("showing log viewer", err, true)
+		author, _ := cmd.Flags().GetString("author")
+		limit, _ := cmd.Flags().GetInt("limit")
+		self := author == ""
+		if self {
+			var err error
+			author, err = repo.UserEmail()
+			if err != nil {
+				return fail("finding your author email", err)
+			}
+		}
+
+		commits, err := repo.AuthorCommits(author, limit)
+		if err != nil {
+			return fail("listing commits", err)
+		}
+		if len(commits) == 0 {
+			fmt.Printf("No commits by %s on this branch.\n", author)
+		} else {
+			printCommits(fmt.Sprintf("Recent commits by %s:", author), commits)
+		}
+
+		// Uncommitted changes only exist in this clone, so they are
+		// always the current user's.
+		if !self {
+			return nil
+		}
+		staged, unstaged, untracked, err := repo.GetFileStatuses()
+		if err != nil {
+			return fail("getting file status", err)
+		}
+		changed := append(append(staged, unstaged...), untracked...)
+		fmt.Println()
+		if len(changed) == 0 {
+			fmt.Println("No uncommitted changes.")
+			return nil
+		}
+		fmt.Printf("Uncommitted changes (%d):\n", len(changed))
+		for _, f := range changed {
+			where := "unstaged"
+			if f.Staged {
+				where = "staged"
+			}
+			fmt.Printf("  %s %s (%s)\n", f.Status, f.Path, where)
+		}
+		return nil
 	},
 }
 
@@ -41,9 +131,273 @@ var conflictsCmd = &cobra.Command{
 	Use:     "conflicts",
 	Aliases: []string{"cf"},
 	Short:   "Resolve merge conflicts interactively",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		if repo.RebaseInProgress() {
+			return fail("continuing rebase", resolveRebaseConflicts(repo))
+		}
+		finish, err := ui.StartConflictsPicker(repo)
+		if err != nil {
+			return fail("resolving conflicts", err)
+		}
+		if !finish {
+			if repo.MergeInProgress() {
+				fmt.Println("The merge is still in progress; rerun 'cgit conflicts' to finish it.")
+			}
+			return nil
+		}
+
+		err = finishMerge(repo)
+		return fail("finishing merge", err)
+	},
+	Annotations: needsTerminal,
+}
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve --strategy ours|theirs (--all | <path>...)",
+	Short: "Resolve conflicts by taking one side, without the TUI",
+	Long: `Resolve conflicted files by taking ours or theirs wholesale and stage them.
+Pass --all to resolve every conflicted file, or list the paths to resolve.
+The merge itself is left for you to commit.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		strategy, err := cmd.Flags().GetString("strategy")
+		if err != nil {
+			return fail("Getting strategy flag", err)
+		}
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fail("Getting all flag", err)
+		}
+
+		if strategy != "ours" && strategy != "theirs" {
+			return fail("resolving conflicts", fmt.Errorf("--strategy must be ours or theirs"))
+		}
+		if all == (len(args) > 0) {
+			return fail("resolving conflicts", fmt.Errorf("pass either --all or the paths to resolve"))
+		}
+
+		conflicts, err := repo.GetConflictedFiles()
+		if err != nil {
+			return fail("listing conflicts", err)
+		}
+		if len(conflicts) == 0 {
+			return fail("resolving conflicts", fmt.Errorf("there are no conflicted files to resolve"))
+		}
+
+		conflicted := make(map[string]bool, len(conflicts))
+		var paths []string
+		for _, f := range conflicts {
+			conflicted[f.Path] = true
+			paths = append(paths, f.Path)
+		}
+		if !all {
+			for _, path := range args {
+				if !conflicted[path] {
+					return fail("resolving conflicts", fmt.Errorf("%s is not conflicted", path))
+				}
+			}
+			paths = args
+		}
+
+		resolved, err := repo.ResolveConflicts(paths, strategy)
+		for _, path := range resolved {
+			fmt.Printf("  %s (%s)\n", path, strategy)
+		}
+		if err != nil {
+			return fail("resolving conflicts", err)
+		}
+
+		fmt.Printf("Resolved %d file(s) with %s.\n", len(resolved), strategy)
+		if remaining := len(conflicts) - len(resolved); remaining > 0 {
+			fmt.Printf("%d conflicted file(s) remain.\n", remaining)
+		} else if repo.MergeInProgress() {
+			fmt.Println("No conflicts remain; run 'cgit conflicts' to commit the merge.")
+		}
+		return nil
+	},
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Find conflict markers left in tracked files",
+	Long: `Search tracked files for <<<<<<< and >>>>>>> lines left behind by a
+conflict resolution. Exits non-zero when any are found, so it can run
+from a pre-commit hook.
+
+With --pre-push, also list the commits a push would send whose subject
+matches the wip_pattern setting (by default WIP, fixup!, squash! and
+amend! commits). Use it as a hook with:
+
+  echo 'exec cgit check --pre-push' > .git/hooks/pre-push
+  chmod +x .git/hooks/pre-push`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		prePush, err := cmd.Flags().GetBool("pre-push")
+		if err != nil {
+			return fail("Getting pre-push flag", err)
+		}
+
+		hits, err := repo.ScanForConflictMarkers()
+		if err != nil {
+			return fail("checking for conflict markers", err)
+		}
+		if len(hits) > 0 {
+			fmt.Println("Conflict markers:")
+			for _, hit := range hits {
+				fmt.Printf("  %s\n", hit)
+			}
+		}
+
+		var wip []git.CommitInfo
+		if prePush {
+			wip, err = wipCommits(repo, config.Load().WIPPattern)
+			if err != nil {
+				return fail("checking outgoing commits", err)
+			}
+			if len(wip) > 0 {
+				fmt.Println("Work-in-progress commits:")
+				for _, c := range wip {
+					fmt.Printf("  %s %s\n", c.ShortHash, c.Subject)
+				}
+			}
+		}
+
+		switch {
+		case len(hits) > 0 && len(wip) > 0:
+			return fail("checking", fmt.Errorf("%d conflict marker line(s) and %d work-in-progress commit(s) found", len(hits), len(wip)))
+		case len(hits) > 0:
+			return fail("checking for conflict markers", fmt.Errorf("%d conflict marker line(s) found", len(hits)))
+		case len(wip) > 0:
+			return fail("checking outgoing commits", fmt.Errorf("%d work-in-progress commit(s) would be pushed", len(wip)))
+		}
+
+		if prePush {
+			fmt.Println("No conflict markers or work-in-progress commits found.")
+		} else {
+			fmt.Println("No conflict markers found.")
+		}
+		return nil
+	},
+}
+
+// wipCommits returns the outgoing commits whose subject matches pattern.
+func wipCommits(repo *git.GitRepo, pattern string) ([]git.CommitInfo, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wip_pattern: %w", err)
+	}
+	return repo.WIPCommits(re)
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show [commit]",
+	Short: "Show a commit's message and diff (defaults to HEAD)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		ref := "HEAD"
+		if len(args) == 1 {
+			ref = args[0]
+		}
+
+		// Print plainly when piped so the output can be consumed by scripts.
+		if !isTerminal(os.Stdout) {
+			content, err := repo.ShowCommitPlain(ref)
+			if err != nil {
+				return fail("showing commit", err)
+			}
+			fmt.Print(content)
+			return nil
+		}
+
+		// Resolve up front so a bad ref fails before the viewer opens.
+		_, err := repo.ResolveRef(ref)
+		if err != nil {
+			return fail("showing commit", err)
+		}
+
+		err = ui.ShowCommitDiff(repo, ref)
+		return fail("showing commit", err)
+	},
+	Annotations: needsTerminal,
+}
+
+var historyCmd = &cobra.Command{
+	Use:     "history <path>",
+	Aliases: []string{"hist"},
+	Short:   "Browse the commits that changed a file, following renames",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
-		err := ui.StartConflictsPicker(repo)
-		HandleError("resolving conflicts", err, true)
+		err := ui.StartFileHistory(repo, args[0], config.Load().LogLimit)
+		return fail("showing file history", err)
+	},
+	Annotations: needsTerminal,
+}
+
+var blameCmd = &cobra.Command{
+	Use:   "blame <path>",
+	Short: "Show who last changed each line of a file; enter opens that line's commit",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		err := ui.StartBlameViewer(repo, args[0])
+		return fail("showing blame", err)
+	},
+	Annotations: needsTerminal,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [--staged] [--tool] <path>",
+	Short: "Show unstaged (or with --staged, staged) changes to a path",
+	Long: `Show the changes to a path, matching git diff semantics:
+
+  cgit diff <path>           working tree vs index (unstaged changes)
+  cgit diff --staged <path>  index vs HEAD (staged changes)
+
+Pass --tool to open the diff in the tool set by git config diff.tool.
+
+Output is printed plainly when piped, and opens in the diff viewer otherwise.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		path := args[0]
+		staged, _ := cmd.Flags().GetBool("staged")
+
+		// Staged diffs compare against HEAD, so a path deleted from the index
+		// is still valid there.
+		if staged && !repo.IsTracked(path) && !repo.InHead(path) {
+			return fail("showing diff", fmt.Errorf("%s is not in the index or HEAD", path))
+		}
+		if !staged && !repo.IsTracked(path) {
+			return fail("showing diff", fmt.Errorf("%s is not tracked by git", path))
+		}
+
+		tool, _ := cmd.Flags().GetBool("tool")
+		if tool && repo.DifftoolConfigured() {
+			err := repo.LaunchDifftool(path, staged)
+			return fail("running difftool", err)
+		}
+
+		if !isTerminal(os.Stdout) {
+			content, err := repo.Diff(path, git.DiffOptions{Staged: staged, Context: -1, Plain: true})
+			if err != nil {
+				return fail("showing diff", err)
+			}
+			fmt.Print(content)
+			return nil
+		}
+
+		err := ui.ShowDiff(repo, path, staged)
+		return fail("showing diff", err)
 	},
+	Annotations: needsTerminal,
 }
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
@@ -11,6 +13,66 @@ import (
 func init() {
 	manageCmd.Flags().BoolP("staged", "s", false, "Manage Staged files")
 	rootCmd.AddCommand(manageCmd)
+	rootCmd.AddCommand(moveCmd)
+	removeCmd.Flags().Bool("cached", false, "Stop tracking the files but keep them on disk")
+	removeCmd.Flags().BoolP("recursive", "r", false, "Allow removing directories and everything tracked in them")
+	removeCmd.Flags().BoolP("force", "f", false, "Remove files even if they have uncommitted changes")
+	rootCmd.AddCommand(removeCmd)
+}
+
+var removeCmd = &cobra.Command{
+	Use:   "rm <path>...",
+	Short: "Delete tracked files and stage the deletion",
+	Long: "Delete tracked files with git rm, staging the deletion. --cached only stops tracking them and leaves them on disk. " +
+		"Directories need -r. Asks for confirmation first unless confirm_destructive is off or --cached is given.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		var opts git.RemoveOptions
+		opts.Cached, _ = cmd.Flags().GetBool("cached")
+		opts.Recursive, _ = cmd.Flags().GetBool("recursive")
+		opts.Force, _ = cmd.Flags().GetBool("force")
+
+		if !opts.Cached && config.Load().ConfirmDestructive {
+			if !confirm(fmt.Sprintf("Delete %s from disk and stage the deletion?", strings.Join(args, ", "))) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+
+		for _, path := range args {
+			err := repo.DeleteTrackedFile(path, opts)
+			if err != nil {
+				return fail("removing "+path, err)
+			}
+		}
+
+		if opts.Cached {
+			fmt.Printf("Stopped tracking %d path(s); they are still on disk.\n", len(args))
+		} else {
+			fmt.Printf("Deleted %d path(s); the deletion is staged.\n", len(args))
+		}
+		return nil
+	},
+	Annotations: needsTerminal,
+}
+
+var moveCmd = &cobra.Command{
+	Use:   "mv <source> <destination>",
+	Short: "Rename a tracked file, staged as a rename",
+	Long: "Rename a tracked file with git mv so history follows it. Missing directories in the destination " +
+		"are created; an existing destination is never overwritten. In 'cgit manage', press m to rename the file under the cursor.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		err := repo.MoveFile(args[0], args[1])
+		if err != nil {
+			return fail("renaming file", err)
+		}
+		fmt.Printf("Renamed %s → %s (staged).\n", args[0], args[1])
+		return nil
+	},
 }
 
 var manageCmd = &cobra.Command{
@@ -18,22 +80,56 @@ var manageCmd = &cobra.Command{
 	Aliases: []string{"m"},
 	Short:   "Interactively manage files with search support",
 	Long: "Launch an interactive file picker for selecting and staging/restoring files with fuzzy search capabilities. " +
-		"Use /: to search, space: to select files, c: to stage selected files, and r to restore selected files.",
-	Run: func(cmd *cobra.Command, args []string) {
+		"Use /: to search, enter: to select files, c: to stage selected files, and r to restore selected files. " +
+		"Press x (or X) to exit and print the selection for staging (or restoring) without applying it. " +
+		"In the full-screen diff (space), +/- adjust the context lines, a shows all context and F toggles a full-file view with change markers. " +
+		"Press v to review the selected files one by one in the full-screen diff: n/p move between them and s stages the current file and advances. " +
+		"Press t to group files by directory; o folds a directory and enter on a directory selects everything inside it. " +
+		"S stages the whole directory under the cursor (or the current file's directory) at once. " +
+		"d opens the current file in your git difftool, or the full-screen diff if none is configured. " +
+		"R reverts the selected files to HEAD, destroying both their staged and unstaged changes; it asks for confirmation (y) first. " +
+		"m renames the file under the cursor with git mv, and D deletes the selected files (or the one under the cursor) with git rm after confirmation (y). " +
+		"N marks untracked files as intent to add (git add -N): git tracks them without staging any content, so their diff can be staged hunk by hunk with p, which does this itself for an untracked file.",
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 
 		staged, err := cmd.Flags().GetBool("staged")
-		HandleError("getting staged flag", err, true)
+		if err != nil {
+			return fail("getting staged flag", err)
+		}
 
 		repoStatus, err := repo.GetRepositoryStatus()
-		HandleError("getting repository status", err, true)
+		if err != nil {
+			return fail("getting repository status", err)
+		}
 
-		if len(repoStatus.StagedFiles) == 0 && len(repoStatus.UnstagedFiles) == 0 {
+		// The picker stages new files alongside modified ones.
+		unstaged := append(repoStatus.UnstagedFiles, repoStatus.UntrackedFiles...)
+		if len(repoStatus.StagedFiles) == 0 && len(unstaged) == 0 {
 			fmt.Println("No files to manage.")
-			return
+			return nil
 		}
 
-		_, _, err = ui.SelectFiles(repo, repoStatus.StagedFiles, repoStatus.UnstagedFiles, staged)
-		HandleError("selecting files", err, true)
+		result, err := ui.SelectFiles(repo, repoStatus.StagedFiles, unstaged, staged)
+		if err != nil {
+			return fail("selecting files", err)
+		}
+
+		if summary := result.Summary.String(); summary != "" {
+			fmt.Println(strings.ToUpper(summary[:1]) + summary[1:] + ".")
+		}
+
+		if len(result.Files) > 0 {
+			action := "stage"
+			if result.Removing {
+				action = "restore"
+			}
+			fmt.Printf("Selected %d file(s) to %s:\n", len(result.Files), action)
+			for _, f := range result.Files {
+				fmt.Println("  " + f)
+			}
+		}
+		return nil
 	},
+	Annotations: needsTerminal,
 }
@@ -1,68 +1,430 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/corpeningc/cgit/internal/git"
+	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	pushCmd.Flags().BoolP("force-with-lease", "f", false, "Force push with lease (safer force push)")
 	pushCmd.Flags().BoolP("set-upstream", "u", false, "Set upstream tracking for current branch")
+	pushCmd.Flags().BoolP("yes", "y", false, "Push without listing the outgoing commits and asking first")
 	rootCmd.AddCommand(pushCmd)
+	pullCmd.Flags().BoolP("yes", "y", false, "Pull without listing the incoming commits and asking first")
 	rootCmd.AddCommand(pullCmd)
+	incomingCmd.Flags().Bool("no-fetch", false, "List against the last fetch instead of fetching first")
+	rootCmd.AddCommand(incomingCmd)
+	rootCmd.AddCommand(whatsNewCmd)
+	mergeCommand.Flags().Bool("resolve", false, "Open the conflict resolver if the merge stops on conflicts")
+	mergeCommand.Flags().Bool("abort", false, "Abandon a merge that stopped on conflicts")
 	rootCmd.AddCommand(mergeCommand)
+	rootCmd.AddCommand(compareCmd)
+	browseCmd.Flags().BoolP("branch", "b", false, "Open the current branch's page instead of the repository's")
+	rootCmd.AddCommand(browseCmd)
+	prCmd.Flags().String("base", "", "Branch the request should merge into (defaults to config base_branch, then the repo's primary branch)")
+	rootCmd.AddCommand(prCmd)
+}
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Open the page to create a pull request for the current branch",
+	Long: "Open the host's new pull request (merge request on GitLab) page comparing the current branch with the base branch. " +
+		"Prints the URL when no browser is available.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		base, _ := cmd.Flags().GetString("base")
+		if base == "" {
+			base = baseBranch(repo)
+		}
+		branch, err := repo.GetCurrentBranch()
+		if err != nil {
+			return fail("getting current branch", err)
+		}
+		if branch == base {
+			return fail("opening pull request page", fmt.Errorf("you are on the base branch %s; switch to the branch to propose", base))
+		}
+
+		webURL, err := ui.RemoteWebURL(repo, false)
+		if err != nil {
+			return fail("finding the remote's web page", err)
+		}
+		if !repo.HasUpstream() {
+			fmt.Println("Note: this branch has no upstream yet; push it with 'cgit push -u' so the host can see it.")
+		}
+
+		url := git.CompareURL(webURL, base, branch)
+		if err := ui.OpenURL(url); err != nil {
+			fmt.Println(url)
+			return nil
+		}
+		fmt.Println("Opened", url)
+		return nil
+	},
+}
+
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Open the origin remote in your browser",
+	Long:  "Open the web page of the origin remote (GitHub, GitLab and similar hosts, from an ssh or https URL) in the default browser. Prints the URL when no browser is available.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		branch, _ := cmd.Flags().GetBool("branch")
+
+		url, err := ui.RemoteWebURL(repo, branch)
+		if err != nil {
+			return fail("finding the remote's web page", err)
+		}
+
+		if err := ui.OpenURL(url); err != nil {
+			fmt.Println(url)
+			return nil
+		}
+		fmt.Println("Opened", url)
+		return nil
+	},
 }
 
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push committed changes to remote",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 
 		force, _ := cmd.Flags().GetBool("force-with-lease")
 		upstream, _ := cmd.Flags().GetBool("set-upstream")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		if !yes && isInteractive() {
+			ok, err := previewPush(repo)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Push canceled.")
+				return nil
+			}
+		}
 
-		err := repo.PushWithOptions(git.PushOptions{
+		opts := git.PushOptions{
 			ForceWithLease: force,
 			SetUpstream:    upstream,
-		})
-		HandleError("pushing changes", err, true)
+		}
+		err := repo.PushWithOptions(opts)
+		if errors.Is(err, git.ErrNotFastForward) {
+			err = pullAndRetryPush(repo, opts, err)
+		}
+		if err != nil {
+			return fail("pushing changes", err)
+		}
 
 		fmt.Println("Successfully pushed changes.")
+		return nil
 	},
+	Annotations: needsTerminal,
+}
+
+// previewPush lists the commits a push would send and asks whether to go
+// ahead. With nothing new to list it doesn't ask, since the push may still
+// be needed to create the remote branch.
+func previewPush(repo *git.GitRepo) (bool, error) {
+	commits, err := repo.OutgoingCommits()
+	if err != nil {
+		return false, fail("listing outgoing commits", err)
+	}
+	if len(commits) == 0 {
+		return true, nil
+	}
+
+	printCommits(fmt.Sprintf("%d commit(s) will be pushed:", len(commits)), commits)
+	return confirm("Push them?"), nil
+}
+
+// previewPull fetches, lists the commits a pull would bring in and asks
+// whether to go ahead. It reports false, after saying so, when there is
+// nothing to pull or the user declines. Without an upstream there is
+// nothing to preview, so the pull goes ahead.
+func previewPull(repo *git.GitRepo) (bool, error) {
+	if err := repo.Fetch(); err != nil {
+		return false, fail("fetching", err)
+	}
+	commits, err := repo.IncomingCommits()
+	if errors.Is(err, git.ErrNoUpstream) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fail("listing incoming commits", err)
+	}
+
+	if len(commits) == 0 {
+		fmt.Println("Already up to date.")
+		return false, nil
+	}
+	printCommits(fmt.Sprintf("%d commit(s) will be pulled:", len(commits)), commits)
+	if !confirm("Pull them?") {
+		fmt.Println("Pull canceled.")
+		return false, nil
+	}
+	return true, nil
+}
+
+// printCommits prints a heading followed by one line per commit.
+func printCommits(heading string, commits []git.CommitInfo) {
+	fmt.Println(heading)
+	for _, c := range commits {
+		fmt.Printf("  %s %s (%s, %s)\n", c.ShortHash, c.Subject, c.Author, c.Date)
+	}
+}
+
+// pullAndRetryPush offers to pull the remote's new commits and push again
+// after a push is rejected as non-fast-forward.
+func pullAndRetryPush(repo *git.GitRepo, opts git.PushOptions, pushErr error) error {
+	fmt.Println("The remote has commits that are not in your local branch.")
+	if !isInteractive() {
+		return fmt.Errorf("%w; run 'cgit pull' first", git.ErrNotFastForward)
+	}
+	if !confirm("Pull them now and push again?") {
+		return pushErr
+	}
+
+	branchName, err := repo.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	if err := repo.PullLatestRemote(branchName); err != nil {
+		return err
+	}
+	return repo.PushWithOptions(opts)
+}
+
+// resolveMergeConflicts opens the conflict resolver after a merge stops on
+// conflicts, and commits the merge once every file is resolved and the
+// user finishes it. Quitting the resolver early offers to reopen it;
+// declining leaves the merge in progress for 'cgit conflicts' to pick up
+// later.
+func resolveMergeConflicts(repo *git.GitRepo) error {
+	if !isInteractive() {
+		return fmt.Errorf("%w; resolve them with 'cgit conflicts', then commit", git.ErrMergeConflict)
+	}
+
+	fmt.Println("The merge stopped on conflicts.")
+	for {
+		finish, err := ui.StartConflictsPicker(repo)
+		if err != nil {
+			return err
+		}
+		if finish {
+			break
+		}
+
+		remaining, err := repo.GetConflictedFiles()
+		if err != nil {
+			return err
+		}
+		prompt := fmt.Sprintf("%d conflicted file(s) remain. Keep resolving?", len(remaining))
+		if len(remaining) == 0 {
+			prompt = "All conflicts are resolved. Reopen the resolver to finish the merge?"
+		}
+		if !confirm(prompt) {
+			return fmt.Errorf("%w; the merge is still in progress, rerun 'cgit conflicts' to finish it", git.ErrMergeConflict)
+		}
+	}
+
+	return finishMerge(repo)
+}
+
+// finishMerge commits an in-progress merge once no conflicts remain.
+func finishMerge(repo *git.GitRepo) error {
+	if !repo.MergeInProgress() {
+		return nil
+	}
+	remaining, err := repo.GetConflictedFiles()
+	if err != nil || len(remaining) > 0 {
+		return err
+	}
+	if err := repo.FinishMerge(); err != nil {
+		return err
+	}
+	fmt.Println("Conflicts resolved, merge committed.")
+	return nil
 }
 
 var pullCmd = &cobra.Command{
-	Use:   "pull",
+	Use:   "pull [branch]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "Pull latest changes from remote",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 		branchName, err := repo.GetCurrentBranch()
-		HandleError("getting current branch", err, true)
+		if err != nil {
+			return fail("getting current branch", err)
+		}
+
+		// The preview follows the upstream, so it is skipped when another
+		// branch is named.
+		yes, _ := cmd.Flags().GetBool("yes")
+		if len(args) == 0 && !yes && isInteractive() {
+			ok, err := previewPull(repo)
+			if err != nil || !ok {
+				return err
+			}
+		}
 
 		if len(args) > 0 {
 			branchName = args[0]
 		}
 
 		err = repo.PullLatestRemote(branchName)
-		HandleError("pulling latest changes", err, true)
+		if errors.Is(err, git.ErrMergeConflict) {
+			err = resolveMergeConflicts(repo)
+		}
+		if err != nil {
+			return fail("pulling latest changes", err)
+		}
 
 		fmt.Println("Successfully pulled latest changes for branch", branchName)
+		return nil
 	},
+	Annotations: needsTerminal,
 }
 
 var mergeCommand = &cobra.Command{
-	Use:   "merge",
+	Use:   "merge <branch>",
 	Short: "Fetch latest remote changes and merge",
-	Run: func(cmd *cobra.Command, args []string) {
-		branch := args[0]
+	Long: "Pull <branch> and merge it into the current branch. If the merge stops on conflicts it is left in progress: " +
+		"pass --resolve to open the conflict resolver straight away, or run 'cgit conflicts' later. " +
+		"'cgit merge --abort' undoes the merge instead.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 
-		err := repo.MergeLatest(branch)
-		HandleError("merging latest changes", err, true)
+		if abort, _ := cmd.Flags().GetBool("abort"); abort {
+			if err := repo.AbortMerge(); err != nil {
+				return fail("aborting merge", err)
+			}
+			fmt.Println("Merge aborted.")
+			return nil
+		}
+		if len(args) == 0 {
+			return fail("merging latest changes", errors.New("name the branch to merge"))
+		}
+
+		err := repo.MergeLatest(args[0])
+		if errors.Is(err, git.ErrMergeConflict) {
+			if resolve, _ := cmd.Flags().GetBool("resolve"); resolve {
+				err = resolveMergeConflicts(repo)
+			} else {
+				err = mergeConflictHint(repo)
+			}
+		}
+		if err != nil {
+			return fail("merging latest changes", err)
+		}
 
 		fmt.Println("Successfully merged latest changes.")
+		return nil
+	},
+	Annotations: needsTerminal,
+}
+
+// mergeConflictHint explains how to get out of a merge that stopped on
+// conflicts.
+func mergeConflictHint(repo *git.GitRepo) error {
+	conflicts, _ := repo.GetConflictedFiles()
+	return fmt.Errorf("%w in %d file(s); the merge is still in progress.\n"+
+		"  Resolve them with 'cgit conflicts' (or rerun with --resolve), or undo the merge with 'cgit merge --abort'",
+		git.ErrMergeConflict, len(conflicts))
+}
+
+var incomingCmd = &cobra.Command{
+	Use:   "incoming",
+	Short: "List the commits a pull would bring in",
+	Long:  "Fetch from origin, then list the commits on the current branch's upstream that are not in it yet.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		noFetch, _ := cmd.Flags().GetBool("no-fetch")
+		if !noFetch {
+			if err := repo.Fetch(); err != nil {
+				return fail("fetching", err)
+			}
+		}
+
+		commits, err := repo.IncomingCommits()
+		if errors.Is(err, git.ErrNoUpstream) {
+			err = fmt.Errorf("%w; push with 'cgit push -u' to set one", err)
+		}
+		if err != nil {
+			return fail("listing incoming commits", err)
+		}
+
+		if len(commits) == 0 {
+			fmt.Println("Up to date with the upstream; nothing to pull.")
+			return nil
+		}
+		printCommits(fmt.Sprintf("%d incoming commit(s):", len(commits)), commits)
+		return nil
+	},
+}
+
+var whatsNewCmd = &cobra.Command{
+	Use:   "whatsnew",
+	Short: "List the commits your last pull brought in",
+	Long: "List the commits on the current branch's upstream since before your last cgit pull, to see what others changed after a sync. " +
+		"Until cgit has recorded a pull, it lists what the last fetch brought in instead.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		commits, recorded, err := repo.WhatsNew()
+		if errors.Is(err, git.ErrNoUpstream) {
+			err = fmt.Errorf("%w; push with 'cgit push -u' to set one", err)
+		}
+		if err != nil {
+			return fail("listing new commits", err)
+		}
+
+		source := "your last pull"
+		if !recorded {
+			source = "the last fetch"
+		}
+		if len(commits) == 0 {
+			fmt.Printf("Nothing new from %s.\n", source)
+			return nil
+		}
+		printCommits(fmt.Sprintf("%d new commit(s) from %s:", len(commits), source), commits)
+		return nil
+	},
+}
+
+var compareCmd = &cobra.Command{
+	Use:       "compare [incoming|outgoing]",
+	Aliases:   []string{"cmp"},
+	Short:     "Diff the current branch against its upstream",
+	Long:      "Show what a pull would bring in (incoming, the default) or what a push would send (outgoing), as of the last fetch.",
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"incoming", "outgoing"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		direction := "incoming"
+		if len(args) == 1 {
+			direction = args[0]
+		}
+
+		if !repo.HasUpstream() {
+			return fail("comparing with upstream", fmt.Errorf("%w; push with 'cgit push -u' first", git.ErrNoUpstream))
+		}
+
+		err := ui.ShowUpstreamDiff(repo, direction)
+		return fail("comparing with upstream", err)
 	},
+	Annotations: needsTerminal,
 }
@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 	"github.com/corpeningc/cgit/internal/ui"
@@ -9,20 +14,144 @@ import (
 
 func init() {
 	rebaseCmd.Flags().IntP("limit", "n", 0, "Number of commits to show (default from config)")
+	rebaseCmd.Flags().Bool("abort", false, "Abandon a rebase that stopped part way")
 	rootCmd.AddCommand(rebaseCmd)
+	rootCmd.AddCommand(rebaseTodoCmd)
+	rootCmd.AddCommand(continueCmd)
+	rootCmd.AddCommand(abortCmd)
+}
+
+var continueCmd = &cobra.Command{
+	Use:   "continue",
+	Short: "Continue the merge, rebase, cherry-pick or revert in progress",
+	Long: "Run the --continue of whichever operation stopped part way, once its conflicts are resolved. " +
+		"Commit messages are kept as git prepared them.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+
+		if conflicts, err := repo.GetConflictedFiles(); err == nil && len(conflicts) > 0 {
+			return fail("continuing", fmt.Errorf("%d conflicted file(s) remain; resolve them with 'cgit conflicts' first", len(conflicts)))
+		}
+
+		op, err := repo.ContinueOperation()
+		if errors.Is(err, git.ErrMergeConflict) {
+			err = fmt.Errorf("%w; resolve them with 'cgit conflicts', then run 'cgit continue' again", err)
+		}
+		if err != nil {
+			return fail("continuing", err)
+		}
+
+		if next := repo.OperationInProgress(); next != git.OpNone {
+			fmt.Printf("The %s stopped again; run 'cgit continue' when it is ready.\n", next)
+			return nil
+		}
+		fmt.Printf("The %s is finished.\n", op)
+		return nil
+	},
+}
+
+var abortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Abort the merge, rebase, cherry-pick or revert in progress",
+	Long:  "Run the --abort of whichever operation stopped part way, putting the branch back where it was before it started.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		op, err := repo.AbortOperation()
+		if err != nil {
+			return fail("aborting", err)
+		}
+		fmt.Printf("The %s was aborted.\n", op)
+		return nil
+	},
 }
 
 var rebaseCmd = &cobra.Command{
 	Use:   "rebase",
 	Short: "Interactively rebase the last N commits",
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: "Pick, reword, squash, fixup, drop and reorder the last N commits, then rebase. If the rebase stops " +
+		"on conflicts or at an edit it is left in progress: finish it with 'cgit continue', or undo it " +
+		"with 'cgit abort'.",
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
+
+		if abort, _ := cmd.Flags().GetBool("abort"); abort {
+			if err := repo.AbortRebase(); err != nil {
+				return fail("aborting rebase", err)
+			}
+			fmt.Println("Rebase aborted.")
+			return nil
+		}
+		if repo.RebaseInProgress() {
+			return fail("rebasing", errors.New("a rebase is already in progress; finish it with 'cgit continue' or undo it with 'cgit abort'"))
+		}
+
 		cfg := config.Load()
 		limit, _ := cmd.Flags().GetInt("limit")
 		if limit <= 0 {
 			limit = cfg.RebaseLimit
 		}
-		err := ui.StartRebasePicker(repo, limit)
-		HandleError("rebasing", err, true)
+
+		self, err := os.Executable()
+		if err != nil {
+			return fail("rebasing", err)
+		}
+		err = ui.StartRebasePicker(repo, limit, sequenceEditor(self))
+		return fail("rebasing", err)
+	},
+	Annotations: needsTerminal,
+}
+
+// resolveRebaseConflicts opens the conflict resolver on a rebase that
+// stopped on conflicts and continues the rebase once they are resolved,
+// until it completes or the user leaves it in progress.
+func resolveRebaseConflicts(repo *git.GitRepo) error {
+	if !isInteractive() {
+		return fmt.Errorf("%w; resolve them with 'cgit conflicts', or undo the rebase with 'cgit rebase --abort'", git.ErrMergeConflict)
+	}
+
+	for {
+		finish, err := ui.StartConflictsPicker(repo)
+		if err != nil {
+			return err
+		}
+		if !finish {
+			return fmt.Errorf("%w; the rebase is still in progress, rerun 'cgit conflicts' to continue it or 'cgit rebase --abort' to undo it",
+				git.ErrMergeConflict)
+		}
+
+		err = repo.ContinueRebase()
+		if !errors.Is(err, git.ErrMergeConflict) {
+			if err == nil {
+				fmt.Println("Conflicts resolved, rebase finished.")
+			}
+			return err
+		}
+		fmt.Println("The next commit conflicts too.")
+	}
+}
+
+// rebaseTodoCmd is what git runs as the sequence editor during 'cgit
+// rebase', with the path of the todo list to edit.
+var rebaseTodoCmd = &cobra.Command{
+	Use:    "rebase-todo <file>",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := ui.EditRebaseTodo(args[0])
+		if errors.Is(err, ui.ErrRebaseCancelled) {
+			// A failing sequence editor makes git abandon the rebase.
+			os.Exit(1)
+		}
+		return fail("editing rebase todo", err)
 	},
 }
+
+// sequenceEditor builds the command git runs, through its shell, to edit
+// the todo list.
+func sequenceEditor(self string) string {
+	// Git for Windows runs the editor through bash, which wants forward slashes.
+	self = strings.ReplaceAll(self, `\`, `/`)
+	return "'" + strings.ReplaceAll(self, "'", `'\''`) + "' rebase-todo"
+}
@@ -1,53 +1,203 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
+	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
 )
 
-// HandleError prints a styled error and optionally exits.
-func HandleError(operation string, err error, close bool) {
+// HandleError prints a styled error for operation, if there is one.
+func HandleError(operation string, err error) {
 	if err != nil {
 		msg := strings.TrimSpace(err.Error())
 		fmt.Fprintf(os.Stderr, "\033[31;1m✗\033[0m %s: %s\n", operation, msg)
-		if close {
-			os.Exit(1)
-		}
 	}
 }
 
+// commandError is what a command returns when it gives up; Execute prints
+// it through HandleError.
+type commandError struct {
+	operation string
+	err       error
+}
+
+func (e *commandError) Error() string { return e.operation + ": " + e.err.Error() }
+func (e *commandError) Unwrap() error { return e.err }
+
+// fail wraps err with the operation that failed, or returns nil when
+// there is no error.
+func fail(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &commandError{operation: operation, err: err}
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "cgit",
 	Short: "A simplified git workflow tool",
 	Long:  "Simplifies common git operations with interactive interfaces",
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// Skip validation for shell command
-		if cmd.Name() == "shell" {
-			return
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Every command works on ".", so --repo moves there first.
+		if dir, _ := cmd.Flags().GetString("repo"); dir != "" {
+			repo, err := git.NewChecked(dir)
+			if err != nil {
+				return fail("opening --repo", err)
+			}
+			if err := os.Chdir(repo.WorkDir); err != nil {
+				return fail("opening --repo", err)
+			}
 		}
 
-		_, err := exec.LookPath("git")
-		HandleError("checking for git installation", err, true)
+		// The repository's .cgit.toml wins over the global config. Outside a
+		// repository there is none to read.
+		if root, err := git.New(".").TopLevel(); err == nil {
+			if err := config.UseRepo(root); err != nil {
+				return fail("reading "+config.RepoFile, err)
+			}
+		}
+
+		cfg := config.Load()
+		if !config.Exists() && isInteractive() && !isCompletionCommand(cmd) {
+			// Reload rather than take the wizard's answers, so the repository's
+			// overrides still apply; if saving failed that means the defaults.
+			_, err := ui.RunSetupWizard()
+			HandleError("running setup", err)
+			cfg = config.Load()
+		}
+		ui.ApplyTheme(cfg.Theme)
+
+		git.DefaultRetryPolicy = git.RetryPolicy{
+			Attempts: cfg.NetworkRetries,
+			Backoff:  time.Duration(cfg.NetworkBackoffMS) * time.Millisecond,
+		}
+		git.Verbose, _ = cmd.Flags().GetBool("verbose")
+		git.DefaultSignOff = cfg.CommitSignOff
+
+		git.DiffAlgorithm = cfg.DiffAlgorithm
+		git.RenameThreshold = cfg.RenameThreshold
+		if algorithm, _ := cmd.Flags().GetString("diff-algorithm"); algorithm != "" {
+			git.DiffAlgorithm = algorithm
+		}
+		if git.DiffAlgorithm != "" && !slices.Contains(git.DiffAlgorithms, git.DiffAlgorithm) {
+			return fail("choosing diff algorithm", fmt.Errorf("unknown algorithm %q (want one of %s)",
+				git.DiffAlgorithm, strings.Join(git.DiffAlgorithms, ", ")))
+		}
+		git.TerminalPrompts = isInteractive()
+
+		// Skip validation for the shell and for completion, which must not
+		// exit when invoked outside a repository.
+		if cmd.Name() == "shell" || isCompletionCommand(cmd) {
+			return nil
+		}
+
+		if _, err := exec.LookPath("git"); err != nil {
+			return fail("checking for git installation", err)
+		}
 
 		repo := git.New(".")
-		_, err = repo.GetCurrentBranch()
-		HandleError("checking for git repository", err, true)
+		_, err := repo.GetCurrentBranch()
+		return fail("checking for git repository", err)
 	},
 }
 
+// requireArg is cobra.ExactArgs(1) with an error that names the missing
+// argument. cobra prints the usage line after it.
+func requireArg(name string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		switch {
+		case len(args) == 0:
+			return fmt.Errorf("missing %s", name)
+		case len(args) > 1:
+			return fmt.Errorf("expected a single %s, got %d arguments (quote it if it contains spaces)", name, len(args))
+		}
+		return nil
+	}
+}
+
+// confirm asks a yes/no question on stdin; anything but "y" means no.
+func confirm(prompt string) bool {
+	fmt.Print(prompt + " [y/N] ")
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(input)) == "y"
+}
+
+// isInteractive reports whether stdin is a terminal, so TUIs are only
+// launched when someone is there to drive them.
+func isInteractive() bool {
+	return isTerminal(os.Stdin)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// isCompletionCommand reports whether cmd is part of shell completion,
+// which must never block on interactive input.
+func isCompletionCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "completion" || strings.HasPrefix(c.Name(), "__complete") {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the command line set on rootCmd and reports any error it
+// returns: a command's own failure as "✗ operation: error", anything cobra
+// rejected followed by the usage line.
 func Execute() error {
-	return rootCmd.Execute()
+	c, err := rootCmd.ExecuteC()
+	if err == nil {
+		return nil
+	}
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		HandleError(cmdErr.operation, cmdErr.err)
+	} else {
+		c.PrintErrln(c.ErrPrefix(), err.Error())
+		c.Println(c.UsageString())
+	}
+	return err
 }
 
 func init() {
-	// If no subcommand provided, launch interactive shell.
-	rootCmd.Run = func(cmd *cobra.Command, args []string) {
-		runInteractiveShell()
+	// Execute does the reporting, so only usage mistakes get the usage line.
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Show extra detail, such as network retry attempts")
+	rootCmd.PersistentFlags().StringP("repo", "C", "", "Run as if cgit was started in this directory")
+	rootCmd.PersistentFlags().String("diff-algorithm", "", "Diff algorithm for file diffs: myers, minimal, patience or histogram (overrides config diff_algorithm)")
+
+	rootCmd.Flags().Bool("tui", false, "Open the status viewer instead of the interactive shell")
+	rootCmd.Flags().BoolP("quiet", "q", false, "Skip the repository summary printed when the shell starts")
+
+	// If no subcommand provided, launch the interactive shell, or the status
+	// viewer when asked for with --tui or default_view.
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		tui, _ := cmd.Flags().GetBool("tui")
+		if tui || config.Load().DefaultView == "status" {
+			return handleStatusViewerExit(ui.StartStatusViewer(git.New(".")))
+		}
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		runInteractiveShell(quiet)
+		return nil
 	}
 	rootCmd.AddCommand(shellCmd)
 }
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+// runCgit runs a cgit command line the way the shell does and returns what
+// it wrote to stderr.
+func runCgit(t *testing.T, args ...string) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	// Cobra prints the usage line to its output, which is stderr unless set.
+	var cobraErr bytes.Buffer
+	rootCmd.SetErr(&cobraErr)
+	rootCmd.SetOut(&cobraErr)
+	defer func() {
+		os.Stderr = stderr
+		rootCmd.SetErr(nil)
+		rootCmd.SetOut(nil)
+	}()
+
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+	defer rootCmd.SetArgs([]string{})
+	runShellCommand()
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return cobraErr.String() + string(out)
+}
+
+func TestCommandsRejectMissingArgs(t *testing.T) {
+	useTestRepo(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"merge"}, "name the branch to merge"},
+		{[]string{"commit-and-push"}, "missing commit message"},
+		{[]string{"cap"}, "missing commit message"},
+		{[]string{"new-branch"}, "missing branch name"},
+		{[]string{"nb"}, "missing branch name"},
+		{[]string{"commit", "one", "two"}, "accepts at most 1 arg"},
+		{[]string{"switch", "one", "two"}, "accepts at most 1 arg"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if got := runCgit(t, tt.args...); !strings.Contains(got, tt.want) {
+				t.Errorf("stderr = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailedCommandLeavesShellRunning(t *testing.T) {
+	useTestRepo(t)
+
+	got := runCgit(t, "switch", "no-such-branch")
+	if !strings.Contains(got, "✗\033[0m switching branches:") {
+		t.Errorf("stderr = %q, want the styled switching branches error", got)
+	}
+	if strings.Contains(got, "Usage:") {
+		t.Errorf("stderr = %q, want no usage line for a failed command", got)
+	}
+
+	// The next command still runs.
+	runCgit(t, "new-branch", "next")
+	if branch, _ := git.New(".").GetCurrentBranch(); branch != "next" {
+		t.Errorf("current branch = %q, want next", branch)
+	}
+}
+
+func TestArgErrorPrintsUsage(t *testing.T) {
+	useTestRepo(t)
+	if got := runCgit(t, "new-branch"); !strings.Contains(got, "Usage:") {
+		t.Errorf("stderr = %q, want the usage line after a missing argument", got)
+	}
+}
+
+func TestCommandsAcceptNoArgs(t *testing.T) {
+	// Without an argument these open a TUI instead of failing.
+	for _, c := range []string{"commit", "switch"} {
+		cmd, _, err := rootCmd.Find([]string{c})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Args(cmd, nil); err != nil {
+			t.Errorf("%s with no args: %v", c, err)
+		}
+	}
+}
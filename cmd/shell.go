@@ -1,36 +1,79 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
+	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/peterh/liner"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+func init() {
+	shellCmd.Flags().BoolP("quiet", "q", false, "Skip the repository summary printed on startup")
+
+	ui.PaletteCommands = getCommandNames
+	ui.PaletteCommand = paletteCommand
+}
+
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Start an interactive cgit shell",
 	Long:  "Launch an interactive shell for running cgit commands without repeating 'cgit' prefix",
 	Run: func(cmd *cobra.Command, args []string) {
-		runInteractiveShell()
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		runInteractiveShell(quiet)
 	},
 }
 
-func runInteractiveShell() {
+// inShell is set while the interactive shell is running, so commands it
+// runs can hand control back to it instead of starting another one.
+var inShell bool
+
+// handleStatusViewerExit opens the shell when the status viewer was left
+// with ':'. Inside the shell, quitting the viewer already returns there.
+func handleStatusViewerExit(err error) error {
+	if errors.Is(err, ui.ErrOpenShell) {
+		if !inShell {
+			// The status viewer was just on screen; no need to summarise again.
+			runInteractiveShell(true)
+		}
+		return nil
+	}
+	return fail("showing status", err)
+}
+
+// runInteractiveShell starts the shell, printing a repository summary first
+// unless quiet is set or the shell_dashboard config is off.
+func runInteractiveShell(quiet bool) {
+	inShell = true
+	defer func() { inShell = false }()
+
 	line := liner.NewLiner()
 	defer line.Close()
 
 	line.SetCtrlCAborts(true)
 
 	// Load command history
-	historyFile := getHistoryFilePath()
-	if f, err := os.Open(historyFile); err == nil {
-		line.ReadHistory(f)
-		f.Close()
+	cfg := config.Load()
+	historyFile := historyFilePath(cfg)
+	if historyFile != "" && cfg.HistorySize > 0 {
+		if f, err := os.Open(historyFile); err == nil {
+			line.ReadHistory(f)
+			f.Close()
+		}
 	}
 
 	// Setup tab completion for command names
@@ -45,7 +88,10 @@ func runInteractiveShell() {
 	})
 
 	fmt.Println("cgit interactive shell. Type 'exit' or press Ctrl+D to quit.")
-	fmt.Println("Type 'help' to see available commands.")
+	fmt.Println("Type 'help' to see available commands, or 'status' to open the status viewer.")
+	if !quiet && cfg.ShellDashboard {
+		printDashboard(git.New("."))
+	}
 
 	for {
 		// Get current branch for prompt
@@ -88,10 +134,30 @@ func runInteractiveShell() {
 	}
 
 	// Save history on exit
-	if f, err := os.Create(historyFile); err == nil {
-		line.WriteHistory(f)
-		f.Close()
+	saveHistory(line, historyFile, cfg.HistorySize)
+}
+
+// saveHistory writes the newest size entries of the shell's history to
+// path. A size of 0, or no path, keeps no history.
+func saveHistory(line *liner.State, path string, size int) {
+	if path == "" || size <= 0 {
+		return
+	}
+	var buf bytes.Buffer
+	if _, err := line.WriteHistory(&buf); err != nil {
+		return
+	}
+	entries := strings.SplitAfter(buf.String(), "\n")
+	if entries[len(entries)-1] == "" {
+		entries = entries[:len(entries)-1]
+	}
+	if len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
 	}
+	_ = os.WriteFile(path, []byte(strings.Join(entries, "")), 0o600)
 }
 
 func handleSpecialCommand(input string) bool {
@@ -101,12 +167,26 @@ func handleSpecialCommand(input string) bool {
 		os.Exit(0)
 		return true
 	case "clear", "cls":
-		fmt.Print("\033[H\033[2J")
+		clearScreen()
 		return true
 	}
 	return false
 }
 
+// clearScreen clears the terminal. Windows consoles that predate ANSI
+// support need cls; terminals that set TERM there (mintty, Git Bash) and
+// everything else take the escape sequence.
+func clearScreen() {
+	if runtime.GOOS == "windows" && os.Getenv("TERM") == "" {
+		cls := exec.Command("cmd", "/c", "cls")
+		cls.Stdout = os.Stdout
+		if cls.Run() == nil {
+			return
+		}
+	}
+	fmt.Print("\033[H\033[2J")
+}
+
 func executeCommand(input string) {
 	// Parse input into command and args
 	parts := parseCommandLine(input)
@@ -114,17 +194,177 @@ func executeCommand(input string) {
 		return
 	}
 
+	// Cobra keeps flag values between Execute calls, so clear what the
+	// previous command set before parsing this one.
+	resetFlags(rootCmd)
+
 	// Reset rootCmd args and execute
 	rootCmd.SetArgs(parts)
+	defer rootCmd.SetArgs([]string{})
 
-	// Capture the command execution
-	// We need to handle errors differently in shell mode - don't exit
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	c, args, err := rootCmd.Find(parts)
+	if err != nil || !config.Load().ShellPager || !isTerminal(os.Stdout) || shellNeedsTerminal(c, args) {
+		runShellCommand()
+		return
 	}
+	pageOutput(captureStdout(runShellCommand))
+}
+
+// runShellCommand executes the args set on rootCmd. Execute has already
+// reported any error, and a failed command leaves the shell running.
+func runShellCommand() {
+	// --repo moves into its directory; that is for this command only.
+	if wd, err := os.Getwd(); err == nil {
+		defer os.Chdir(wd)
+	}
+	Execute()
+}
+
+// terminalAnnotation marks, in a command's Annotations, a command that
+// takes over the terminal: it opens a TUI or an editor, or may stop to ask
+// a question. The shell doesn't capture such a command's output for the
+// pager, and the status viewer's command palette hands it the terminal.
+const terminalAnnotation = "cgit_needs_terminal"
+
+// needsTerminal and needsTerminalWithoutArgs are the Annotations of those
+// commands; the second is for commands that only take over the terminal
+// when given no arguments, like commit without a message. They are shared,
+// so they must not be changed.
+var (
+	needsTerminal            = map[string]string{terminalAnnotation: "always"}
+	needsTerminalWithoutArgs = map[string]string{terminalAnnotation: "without-args"}
+)
+
+// commandNeedsTerminal reports whether running c with args, as returned by
+// rootCmd.Find, takes over the terminal.
+func commandNeedsTerminal(c *cobra.Command, args []string) bool {
+	switch c.Annotations[terminalAnnotation] {
+	case "":
+		return false
+	case needsTerminalWithoutArgs[terminalAnnotation]:
+		return len(positionalArgs(c, args)) == 0
+	}
+	return true
+}
+
+// shellNeedsTerminal reports whether the command line must write straight
+// to the terminal instead of having its output captured for the pager.
+func shellNeedsTerminal(c *cobra.Command, args []string) bool {
+	return commandNeedsTerminal(c, args) || c == rootCmd
+}
+
+// positionalArgs returns the arguments in args that are neither flags nor
+// flag values, going by the flags c takes. Unlike c.ParseFlags it leaves
+// the flags unset, since the command still has to parse them itself.
+func positionalArgs(c *cobra.Command, args []string) []string {
+	flags := pflag.NewFlagSet(c.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(c.Flags())
+	flags.AddFlagSet(c.InheritedFlags())
+	takesValue := func(f *pflag.Flag) bool { return f != nil && f.NoOptDefVal == "" }
+
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(positional, args[i+1:]...)
+		case strings.HasPrefix(arg, "--"):
+			name, _, inline := strings.Cut(arg[2:], "=")
+			if takesValue(flags.Lookup(name)) && !inline {
+				i++
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// Shorthands can be grouped, as in -as. The first that takes a
+			// value takes the rest of arg, or else the next argument.
+			for j := 1; j < len(arg); j++ {
+				if takesValue(flags.ShorthandLookup(arg[j : j+1])) {
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return positional
+}
+
+// captureStdout runs fn with stdout redirected into a buffer and returns
+// what it printed. Stderr is left alone so errors still show immediately.
+func captureStdout(fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		fn()
+		return ""
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	rootCmd.SetOut(w)
+
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		r.Close()
+		done <- buf.String()
+	}()
 
-	// Reset args for next command
-	rootCmd.SetArgs([]string{})
+	defer func() {
+		os.Stdout = stdout
+		rootCmd.SetOut(nil)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// pageOutput shows output through $PAGER (less by default), which exits
+// straight away when the output fits on one screen, as git does. It falls
+// back to printing when no pager can be started.
+func pageOutput(output string) {
+	if output == "" {
+		return
+	}
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	fields := strings.Fields(pager)
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	cmd.Stdin = strings.NewReader(output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Print(output)
+	}
+}
+
+// resetFlags puts every flag of cmd and its subcommands back to its
+// default and marks it unchanged.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		// Setting a slice flag appends, so replace its contents instead.
+		if slice, ok := f.Value.(interface{ Replace([]string) error }); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
 }
 
 func parseCommandLine(input string) []string {
@@ -159,10 +399,35 @@ func parseCommandLine(input string) []string {
 	return parts
 }
 
+// paletteCommand builds a child cgit process for a line typed into the
+// status viewer's command palette. Running it as a separate process keeps
+// a failing command's exit from taking the viewer down with it.
+func paletteCommand(input string) (*exec.Cmd, bool, error) {
+	parts := parseCommandLine(input)
+	if len(parts) == 0 {
+		return nil, false, errors.New("no command given")
+	}
+
+	c, args, err := rootCmd.Find(parts)
+	if err != nil || c == rootCmd {
+		return nil, false, fmt.Errorf("unknown command %q", parts[0])
+	}
+	if c == shellCmd {
+		return nil, false, errors.New("press ! to open the shell")
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return exec.Command(self, parts...), commandNeedsTerminal(c, args), nil
+}
+
 func getCommandNames() []string {
 	var names []string
 	for _, cmd := range rootCmd.Commands() {
-		if cmd.Name() == "shell" {
+		if cmd.Name() == "shell" || cmd.Hidden {
 			continue
 		}
 		names = append(names, cmd.Name())
@@ -170,10 +435,107 @@ func getCommandNames() []string {
 	return names
 }
 
-func getHistoryFilePath() string {
-	homeDir, err := os.UserHomeDir()
+// historyFilePath returns where the shell keeps its history: history_file
+// when set, else $XDG_DATA_HOME/cgit/history, else ~/.cgit_history. It
+// returns "" when there is no home directory to fall back on, and history
+// is then not kept. A history left in ~/.cgit_history from before
+// XDG_DATA_HOME was set is moved to the XDG location the first time.
+func historyFilePath(cfg config.Config) string {
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return ".cgit_history"
+		home = ""
+	}
+	if cfg.HistoryFile != "" {
+		return expandHome(cfg.HistoryFile, home)
+	}
+	var legacy string
+	if home != "" {
+		legacy = filepath.Join(home, ".cgit_history")
+	}
+	// The XDG spec says to ignore a relative XDG_DATA_HOME.
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		path := filepath.Join(dir, "cgit", "history")
+		if legacy == "" || !isMissing(path) || isMissing(legacy) {
+			return path
+		}
+		if os.MkdirAll(filepath.Dir(path), 0o755) != nil || os.Rename(legacy, path) != nil {
+			// Keep using the old file rather than starting over.
+			return legacy
+		}
+		return path
+	}
+	return legacy
+}
+
+// isMissing reports whether nothing exists at path.
+func isMissing(path string) bool {
+	_, err := os.Lstat(path)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// expandHome replaces a leading ~ in path with home, as a shell would. The
+// path is left alone when home is unknown.
+func expandHome(path, home string) string {
+	if home == "" {
+		return path
+	}
+	if path == "~" {
+		return home
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(home, rest)
+	}
+	if rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator)); ok {
+		return filepath.Join(home, rest)
+	}
+	return path
+}
+
+// printDashboard prints a short summary of the repository: branch and
+// upstream state, pending changes, stashes and the last commit. It prints
+// nothing outside a repository.
+func printDashboard(repo *git.GitRepo) {
+	// The three lookups are independent, so they run side by side; each
+	// writes only its own results.
+	var (
+		status    *git.RepoStatus
+		stashes   []git.StashEntry
+		last      git.CommitInfo
+		err       error
+		stashErr  error
+		commitErr error
+		wg        sync.WaitGroup
+	)
+	wg.Add(3)
+	go func() { defer wg.Done(); status, err = repo.GetRepositoryStatus() }()
+	go func() { defer wg.Done(); stashes, stashErr = repo.StashList() }()
+	go func() { defer wg.Done(); last, commitErr = repo.LastCommit() }()
+	wg.Wait()
+	if err != nil {
+		return
+	}
+
+	branch := status.CurrentBranch
+	if status.Upstream != "" {
+		branch += fmt.Sprintf("  ↑%d ↓%d", status.Ahead, status.Behind)
+	} else {
+		branch += "  (no upstream)"
+	}
+
+	changes := fmt.Sprintf("%d staged, %d unstaged, %d untracked",
+		len(status.StagedFiles), len(status.UnstagedFiles), len(status.UntrackedFiles))
+
+	parts := []string{branch, changes}
+	if stashErr == nil && len(stashes) == 1 {
+		parts = append(parts, "1 stash")
+	} else if len(stashes) > 1 {
+		parts = append(parts, fmt.Sprintf("%d stashes", len(stashes)))
+	}
+
+	fmt.Println()
+	fmt.Println("  " + strings.Join(parts, "  ·  "))
+	if commitErr == nil {
+		fmt.Printf("  last: %s %s (%s)\n", last.ShortHash, last.Subject, last.Date)
 	}
-	return filepath.Join(homeDir, ".cgit_history")
+	fmt.Println()
 }
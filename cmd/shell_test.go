@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corpeningc/cgit/internal/config"
+	"github.com/corpeningc/cgit/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// useTestRepo runs the test inside a fresh repository with one commit, as
+// if cgit had been started there, with a config file of its own.
+func useTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	t.Chdir(dir)
+	t.Setenv("CGIT_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+
+	// Run commands as the shell does.
+	inShell = true
+	t.Cleanup(func() { inShell = false })
+	return dir
+}
+
+func TestShellResetsFlagsBetweenCommands(t *testing.T) {
+	useTestRepo(t)
+
+	var seen []bool
+	probe := &cobra.Command{
+		Use: "probe",
+		Run: func(cmd *cobra.Command, args []string) {
+			staged, _ := cmd.Flags().GetBool("staged")
+			seen = append(seen, staged)
+		},
+	}
+	probe.Flags().BoolP("staged", "s", false, "")
+	rootCmd.AddCommand(probe)
+	t.Cleanup(func() { rootCmd.RemoveCommand(probe) })
+
+	executeCommand("probe -s")
+	executeCommand("probe")
+	if len(seen) != 2 || !seen[0] || seen[1] {
+		t.Fatalf("staged flag over two runs = %v, want [true false]", seen)
+	}
+
+	// Flags of commands other than the one that ran are reset too, as
+	// `manage -s` followed by `manage` needs.
+	staged := manageCmd.Flags().Lookup("staged")
+	if err := staged.Value.Set("true"); err != nil {
+		t.Fatal(err)
+	}
+	staged.Changed = true
+	executeCommand("probe")
+	if staged.Value.String() != "false" || staged.Changed {
+		t.Errorf("manage --staged after the next command = %s (changed %v), want false", staged.Value, staged.Changed)
+	}
+}
+
+func TestShellNeedsTerminal(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"commit", true},
+		{"commit -a", true},
+		{"commit --all", true},
+		{"commit -v", true},
+		{"commit 'fix the build'", false},
+		{"commit -a 'fix the build'", false},
+		{"commit --repo . 'fix the build'", false},
+		{"commit -C . 'fix the build'", false},
+		{"commit -- -a", false},
+		{"commit -s", true},
+		{"commit -as", true},
+		{"commit --signoff", true},
+		{"commit -as 'fix the build'", false},
+		{"amend -s", true},
+		{"log", true},
+		{"push", true},
+		{"rm a.txt", true},
+		{"gc", true},
+		{"undo", false},
+		{"export -o patches", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			c, args, err := rootCmd.Find(parseCommandLine(tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := shellNeedsTerminal(c, args); got != tt.want {
+				t.Errorf("shellNeedsTerminal(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDashboardShowsEveryLookup(t *testing.T) {
+	dir := useTestRepo(t)
+	for _, args := range [][]string{
+		{"commit", "-q", "--allow-empty", "-m", "second"},
+		{"stash", "-q", "--include-untracked"},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(func() { printDashboard(git.New(dir)) })
+	for _, want := range []string{"main  (no upstream)", "0 staged, 0 unstaged, 1 untracked", "1 stash", "last: ", " second ("} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dashboard lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHistoryFilePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	xdg := t.TempDir()
+
+	t.Setenv("XDG_DATA_HOME", "")
+	if got, want := historyFilePath(config.Config{}), filepath.Join(home, ".cgit_history"); got != want {
+		t.Errorf("without XDG_DATA_HOME = %q, want %q", got, want)
+	}
+	if got, want := historyFilePath(config.Config{HistoryFile: "~/hist/cgit"}), filepath.Join(home, "hist", "cgit"); got != want {
+		t.Errorf("history_file ~/hist/cgit = %q, want %q", got, want)
+	}
+	if got, want := historyFilePath(config.Config{HistoryFile: "/tmp/~x"}), "/tmp/~x"; got != want {
+		t.Errorf("history_file /tmp/~x = %q, want %q", got, want)
+	}
+
+	// An old history moves to the XDG location once XDG_DATA_HOME is set.
+	legacy := filepath.Join(home, ".cgit_history")
+	if err := os.WriteFile(legacy, []byte("status\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_DATA_HOME", xdg)
+	want := filepath.Join(xdg, "cgit", "history")
+	if got := historyFilePath(config.Config{}); got != want {
+		t.Fatalf("with XDG_DATA_HOME = %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(want); err != nil || string(data) != "status\n" {
+		t.Errorf("XDG history = %q, %v; want the old history", data, err)
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		t.Error("old history was left behind")
+	}
+
+	// With both present, the XDG one wins and the old one is left alone.
+	if err := os.WriteFile(legacy, []byte("log\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	historyFilePath(config.Config{})
+	if data, _ := os.ReadFile(want); string(data) != "status\n" {
+		t.Errorf("XDG history = %q, want it untouched", data)
+	}
+}
+
+func TestShellRepoFlagLastsOneCommand(t *testing.T) {
+	useTestRepo(t)
+	start, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", other},
+		{"-C", other, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	var ranIn string
+	probe := &cobra.Command{
+		Use: "probe",
+		Run: func(cmd *cobra.Command, args []string) {
+			ranIn, _ = os.Getwd()
+		},
+	}
+	rootCmd.AddCommand(probe)
+	t.Cleanup(func() { rootCmd.RemoveCommand(probe) })
+
+	executeCommand("--repo " + other + " probe")
+	if want, _ := filepath.EvalSymlinks(other); ranIn != want {
+		t.Errorf("command ran in %q, want %q", ranIn, want)
+	}
+	if wd, _ := os.Getwd(); wd != start {
+		t.Errorf("after the command the shell is in %q, want %q", wd, start)
+	}
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 	"github.com/corpeningc/cgit/internal/ui"
 	"github.com/spf13/cobra"
@@ -12,23 +14,62 @@ func init() {
 	rootCmd.AddCommand(popCmd)
 	rootCmd.AddCommand(storeCmd)
 	rootCmd.AddCommand(fullCleanCmd)
+	gcCmd.Flags().Bool("aggressive", false, "Repack much harder; slow, but can reclaim more space in old repositories")
+	rootCmd.AddCommand(gcCmd)
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Compress the repository and report the space reclaimed",
+	Long:  "Run git gc to repack objects and prune unreachable ones, printing the object counts and sizes before and after.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo := git.New(".")
+		aggressive, _ := cmd.Flags().GetBool("aggressive")
+
+		before, err := repo.RepoSize()
+		if err != nil {
+			return fail("measuring repository", err)
+		}
+		fmt.Println("Before:", before)
+
+		gc := func() error { return repo.GC(aggressive) }
+		if isInteractive() && isTerminal(os.Stdout) {
+			err = ui.RunWithSpinner("Running git gc...", gc)
+		} else {
+			err = gc()
+		}
+		if err != nil {
+			return fail("running gc", err)
+		}
+
+		after, err := repo.RepoSize()
+		if err != nil {
+			return fail("measuring repository", err)
+		}
+		fmt.Println("After: ", after)
+		return nil
+	},
+	Annotations: needsTerminal,
 }
 
 var popCmd = &cobra.Command{
 	Use:   "pop",
 	Short: "Interactively select and pop a stash",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 
 		err := ui.StartStashPicker(repo)
-		HandleError("popping stash", err, true)
+		return fail("popping stash", err)
 	},
+	Annotations: needsTerminal,
 }
 
 var storeCmd = &cobra.Command{
-	Use:   "store",
+	Use:   "store [name]",
+	Args:  cobra.MaximumNArgs(1),
 	Short: "Store changes in a stash",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 		var err error
 
@@ -39,9 +80,12 @@ var storeCmd = &cobra.Command{
 			err = repo.Stash("")
 		}
 
-		HandleError("stashing changes", err, true)
+		if err != nil {
+			return fail("stashing changes", err)
+		}
 
 		fmt.Println("Successfully stored changes.")
+		return nil
 	},
 }
 
@@ -49,12 +93,23 @@ var fullCleanCmd = &cobra.Command{
 	Use:     "full-clean",
 	Aliases: []string{"fc"},
 	Short:   "Hard reset branch; Clean files and directories",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo := git.New(".")
 
+		if config.Load().ConfirmDestructive {
+			if !confirm("This discards all uncommitted changes and untracked files. Continue?") {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+
 		err := repo.FullClean()
-		HandleError("performing full clean", err, true)
+		if err != nil {
+			return fail("performing full clean", err)
+		}
 
 		fmt.Println("Successfully cleaned repository.")
+		return nil
 	},
+	Annotations: needsTerminal,
 }
@@ -7,32 +7,73 @@ import (
 )
 
 type Config struct {
-	LogLimit    int    `json:"log_limit"`
-	RebaseLimit int    `json:"rebase_limit"`
-	SplitPane   bool   `json:"split_pane"`
-	Editor      string `json:"editor"`
+	LogLimit           int    `json:"log_limit"`
+	RebaseLimit        int    `json:"rebase_limit"`
+	SplitPane          bool   `json:"split_pane"`
+	Editor             string `json:"editor"`
+	Theme              string `json:"theme"`
+	BaseBranch         string `json:"base_branch"`
+	ConfirmDestructive bool   `json:"confirm_destructive"`
+	FullFileMaxLines   int    `json:"full_file_max_lines"`
+	NetworkRetries     int    `json:"network_retries"`
+	NetworkBackoffMS   int    `json:"network_backoff_ms"`
+	DefaultView        string `json:"default_view"`
+	ShellDashboard     bool   `json:"shell_dashboard"`
+	DiffAlgorithm      string `json:"diff_algorithm"`
+	RenameThreshold    int    `json:"rename_threshold"`
+	ShellPager         bool   `json:"shell_pager"`
+	WIPPattern         string `json:"wip_pattern"`
+	CommitUseEditor    bool   `json:"commit_use_editor"`
+	CommitSignOff      bool   `json:"commit_signoff"`
+	SubjectMaxLength   int    `json:"subject_max_length"`
+	HistoryFile        string `json:"history_file"`
+	HistorySize        int    `json:"history_size"`
+	ShowHelp           bool   `json:"show_help"`
 }
 
 func Default() Config {
 	return Config{
-		LogLimit:    100,
-		RebaseLimit: 15,
-		SplitPane:   true,
-		Editor:      "",
+		LogLimit:           100,
+		RebaseLimit:        15,
+		SplitPane:          true,
+		Editor:             "",
+		Theme:              "default",
+		BaseBranch:         "",
+		ConfirmDestructive: true,
+		FullFileMaxLines:   2000,
+		NetworkRetries:     3,
+		NetworkBackoffMS:   1000,
+		DefaultView:        "shell",
+		ShellDashboard:     true,
+		RenameThreshold:    50,
+		ShellPager:         true,
+		WIPPattern:         `(?i)^(wip\b|fixup!|squash!|amend!)`,
+		SubjectMaxLength:   72,
+		HistorySize:        500,
+		ShowHelp:           true,
 	}
 }
 
 // Load reads the config file, returning defaults for any missing values.
+// Settings from the repository's RepoFile, once UseRepo has read it, win
+// over both.
 func Load() Config {
 	cfg := Default()
-	data, err := os.ReadFile(Path())
-	if err != nil {
-		return cfg
+	if data, err := os.ReadFile(Path()); err == nil {
+		_ = json.Unmarshal(data, &cfg)
 	}
-	_ = json.Unmarshal(data, &cfg)
+	// UseRepo has already checked the overrides apply cleanly.
+	_ = applyOverrides(&cfg, repoOverrides)
 	return cfg
 }
 
+// Exists reports whether a config file has been written yet.
+// Its absence is how cgit detects a first run.
+func Exists() bool {
+	_, err := os.Stat(Path())
+	return err == nil
+}
+
 // Save writes cfg to the config file, creating the directory if needed.
 func Save(cfg Config) error {
 	p := Path()
@@ -46,6 +87,20 @@ func Save(cfg Config) error {
 	return os.WriteFile(p, data, 0o644)
 }
 
+// Update applies change to the config file and saves it. The repository's
+// overrides are left out, so a setting toggled from a view is remembered
+// without copying them into the global file.
+func Update(change func(*Config)) error {
+	cfg := Default()
+	if data, err := os.ReadFile(Path()); err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+	}
+	change(&cfg)
+	return Save(cfg)
+}
+
 // Path returns the config file path, respecting CGIT_CONFIG env var.
 func Path() string {
 	if p := os.Getenv("CGIT_CONFIG"); p != "" {
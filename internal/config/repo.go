@@ -0,0 +1,206 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RepoFile is the per-repository config file, read from the repository
+// root. Its settings win over the global config file.
+const RepoFile = ".cgit.toml"
+
+// repoOverrides holds the settings read from RepoFile by UseRepo, keyed by
+// their JSON names, and repoPath where they came from.
+var (
+	repoOverrides map[string]any
+	repoPath      string
+)
+
+// RepoPath returns the RepoFile UseRepo read, or "" when there was none.
+func RepoPath() string {
+	return repoPath
+}
+
+// UseRepo reads root's RepoFile so that Load applies it on top of the
+// global config. A missing file is not an error.
+func UseRepo(root string) error {
+	repoOverrides, repoPath = nil, ""
+	path := filepath.Join(root, RepoFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	overrides, err := parseRepoFile(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// Check the types now, so a bad value is reported once rather than
+	// silently dropped by every Load.
+	var cfg Config
+	if err := applyOverrides(&cfg, overrides); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	repoOverrides, repoPath = overrides, path
+	return nil
+}
+
+func applyOverrides(cfg *Config, overrides map[string]any) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(data, cfg); errors.As(err, &typeErr) {
+		return fmt.Errorf("%s should be %s, not %s", typeErr.Field, kindName(typeErr.Type.Kind()), typeErr.Value)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func kindName(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.String:
+		return "a string"
+	default:
+		return "a number"
+	}
+}
+
+// repoKeys are the settings a RepoFile may set: conventions a team shares.
+// Everything else is personal (which editor to run, where history goes,
+// whether to be asked before destructive commands), so a repository that
+// is cloned should not be able to change it.
+var repoKeys = map[string]bool{
+	"base_branch":        true,
+	"wip_pattern":        true,
+	"subject_max_length": true,
+	"rename_threshold":   true,
+	"diff_algorithm":     true,
+	"commit_signoff":     true,
+}
+
+// configKeys lists the JSON names of Config's fields.
+func configKeys() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// parseRepoFile reads the flat subset of TOML the config needs: one
+// `key = value` per line, where value is a quoted string, an integer or a
+// boolean. Keys are those of the global config file that are in repoKeys.
+func parseRepoFile(content string) (map[string]any, error) {
+	known := configKeys()
+	overrides := make(map[string]any)
+	for i, line := range strings.Split(content, "\n") {
+		lineNo := i + 1
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported; put settings at the top level", lineNo)
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		if !known[key] {
+			return nil, fmt.Errorf("line %d: unknown setting %q", lineNo, key)
+		}
+		if !repoKeys[key] {
+			return nil, fmt.Errorf("line %d: %s is a personal setting and can only be set in your own config", lineNo, key)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+		overrides[key] = value
+	}
+	return overrides, nil
+}
+
+// parseTOMLValue parses a string, integer or boolean, with an optional
+// trailing comment.
+func parseTOMLValue(raw string) (any, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := closingQuote(raw)
+		if end < 0 {
+			return nil, errors.New("unterminated string")
+		}
+		if err := onlyComment(raw[end+1:]); err != nil {
+			return nil, err
+		}
+		return strconv.Unquote(raw[:end+1])
+
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return nil, errors.New("unterminated string")
+		}
+		if err := onlyComment(raw[end+2:]); err != nil {
+			return nil, err
+		}
+		return raw[1 : end+1], nil
+	}
+
+	value, _, _ := strings.Cut(raw, "#")
+	value = strings.TrimSpace(value)
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %q: quote strings, and use true/false or a whole number otherwise", value)
+	}
+	return n, nil
+}
+
+// closingQuote returns the index of the quote that ends the basic string
+// starting at s[0], or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func onlyComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after the value", rest)
+	}
+	return nil
+}
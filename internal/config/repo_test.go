@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRepoFile(t *testing.T) {
+	tests := []struct {
+		content string
+		want    map[string]any
+		err     string
+	}{
+		{
+			content: "# team defaults\nbase_branch = \"develop\"\nsubject_max_length = 50 # short\ncommit_signoff = true\n",
+			want:    map[string]any{"base_branch": "develop", "subject_max_length": int64(50), "commit_signoff": true},
+		},
+		{content: "wip_pattern = '^WIP'\r\n", want: map[string]any{"wip_pattern": "^WIP"}},
+		{content: "editor = \"vim\"\n", err: "line 1: editor is a personal setting"},
+		{content: "base_branch = \"main\"\nhistory_file = \"/tmp/h\"\n", err: "line 2: history_file is a personal setting"},
+		{content: "confirm_destructive = false\n", err: "confirm_destructive is a personal setting"},
+		{content: "commit_use_editor = true\n", err: "commit_use_editor is a personal setting"},
+		{content: "default_remote = \"upstream\"\n", err: `unknown setting "default_remote"`},
+		{content: "[core]\n", err: "tables are not supported"},
+	}
+	for _, tt := range tests {
+		got, err := parseRepoFile(tt.content)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseRepoFile(%q) error = %v, want one containing %q", tt.content, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRepoFile(%q): %v", tt.content, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRepoFile(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestRepoKeysAreConfigKeys(t *testing.T) {
+	known := configKeys()
+	for key := range repoKeys {
+		if !known[key] {
+			t.Errorf("repoKeys has %q, which is not a config setting", key)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BranchCacheTTL is how long SwitcherBranches reuses the branch list read
+// by an earlier call, including one from a previous run. Branch changes
+// made through GitRepo clear it straight away; ones made with git directly
+// show up once it expires or after InvalidateBranchCache.
+var BranchCacheTTL = 30 * time.Second
+
+// branchCacheFile holds the last `git branch -a` output. It lives in the
+// worktree's own git dir: each worktree marks its checked-out branch
+// differently, so worktrees don't share it.
+const branchCacheFile = "cgit-branches"
+
+// branchCachePath returns where the branch list is cached, or "" outside
+// a repository.
+func (repo *GitRepo) branchCachePath() string {
+	if repo.gitDir == "" {
+		cmd := exec.Command("git", "rev-parse", "--absolute-git-dir")
+		cmd.Dir = repo.WorkDir
+		out, err := cmd.Output()
+		if err != nil {
+			return ""
+		}
+		repo.gitDir = strings.TrimSpace(string(out))
+	}
+	return filepath.Join(repo.gitDir, branchCacheFile)
+}
+
+// SwitcherBranches is GetAllBranches for the branch switcher, which is
+// opened over and over: the list may come from the branch cache (see
+// BranchCacheTTL). Everything else should call GetAllBranches, which
+// always asks git.
+func (repo *GitRepo) SwitcherBranches(remote bool) ([]string, error) {
+	output, ok := repo.cachedBranchList()
+	if !ok {
+		var err error
+		output, err = repo.branchList()
+		if err != nil {
+			return nil, err
+		}
+		repo.storeBranchList(output)
+	}
+	return parseBranchList(output, remote), nil
+}
+
+// cachedBranchList returns the cached `git branch -a` output if it is
+// younger than BranchCacheTTL.
+func (repo *GitRepo) cachedBranchList() (string, bool) {
+	path := repo.branchCachePath()
+	if path == "" || BranchCacheTTL <= 0 {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) >= BranchCacheTTL {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// storeBranchList caches output for later calls. Failing to write only
+// costs the next call a trip to git, so errors are ignored.
+func (repo *GitRepo) storeBranchList(output string) {
+	if path := repo.branchCachePath(); path != "" && BranchCacheTTL > 0 {
+		_ = os.WriteFile(path, []byte(output), 0o644)
+	}
+}
+
+// InvalidateBranchCache makes the next SwitcherBranches read the branch
+// list from git again.
+func (repo *GitRepo) InvalidateBranchCache() {
+	if path := repo.branchCachePath(); path != "" {
+		_ = os.Remove(path)
+	}
+}
@@ -3,38 +3,84 @@ package git
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 )
 
-// GetDefaultBranch returns the repo's primary branch name.
-// It tries the remote HEAD ref first, then checks for local main/master, then falls back to "main".
-func (repo *GitRepo) GetDefaultBranch() string {
-	// Try origin's HEAD ref (set by git clone / git remote set-head)
-	cmd := exec.Command("git", "symbolic-ref", "refs/remotes/origin/HEAD")
+// ErrNoDefaultBranch is returned by DefaultBranch when origin/HEAD is unset
+// and none of the usual branch names exist.
+var ErrNoDefaultBranch = errors.New("can't tell which branch is the default")
+
+// defaultBranchNames are tried, in order, when origin/HEAD is unset.
+var defaultBranchNames = []string{"main", "master", "trunk"}
+
+// DefaultBranch returns the repo's primary branch: the one origin/HEAD
+// points at (set by git clone or git remote set-head), else the first of
+// main, master and trunk that exists locally or on origin. The result is
+// cached for the life of repo.
+func (repo *GitRepo) DefaultBranch() (string, error) {
+	if repo.defaultBranch != "" {
+		return repo.defaultBranch, nil
+	}
+
+	cmd := exec.Command("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
 	cmd.Dir = repo.WorkDir
 	if out, err := cmd.Output(); err == nil {
-		ref := strings.TrimSpace(string(out))
-		// ref is like "refs/remotes/origin/main"
-		if idx := strings.LastIndex(ref, "/"); idx >= 0 {
-			return ref[idx+1:]
+		// out is like "origin/main"
+		if _, branch, ok := strings.Cut(strings.TrimSpace(string(out)), "/"); ok && branch != "" {
+			repo.defaultBranch = branch
+			return branch, nil
 		}
 	}
 
-	// Fall back to checking whether main or master exist locally
-	for _, branch := range []string{"main", "master"} {
-		check := exec.Command("git", "rev-parse", "--verify", branch)
-		check.Dir = repo.WorkDir
-		if check.Run() == nil {
-			return branch
+	for _, branch := range defaultBranchNames {
+		for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/origin/" + branch} {
+			check := exec.Command("git", "rev-parse", "--verify", "--quiet", ref)
+			check.Dir = repo.WorkDir
+			if check.Run() == nil {
+				repo.defaultBranch = branch
+				return branch, nil
+			}
 		}
 	}
+	return "", ErrNoDefaultBranch
+}
 
+// GetDefaultBranch is DefaultBranch for callers that need a name either
+// way: it falls back to "main" when nothing can be detected.
+func (repo *GitRepo) GetDefaultBranch() string {
+	if branch, err := repo.DefaultBranch(); err == nil {
+		return branch
+	}
 	return "main"
 }
 
+// SetDefaultBranch points origin/HEAD at branch, which must already exist
+// as origin/<branch>, so DefaultBranch reports it from now on. An empty
+// branch asks the remote for its default instead.
+func (repo *GitRepo) SetDefaultBranch(branch string) error {
+	repo.defaultBranch = ""
+	if branch == "" {
+		return repo.runNetwork("set default branch", nil, "remote", "set-head", "origin", "--auto")
+	}
+
+	verify := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch)
+	verify.Dir = repo.WorkDir
+	if verify.Run() != nil {
+		return fmt.Errorf("%w: origin/%s", ErrNoSuchRemoteBranch, branch)
+	}
+
+	cmd := exec.Command("git", "remote", "set-head", "origin", branch)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	return formatCommandError("set default branch", cmd.Run(), stdout, stderr)
+}
+
 func (repo *GitRepo) GetCurrentBranch() (string, error) {
 	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
 	cmd.Env = os.Environ()
@@ -48,6 +94,9 @@ func (repo *GitRepo) GetCurrentBranch() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// MergeLatest pulls branch and merges it into the current branch. When the
+// merge stops on conflicts the error matches ErrMergeConflict and the merge
+// is left in progress, to be resolved or undone with AbortMerge.
 func (repo *GitRepo) MergeLatest(branch string) error {
 	currentBranch, err := repo.GetCurrentBranch()
 	if err != nil {
@@ -56,22 +105,14 @@ func (repo *GitRepo) MergeLatest(branch string) error {
 
 	// Don't merge into the default branch directly — just pull
 	if currentBranch == repo.GetDefaultBranch() {
-		cmd := exec.Command("git", "pull")
-		cmd.Dir = repo.WorkDir
-
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-
-		err := cmd.Run()
-		return formatCommandError("pull", err, stdout, stderr)
+		return repo.conflictError(repo.pull())
 	}
 
 	// Get latest from remote
 	err = repo.PullLatestRemote(branch)
 
 	if err != nil {
-		return err
+		return repo.conflictError(err)
 	}
 
 	cmd := exec.Command("git", "merge", "origin/"+branch)
@@ -82,7 +123,55 @@ func (repo *GitRepo) MergeLatest(branch string) error {
 	cmd.Stderr = &stderr
 
 	err = cmd.Run()
-	return formatCommandError("merge", err, stdout, stderr)
+	return repo.conflictError(formatCommandError("merge", err, stdout, stderr))
+}
+
+// conflictError makes sure a failed merge that left conflicts behind
+// matches ErrMergeConflict, even when git's wording wasn't recognised.
+func (repo *GitRepo) conflictError(err error) error {
+	if err == nil || errors.Is(err, ErrMergeConflict) || !repo.MergeInProgress() {
+		return err
+	}
+	if conflicts, _ := repo.GetConflictedFiles(); len(conflicts) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrMergeConflict, err)
+}
+
+// AbortMerge abandons an in-progress merge, restoring the branch to how it
+// was before the merge started.
+func (repo *GitRepo) AbortMerge() error {
+	cmd := exec.Command("git", "merge", "--abort")
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatCommandError("abort merge", err, stdout, stderr)
+}
+
+// MergeInProgress reports whether a merge is waiting to be committed,
+// e.g. after conflicts have stopped it.
+func (repo *GitRepo) MergeInProgress() bool {
+	cmd := exec.Command("git", "rev-parse", "-q", "--verify", "MERGE_HEAD")
+	cmd.Dir = repo.WorkDir
+	return cmd.Run() == nil
+}
+
+// FinishMerge commits a merge whose conflicts have been resolved, keeping
+// git's prepared merge message.
+func (repo *GitRepo) FinishMerge() error {
+	cmd := exec.Command("git", "commit", "--no-edit")
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatCommandError("finish merge", err, stdout, stderr)
 }
 
 func (repo *GitRepo) MergeLocalBranch(branchName string) error {
@@ -98,6 +187,7 @@ func (repo *GitRepo) MergeLocalBranch(branchName string) error {
 }
 
 func (repo *GitRepo) CreateBranch(branchName string) error {
+	defer repo.InvalidateBranchCache()
 	cmd := exec.Command("git", "checkout", "-b", branchName)
 	cmd.Dir = repo.WorkDir
 
@@ -110,6 +200,7 @@ func (repo *GitRepo) CreateBranch(branchName string) error {
 }
 
 func (repo *GitRepo) SwitchBranch(branchName string) error {
+	defer repo.InvalidateBranchCache()
 	cmd := exec.Command("git", "checkout", branchName)
 	cmd.Dir = repo.WorkDir
 
@@ -121,7 +212,17 @@ func (repo *GitRepo) SwitchBranch(branchName string) error {
 	return formatCommandError("switch branch", err, stdout, stderr)
 }
 
+// GetAllBranches lists local branches, and with remote also origin's.
 func (repo *GitRepo) GetAllBranches(remote bool) ([]string, error) {
+	output, err := repo.branchList()
+	if err != nil {
+		return nil, err
+	}
+	return parseBranchList(output, remote), nil
+}
+
+// branchList returns the output of `git branch -a`.
+func (repo *GitRepo) branchList() (string, error) {
 	getBranchCmd := exec.Command("git", "branch", "-a")
 	getBranchCmd.Dir = repo.WorkDir
 
@@ -131,11 +232,16 @@ func (repo *GitRepo) GetAllBranches(remote bool) ([]string, error) {
 
 	err := getBranchCmd.Run()
 	if err != nil {
-		return nil, formatCommandError("get branches", err, stdout, stderr)
+		return "", formatCommandError("get branches", err, stdout, stderr)
 	}
+	return stdout.String(), nil
+}
 
+// parseBranchList turns `git branch -a` output into branch names, keeping
+// origin's branches only with remote.
+func parseBranchList(output string, remote bool) []string {
 	var branches []string
-	scanner := bufio.NewScanner(&stdout)
+	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
@@ -161,10 +267,59 @@ func (repo *GitRepo) GetAllBranches(remote bool) ([]string, error) {
 		}
 	}
 
+	return branches
+}
+
+// ErrNoSuchRemoteBranch is returned by SetUpstream when the remote-tracking
+// branch is unknown, either because it was never pushed or because it
+// hasn't been fetched yet.
+var ErrNoSuchRemoteBranch = errors.New("no such remote branch")
+
+// RemoteBranches lists remote-tracking branches as "remote/branch",
+// leaving out each remote's HEAD.
+func (repo *GitRepo) RemoteBranches() ([]string, error) {
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/remotes")
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("list remote branches", err, stdout, stderr)
+	}
+
+	var branches []string
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
+		if line == "" || strings.HasSuffix(line, "/HEAD") || !strings.Contains(line, "/") {
+			continue
+		}
+		branches = append(branches, line)
+	}
 	return branches, nil
 }
 
+// SetUpstream makes the current branch track remoteRef, e.g. "origin/main".
+func (repo *GitRepo) SetUpstream(remoteRef string) error {
+	verify := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/remotes/"+remoteRef)
+	verify.Dir = repo.WorkDir
+	if verify.Run() != nil {
+		return fmt.Errorf("%w: %s", ErrNoSuchRemoteBranch, remoteRef)
+	}
+
+	cmd := exec.Command("git", "branch", "--set-upstream-to="+remoteRef)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	return formatCommandError("set upstream", cmd.Run(), stdout, stderr)
+}
+
+// ErrBranchNotMerged is returned by DeleteBranch when git refuses to delete
+// a branch whose commits are not reachable from HEAD or its upstream. This
+// is common after a squash merge, where the work landed under new hashes.
+var ErrBranchNotMerged = errors.New("branch is not fully merged")
+
 func (repo *GitRepo) DeleteBranch(branchName string) error {
+	defer repo.InvalidateBranchCache()
 	cmd := exec.Command("git", "branch", "-d", branchName)
 	cmd.Dir = repo.WorkDir
 
@@ -173,10 +328,51 @@ func (repo *GitRepo) DeleteBranch(branchName string) error {
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	if err != nil && strings.Contains(stderr.String(), "not fully merged") {
+		return fmt.Errorf("delete branch %s: %w", branchName, ErrBranchNotMerged)
+	}
 	return formatCommandError("delete branch", err, stdout, stderr)
 }
 
+// MergedBranches lists the local branches whose commits are all in into,
+// which are safe to delete. The current branch and into are left out.
+func (repo *GitRepo) MergedBranches(into string) ([]string, error) {
+	return repo.branchesByMerge("--merged", into)
+}
+
+// UnmergedBranches lists the local branches with commits that are not in
+// into. The current branch and into are left out.
+func (repo *GitRepo) UnmergedBranches(into string) ([]string, error) {
+	return repo.branchesByMerge("--no-merged", into)
+}
+
+func (repo *GitRepo) branchesByMerge(filter, into string) ([]string, error) {
+	cmd := exec.Command("git", "branch", "--format=%(HEAD)%(refname:short)", filter, into)
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("list branches", err, stdout, stderr)
+	}
+
+	var branches []string
+	for _, line := range splitLines(stdout.String()) {
+		// %(HEAD) is "*" for the current branch and a space otherwise.
+		if line == "" || strings.HasPrefix(line, "*") {
+			continue
+		}
+		if name := line[1:]; name != into {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
 func (repo *GitRepo) ForceDeleteBranch(branchName string) error {
+	defer repo.InvalidateBranchCache()
 	cmd := exec.Command("git", "branch", "-D", branchName)
 	cmd.Dir = repo.WorkDir
 
@@ -189,6 +385,7 @@ func (repo *GitRepo) ForceDeleteBranch(branchName string) error {
 }
 
 func (repo *GitRepo) RenameBranch(oldName, newName string) error {
+	defer repo.InvalidateBranchCache()
 	cmd := exec.Command("git", "branch", "-m", oldName, newName)
 	cmd.Dir = repo.WorkDir
 
@@ -222,7 +419,7 @@ func (repo *GitRepo) GetBranchDetails() ([]BranchDetail, error) {
 	}
 
 	var branches []BranchDetail
-	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
 		if line == "" {
 			continue
 		}
@@ -241,3 +438,109 @@ func (repo *GitRepo) GetBranchDetails() ([]BranchDetail, error) {
 	return branches, nil
 }
 
+// SyncOutcome describes what SyncAllBranches did with one branch.
+type SyncOutcome int
+
+const (
+	SyncUpToDate   SyncOutcome = iota // already at or ahead of its upstream
+	SyncAdvanced                      // fast-forwarded to its upstream
+	SyncDiverged                      // has commits of its own; left untouched
+	SyncNoUpstream                    // tracks nothing; left untouched
+	SyncFailed                        // the fast-forward was attempted and failed
+)
+
+// BranchSync is the result of syncing one local branch.
+type BranchSync struct {
+	Branch   string
+	Upstream string
+	Outcome  SyncOutcome
+	Ahead    int // commits on Branch missing from Upstream
+	Behind   int // commits on Upstream missing from Branch, before syncing
+	Err      error
+}
+
+// SyncAllBranches fetches every remote, then fast-forwards each local branch
+// that is strictly behind its upstream. Other branches are moved with
+// update-ref so they never need checking out; the current branch is merged
+// with --ff-only so the working tree follows. Branches that have diverged
+// are left alone and reported.
+func (repo *GitRepo) SyncAllBranches() ([]BranchSync, error) {
+	if err := repo.runNetwork("fetch", nil, "fetch", "--all"); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)|%(upstream:short)|%(HEAD)", "refs/heads/")
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("list branches", err, stdout, stderr)
+	}
+
+	var results []BranchSync
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
+		parts := strings.SplitN(line, "|", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		result := BranchSync{Branch: parts[0], Upstream: parts[1]}
+		if result.Upstream == "" {
+			result.Outcome = SyncNoUpstream
+			results = append(results, result)
+			continue
+		}
+
+		result.Ahead, result.Behind, result.Err = repo.countDivergence(result.Branch, result.Upstream)
+		switch {
+		case result.Err != nil:
+			result.Outcome = SyncFailed
+		case result.Behind == 0:
+			result.Outcome = SyncUpToDate
+		case result.Ahead > 0:
+			result.Outcome = SyncDiverged
+		default:
+			result.Err = repo.fastForward(result.Branch, result.Upstream, parts[2] == "*")
+			result.Outcome = SyncAdvanced
+			if result.Err != nil {
+				result.Outcome = SyncFailed
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// countDivergence counts the commits unique to each side of branch and
+// upstream.
+func (repo *GitRepo) countDivergence(branch, upstream string) (ahead, behind int, err error) {
+	cmd := exec.Command("git", "rev-list", "--left-right", "--count", "refs/heads/"+branch+"..."+upstream)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, 0, formatCommandError("compare with upstream", err, stdout, stderr)
+	}
+	if _, err := fmt.Sscanf(stdout.String(), "%d %d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %q", stdout.String())
+	}
+	return ahead, behind, nil
+}
+
+// fastForward moves branch to upstream. The current branch goes through
+// merge --ff-only so the index and working tree are updated too; git
+// refuses if local changes would be overwritten.
+func (repo *GitRepo) fastForward(branch, upstream string, current bool) error {
+	var cmd *exec.Cmd
+	if current {
+		cmd = exec.Command("git", "merge", "--ff-only", upstream)
+	} else {
+		cmd = exec.Command("git", "update-ref", "-m", "cgit sync-all: fast-forward", "refs/heads/"+branch, upstream)
+	}
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	return formatCommandError("fast-forward "+branch, cmd.Run(), stdout, stderr)
+}
@@ -0,0 +1,115 @@
+package git
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestClone creates a bare origin holding a main branch with files and
+// returns a clone of it, on main.
+func newTestClone(t *testing.T, files map[string]string) *GitRepo {
+	t.Helper()
+	seed := newTestRepo(t, files)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	gitRun(t, seed.WorkDir, "clone", "-q", "--bare", seed.WorkDir, origin)
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	gitRun(t, seed.WorkDir, "clone", "-q", origin, dir)
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	gitRun(t, dir, "config", "commit.gpgsign", "false")
+	gitRun(t, dir, "config", "pull.rebase", "false")
+	return New(dir)
+}
+
+func TestMergeLatestConflict(t *testing.T) {
+	repo := newTestClone(t, map[string]string{"a.txt": "base\n"})
+	dir := repo.WorkDir
+
+	gitRun(t, dir, "checkout", "-q", "-b", "topic")
+	writeFiles(t, dir, map[string]string{"a.txt": "topic\n"})
+	gitRun(t, dir, "commit", "-q", "-am", "topic change")
+
+	gitRun(t, dir, "checkout", "-q", "main")
+	writeFiles(t, dir, map[string]string{"a.txt": "main\n"})
+	gitRun(t, dir, "commit", "-q", "-am", "main change")
+	gitRun(t, dir, "push", "-q", "origin", "main")
+	gitRun(t, dir, "checkout", "-q", "topic")
+
+	err := repo.MergeLatest("main")
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("MergeLatest error = %v, want ErrMergeConflict", err)
+	}
+	if !repo.MergeInProgress() {
+		t.Error("the merge should be left in progress for the user to resolve")
+	}
+	conflicts, err := repo.GetConflictedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Path != "a.txt" {
+		t.Errorf("conflicted files = %+v, want a.txt", conflicts)
+	}
+}
+
+func TestWhatsNewAfterDefaultBranchPull(t *testing.T) {
+	repo := newTestClone(t, map[string]string{"a.txt": "base\n"})
+	origin := strings.TrimSpace(gitRun(t, repo.WorkDir, "remote", "get-url", "origin"))
+	// The upstream is recorded under its real name, not origin/<branch>.
+	gitRun(t, repo.WorkDir, "remote", "rename", "origin", "team")
+
+	other := filepath.Join(t.TempDir(), "other")
+	gitRun(t, repo.WorkDir, "clone", "-q", origin, other)
+	gitRun(t, other, "config", "user.name", "Teammate")
+	gitRun(t, other, "config", "user.email", "mate@example.com")
+	writeFiles(t, other, map[string]string{"b.txt": "b\n"})
+	gitRun(t, other, "add", "b.txt")
+	gitRun(t, other, "commit", "-q", "-m", "add b")
+	gitRun(t, other, "push", "-q", "origin", "main")
+
+	if err := repo.MergeLatest("main"); err != nil {
+		t.Fatal(err)
+	}
+	commits, recorded, err := repo.WhatsNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recorded {
+		t.Error("the pull was not recorded")
+	}
+	if len(commits) != 1 || commits[0].Subject != "add b" {
+		t.Errorf("WhatsNew = %+v, want the one pulled commit", commits)
+	}
+}
+
+func TestBranchCacheIsSwitcherOnly(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"a.txt": "a\n"})
+	if _, err := repo.SwitcherBranches(false); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, repo.WorkDir, "branch", "made-outside")
+
+	branches, err := repo.GetAllBranches(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(branches, "made-outside") {
+		t.Errorf("GetAllBranches = %q, want it to read git and include made-outside", branches)
+	}
+	if branches, _ := repo.SwitcherBranches(false); slices.Contains(branches, "made-outside") {
+		t.Errorf("SwitcherBranches = %q, want the cached list", branches)
+	}
+
+	worktree := New(filepath.Join(t.TempDir(), "wt"))
+	gitRun(t, repo.WorkDir, "worktree", "add", "-q", worktree.WorkDir)
+	branches, err = worktree.SwitcherBranches(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(branches, "made-outside") {
+		t.Errorf("worktree's SwitcherBranches = %q, want its own fresh list", branches)
+	}
+}
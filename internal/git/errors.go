@@ -0,0 +1,95 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors recognised from git's output. Check for them with
+// errors.Is; the full git output is still part of the error message.
+var (
+	ErrNotFastForward  = errors.New("remote has commits that are not in the local branch")
+	ErrMergeConflict   = errors.New("merge conflict")
+	ErrNoUpstream      = errors.New("no upstream configured for the current branch")
+	ErrAuthFailed      = errors.New("authentication failed")
+	ErrNothingToCommit = errors.New("nothing to commit")
+)
+
+// errorSignatures maps lowercase fragments of git's output to sentinel
+// errors. The first match wins.
+var errorSignatures = []struct {
+	signature string
+	kind      error
+}{
+	{"non-fast-forward", ErrNotFastForward},
+	{"(fetch first)", ErrNotFastForward},
+	{"updates were rejected because the tip", ErrNotFastForward},
+	{"conflict (", ErrMergeConflict},
+	{"automatic merge failed", ErrMergeConflict},
+	{"fix conflicts and then commit", ErrMergeConflict},
+	{"has no upstream branch", ErrNoUpstream},
+	{"no upstream configured", ErrNoUpstream},
+	{"there is no tracking information", ErrNoUpstream},
+	{"authentication failed", ErrAuthFailed},
+	{"permission denied (publickey)", ErrAuthFailed},
+	{"could not read username", ErrAuthFailed},
+	{"invalid username or password", ErrAuthFailed},
+	{"nothing to commit", ErrNothingToCommit},
+	{"no changes added to commit", ErrNothingToCommit},
+}
+
+// classifyError returns the sentinel error matching git's output, or nil.
+func classifyError(output string) error {
+	lower := strings.ToLower(output)
+	for _, s := range errorSignatures {
+		if strings.Contains(lower, s.signature) {
+			return s.kind
+		}
+	}
+	return nil
+}
+
+// CommandError is a failed git command. Kind holds the recognised sentinel
+// error, if any, so callers can use errors.Is without parsing Stderr.
+type CommandError struct {
+	Operation string
+	Err       error
+	Stdout    string
+	Stderr    string
+	Kind      error
+	// Hint replaces the raw output in the message unless Verbose is set.
+	Hint string
+}
+
+func (e *CommandError) Error() string {
+	if e.Hint != "" && !Verbose {
+		return fmt.Sprintf("%s failed: %s\n(run with --verbose for git's full output)", e.Operation, e.Hint)
+	}
+	msg := fmt.Sprintf("%s failed: %v\nStdout: %s\nStderr: %s", e.Operation, e.Err, e.Stdout, e.Stderr)
+	if e.Hint != "" {
+		msg += "\nhint: " + e.Hint
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+func formatCommandError(operation string, err error, stdout, stderr bytes.Buffer) error {
+	if err == nil {
+		return nil
+	}
+	return &CommandError{
+		Operation: operation,
+		Err:       err,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Kind:      classifyError(stderr.String() + "\n" + stdout.String()),
+	}
+}
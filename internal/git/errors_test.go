@@ -0,0 +1,56 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   error
+	}{
+		{"rejected push", " ! [rejected]        main -> main (non-fast-forward)\nerror: failed to push some refs to 'origin'", ErrNotFastForward},
+		{"fetch first", " ! [rejected]        main -> main (fetch first)", ErrNotFastForward},
+		{"merge conflict", "CONFLICT (content): Merge conflict in a.txt\nAutomatic merge failed; fix conflicts and then commit the result.", ErrMergeConflict},
+		{"no upstream", "fatal: The current branch topic has no upstream branch.", ErrNoUpstream},
+		{"no tracking", "There is no tracking information for the current branch.", ErrNoUpstream},
+		{"https auth", "remote: Invalid username or password.\nfatal: Authentication failed for 'https://example.com/repo.git/'", ErrAuthFailed},
+		{"ssh auth", "git@example.com: Permission denied (publickey).\nfatal: Could not read from remote repository.", ErrAuthFailed},
+		{"prompts disabled", "fatal: could not read Username for 'https://example.com': terminal prompts disabled", ErrAuthFailed},
+		{"clean tree", "On branch main\nnothing to commit, working tree clean", ErrNothingToCommit},
+		{"nothing staged", "no changes added to commit (use \"git add\" and/or \"git commit -a\")", ErrNothingToCommit},
+		{"unknown", "fatal: not a git repository (or any of the parent directories): .git", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.output); got != tt.want {
+				t.Errorf("classifyError(%q) = %v, want %v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCommandErrorKeepsOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stderr.WriteString("fatal: The current branch topic has no upstream branch.\n")
+	runErr := errors.New("exit status 128")
+
+	err := formatCommandError("push", runErr, stdout, stderr)
+	if !errors.Is(err, ErrNoUpstream) {
+		t.Errorf("errors.Is(err, ErrNoUpstream) = false for %v", err)
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Stderr != stderr.String() {
+		t.Errorf("error does not carry git's stderr: %#v", err)
+	}
+	if !bytes.Contains([]byte(err.Error()), []byte("no upstream branch")) {
+		t.Errorf("message %q lost git's output", err.Error())
+	}
+
+	if err := formatCommandError("push", nil, stdout, stderr); err != nil {
+		t.Errorf("formatCommandError with no error = %v, want nil", err)
+	}
+}
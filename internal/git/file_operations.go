@@ -3,6 +3,8 @@ package git
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,9 +14,31 @@ import (
 
 type FileStatus struct {
 	Path     string
-	Status   string // M(odified), A(dded), D(eleted), R(enamed), ?(untracked), U(nmerged)
+	Status   string // M(odified), A(dded), D(eleted), R(enamed), ?(untracked), U(nmerged), !(ignored)
 	Staged   bool
 	WorkTree bool
+	// OrigPath is the path a staged rename or copy came from.
+	OrigPath string
+}
+
+// ErrFileGone is returned when a file disappeared from the working tree
+// between a status refresh and an operation on it. Callers should refresh
+// their file lists rather than surface the raw git error.
+var ErrFileGone = errors.New("file no longer exists")
+
+// isPathspecError reports whether git's stderr says a path did not match.
+func isPathspecError(stderr string) bool {
+	return strings.Contains(stderr, "did not match any file") ||
+		strings.Contains(stderr, "pathspec")
+}
+
+// formatPathCommandError is formatCommandError for commands that take file
+// paths, mapping "no such path" failures to ErrFileGone.
+func formatPathCommandError(operation string, err error, stdout, stderr bytes.Buffer) error {
+	if err != nil && isPathspecError(stderr.String()) {
+		return fmt.Errorf("%s: %w", operation, ErrFileGone)
+	}
+	return formatCommandError(operation, err, stdout, stderr)
 }
 
 func (repo *GitRepo) GetModifiedFiles() ([]string, error) {
@@ -53,68 +77,300 @@ func (repo *GitRepo) AddFiles(files []string) error {
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	return formatCommandError("add files", err, stdout, stderr)
+	return formatPathCommandError("add files", err, stdout, stderr)
 }
 
-func (repo *GitRepo) GetFileStatuses() ([]FileStatus, []FileStatus, error) {
-	cmd := exec.Command("git", "status", "--porcelain=v1")
+// IntentToAdd records untracked paths with git add -N: git starts tracking
+// them, but none of their content is staged. They then show up in diffs
+// like any modified file, so new files can be staged hunk by hunk.
+func (repo *GitRepo) IntentToAdd(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	args := append([]string{"add", "--intent-to-add", "--"}, paths...)
+	cmd := exec.Command("git", args...)
 	cmd.Dir = repo.WorkDir
 
-	output, err := cmd.Output()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatPathCommandError("intent to add", err, stdout, stderr)
+}
+
+// StageDirectory stages every change under dir, including new and deleted
+// files.
+func (repo *GitRepo) StageDirectory(dir string) error {
+	cmd := exec.Command("git", "add", "--", dir)
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatPathCommandError("stage directory", err, stdout, stderr)
+}
+
+// GetFileStatuses splits the working tree's changes into staged changes,
+// unstaged changes to tracked files, and untracked files, the way
+// `git status` groups them. Callers that also show the branch should use
+// GetRepositoryStatus, which reads both from the same git process.
+func (repo *GitRepo) GetFileStatuses() (staged, unstaged, untracked []FileStatus, err error) {
+	status, err := repo.GetRepositoryStatus()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
+	return status.StagedFiles, status.UnstagedFiles, status.UntrackedFiles, nil
+}
 
-	var stagedFiles, unstagedFiles []FileStatus
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+// splitLines splits git output into lines. Line endings may be CRLF, as
+// with some Windows setups, so a trailing "\r" is dropped from each line.
+func splitLines(output string) []string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) < 3 {
-			continue
-		}
+// unquotePath strips the quotes git puts around paths with special
+// characters.
+func unquotePath(path string) string {
+	if len(path) >= 2 && strings.HasPrefix(path, "\"") && strings.HasSuffix(path, "\"") {
+		return path[1 : len(path)-1]
+	}
+	return path
+}
 
-		stageStatus := string(line[0])
-		workTreeStatus := string(line[1])
-		filePath := strings.TrimSpace(line[3:])
+// GetIgnoredFiles lists paths matched by .gitignore, reported by
+// `git status --ignored`. Ignored directories are listed once, with a
+// trailing slash, rather than file by file.
+func (repo *GitRepo) GetIgnoredFiles() ([]FileStatus, error) {
+	status, err := repo.GetRepositoryStatusWithIgnored()
+	if err != nil {
+		return nil, err
+	}
+	return status.IgnoredFiles, nil
+}
 
-		// Git quotes filenames with special characters - remove the quotes
-		if strings.HasPrefix(filePath, "\"") && strings.HasSuffix(filePath, "\"") {
-			filePath = filePath[1 : len(filePath)-1]
-		}
+// WholeFileContext is a context size large enough to show every line of a
+// file around its changes.
+const WholeFileContext = 100000
+
+// DiffAlgorithms are the values accepted for DiffAlgorithm and
+// DiffOptions.Algorithm; "" leaves git's own default (usually myers).
+var DiffAlgorithms = []string{"myers", "minimal", "patience", "histogram"}
+
+// DiffAlgorithm is the diff algorithm used when DiffOptions.Algorithm is
+// empty.
+var DiffAlgorithm string
+
+// RenameThreshold is the similarity percentage (git diff -M<n>%) at which
+// a deleted and an added file are shown as one rename. 0 uses git's
+// default of 50%.
+var RenameThreshold int
+
+type DiffOptions struct {
+	Staged bool
+	// Context is the number of context lines (git diff -U<n>); negative
+	// leaves git's default in place.
+	Context int
+	// Plain disables color so the output can be parsed.
+	Plain bool
+	// Algorithm overrides DiffAlgorithm for this diff.
+	Algorithm string
+	// OrigPath is the path the file was renamed from, if any. It is added
+	// to the pathspec so git can pair the two sides into a rename diff.
+	OrigPath string
+}
 
-		// Staged files
-		if stageStatus != " " && stageStatus != "?" {
-			stagedFiles = append(stagedFiles, FileStatus{
-				Path:     filePath,
-				Status:   stageStatus,
-				Staged:   true,
-				WorkTree: false,
-			})
-		}
+// args returns the git diff flags for opts, excluding the path.
+func (opts DiffOptions) args() []string {
+	var args []string
+	if opts.Context >= 0 {
+		args = append(args, fmt.Sprintf("-U%d", opts.Context))
+	}
+	algorithm := opts.Algorithm
+	if algorithm == "" {
+		algorithm = DiffAlgorithm
+	}
+	if algorithm != "" {
+		args = append(args, "--diff-algorithm="+algorithm)
+	}
+	if RenameThreshold > 0 {
+		args = append(args, fmt.Sprintf("-M%d%%", RenameThreshold))
+	} else {
+		args = append(args, "-M")
+	}
+	return args
+}
 
-		// Unstaged files
-		if workTreeStatus != " " {
-			unstagedFiles = append(unstagedFiles, FileStatus{
-				Path:     filePath,
-				Status:   workTreeStatus,
-				Staged:   false,
-				WorkTree: true,
-			})
+// paths returns the pathspec for a diff of filePath.
+func (opts DiffOptions) paths(filePath string) []string {
+	if opts.OrigPath != "" {
+		return []string{"--", opts.OrigPath, filePath}
+	}
+	return []string{"--", filePath}
+}
+
+func (repo *GitRepo) FileDiff(filePath string, staged bool) (string, error) {
+	return repo.FileDiffWithOptions(filePath, DiffOptions{Staged: staged, Context: -1})
+}
+
+// Diff returns exactly what `git diff [--staged] -- filePath` prints,
+// without the fallbacks FileDiffWithOptions uses to show something for
+// untracked and deleted files.
+func (repo *GitRepo) Diff(filePath string, opts DiffOptions) (string, error) {
+	color := "--color=always"
+	if opts.Plain {
+		color = "--color=never"
+	}
+	args := append([]string{"diff", color}, opts.args()...)
+	if opts.Staged {
+		args = append(args, "--staged")
+	}
+	args = append(args, opts.paths(filePath)...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return stdout.String(), formatCommandError("diff", err, stdout, stderr)
+}
+
+// StreamDiff runs the diff FileDiffWithOptions would and hands its output
+// to emit as git produces it, a run of whole lines at a time, so the top of
+// a huge diff can be shown before the rest arrives. When the diff prints
+// nothing, the FileDiffWithOptions fallbacks are emitted in one piece.
+// Cancelling ctx stops git.
+func (repo *GitRepo) StreamDiff(ctx context.Context, filePath string, opts DiffOptions, emit func(string)) error {
+	color := "--color=always"
+	if opts.Plain {
+		color = "--color=never"
+	}
+	args := append([]string{"diff", color}, opts.args()...)
+	if opts.Staged {
+		args = append(args, "--staged")
+	}
+	args = append(args, opts.paths(filePath)...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repo.WorkDir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	emitted := false
+	buf := make([]byte, 64*1024)
+	var pending []byte
+	for {
+		n, readErr := stdout.Read(buf)
+		pending = append(pending, buf[:n]...)
+		if i := bytes.LastIndexByte(pending, '\n'); i >= 0 {
+			emit(string(pending[:i+1]))
+			pending = pending[i+1:]
+			emitted = true
 		}
+		if readErr != nil {
+			break
+		}
+	}
+	if len(pending) > 0 {
+		emit(string(pending))
+		emitted = true
+	}
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if emitted {
+		return formatCommandError("diff", err, bytes.Buffer{}, stderr)
 	}
 
-	return stagedFiles, unstagedFiles, nil
+	content, err := repo.FileDiffWithOptions(filePath, opts)
+	if err != nil {
+		return err
+	}
+	emit(content)
+	return nil
 }
 
-func (repo *GitRepo) FileDiff(filePath string, staged bool) (string, error) {
-	// First try normal diff for modified files
-	var cmd *exec.Cmd
+// ErrNoDifftool is returned by LaunchDifftool when diff.tool is not set.
+var ErrNoDifftool = errors.New("no difftool configured (set one with git config diff.tool)")
+
+// DifftoolConfigured reports whether `git config diff.tool` is set.
+func (repo *GitRepo) DifftoolConfigured() bool {
+	cmd := exec.Command("git", "config", "diff.tool")
+	cmd.Dir = repo.WorkDir
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) != ""
+}
+
+// DifftoolCommand builds the `git difftool` invocation for filePath. The
+// caller runs it with the terminal attached, since most tools need it.
+func (repo *GitRepo) DifftoolCommand(filePath string, staged bool) *exec.Cmd {
+	args := []string{"difftool", "--no-prompt"}
 	if staged {
-		cmd = exec.Command("git", "diff", "--color=always", "--staged", filePath)
-	} else {
-		cmd = exec.Command("git", "diff", "--color=always", filePath)
+		args = append(args, "--staged")
+	}
+	args = append(args, "--", filePath)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.WorkDir
+	return cmd
+}
+
+// LaunchDifftool opens filePath in the configured difftool and waits for
+// it to exit.
+func (repo *GitRepo) LaunchDifftool(filePath string, staged bool) error {
+	if !repo.DifftoolConfigured() {
+		return ErrNoDifftool
+	}
+	cmd := repo.DifftoolCommand(filePath, staged)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// IsTracked reports whether path (a file or directory) is in the index.
+func (repo *GitRepo) IsTracked(path string) bool {
+	cmd := exec.Command("git", "ls-files", "--error-unmatch", "--", path)
+	cmd.Dir = repo.WorkDir
+	return cmd.Run() == nil
+}
+
+// InHead reports whether path exists in the HEAD commit.
+func (repo *GitRepo) InHead(path string) bool {
+	cmd := exec.Command("git", "cat-file", "-e", "HEAD:"+path)
+	cmd.Dir = repo.WorkDir
+	return cmd.Run() == nil
+}
+
+func (repo *GitRepo) FileDiffWithOptions(filePath string, opts DiffOptions) (string, error) {
+	// First try normal diff for modified files
+	color := "--color=always"
+	if opts.Plain {
+		color = "--color=never"
 	}
+	args := append([]string{"diff", color}, opts.args()...)
+	if opts.Staged {
+		args = append(args, "--staged")
+	}
+	args = append(args, opts.paths(filePath)...)
+	cmd := exec.Command("git", args...)
 	cmd.Dir = repo.WorkDir
 
 	var stdout, stderr bytes.Buffer
@@ -127,7 +383,8 @@ func (repo *GitRepo) FileDiff(filePath string, staged bool) (string, error) {
 	}
 
 	// If that fails, try diff with HEAD for deleted files
-	cmd = exec.Command("git", "diff", "HEAD", "--", filePath)
+	args = append(append(append([]string{"diff"}, opts.args()...), "HEAD"), opts.paths(filePath)...)
+	cmd = exec.Command("git", args...)
 	cmd.Dir = repo.WorkDir
 
 	stdout.Reset()
@@ -157,6 +414,9 @@ func (repo *GitRepo) FileDiff(filePath string, staged bool) (string, error) {
 		if strings.HasPrefix(status, "??") {
 			return repo.readFileAsDiff(filePath)
 		}
+		if status == "" && !repo.pathExists(filePath) {
+			return "", ErrFileGone
+		}
 	}
 
 	return "No differences to show for this file.\n\nThis might be because:\n- The file is unmodified\n- The file was renamed\n- The file is not tracked by git", nil
@@ -234,39 +494,206 @@ func (repo *GitRepo) ResolveConflictTheirs(filePath string) error {
 	return formatCommandError("add after theirs", addCmd.Run(), stdout, stderr)
 }
 
+// ResolveConflicts resolves each path by taking side, "ours" or "theirs",
+// and stages it. It stops at the first failure and returns the paths
+// resolved so far.
+func (repo *GitRepo) ResolveConflicts(paths []string, side string) ([]string, error) {
+	resolve := repo.ResolveConflictOurs
+	switch side {
+	case "ours":
+	case "theirs":
+		resolve = repo.ResolveConflictTheirs
+	default:
+		return nil, fmt.Errorf("unknown side %q: use ours or theirs", side)
+	}
+
+	var resolved []string
+	for _, path := range paths {
+		if err := resolve(path); err != nil {
+			return resolved, fmt.Errorf("%s: %w", path, err)
+		}
+		resolved = append(resolved, path)
+	}
+	return resolved, nil
+}
+
+// ResolutionPreview returns a colored diff of how filePath would change if
+// its conflict were resolved by taking side, "ours" or "theirs".
+func (repo *GitRepo) ResolutionPreview(filePath, side string) (string, error) {
+	cmd := exec.Command("git", "diff", "--color=always", "-R", "--"+side, "--", filePath)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("preview "+side, err, stdout, stderr)
+	}
+
+	// git notes the unmerged path before the diff itself.
+	lines := splitLines(stdout.String())
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "* Unmerged path") {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ErrUnresolvedConflict is returned when a file still has conflict markers
+// after a merge tool exits.
+var ErrUnresolvedConflict = errors.New("conflict markers remain")
+
+// MergetoolCommand builds the `git mergetool` invocation for filePath. The
+// caller runs it with the terminal attached, since most tools need it.
+func (repo *GitRepo) MergetoolCommand(filePath string) *exec.Cmd {
+	cmd := exec.Command("git", "mergetool", "--no-prompt", "--", filePath)
+	cmd.Dir = repo.WorkDir
+	return cmd
+}
+
+// HasConflictMarkers reports whether filePath still contains conflict
+// markers. A file removed to resolve a conflict has none.
+func (repo *GitRepo) HasConflictMarkers(filePath string) (bool, error) {
+	content, err := os.ReadFile(filepath.Join(repo.WorkDir, filePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading file: %w", err)
+	}
+	for _, line := range splitLines(string(content)) {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ScanForConflictMarkers searches tracked files for conflict markers left
+// behind by a botched resolution and returns each hit as "path:line".
+// Only the <<<<<<< and >>>>>>> lines are matched; ======= alone is too
+// common in ordinary text to mean anything.
+func (repo *GitRepo) ScanForConflictMarkers() ([]string, error) {
+	cmd := exec.Command("git", "grep", "-n", "-I", "-E", "^(<{7}|>{7})( |$)")
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+		return nil, nil // no matches
+	}
+	if err != nil {
+		return nil, formatCommandError("grep", err, stdout, stderr)
+	}
+
+	var hits []string
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
+		// path:line:text
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) == 3 {
+			hits = append(hits, parts[0]+":"+parts[1])
+		}
+	}
+	return hits, nil
+}
+
+// ConflictCount returns how many conflict regions remain in filePath.
+func (repo *GitRepo) ConflictCount(filePath string) (int, error) {
+	content, err := os.ReadFile(filepath.Join(repo.WorkDir, filePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading file: %w", err)
+	}
+	count := 0
+	for _, line := range splitLines(string(content)) {
+		if strings.HasPrefix(line, "<<<<<<< ") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// StageIfResolved stages filePath unless it still has conflict markers, in
+// which case it returns ErrUnresolvedConflict. Tools that stage on their
+// own leave nothing to do here, and staging again is harmless.
+func (repo *GitRepo) StageIfResolved(filePath string) error {
+	unresolved, err := repo.HasConflictMarkers(filePath)
+	if err != nil {
+		return err
+	}
+	if unresolved {
+		return ErrUnresolvedConflict
+	}
+	cmd := exec.Command("git", "add", "-A", "--", filePath)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	return formatCommandError("add after mergetool", cmd.Run(), stdout, stderr)
+}
+
 func (repo *GitRepo) readFileAsDiff(filePath string) (string, error) {
 	fullPath := filepath.Join(repo.WorkDir, filePath)
 	content, err := os.ReadFile(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrFileGone
+	}
 	if err != nil {
 		return "", fmt.Errorf("reading file: %w", err)
 	}
 
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n", filePath, strings.Count(string(content), "\n"))
-	for _, line := range strings.Split(string(content), "\n") {
+	for _, line := range splitLines(string(content)) {
 		// Don't add a "+" line for the trailing empty string after the final newline
 		fmt.Fprintf(&sb, "+%s\n", line)
 	}
 	return sb.String(), nil
 }
 
+// RemoveFiles discards changes to files: untracked ones are deleted and
+// tracked ones restored, from the index when staged is set. Every path is
+// tried even if some fail; the errors for those are joined, wrapping
+// ErrFileGone for paths that no longer exist.
 func (r *GitRepo) RemoveFiles(files []string, staged bool) error {
 	if len(files) == 0 {
 		return nil
 	}
 
+	var errs []error
 	var toRestore []string
 	for _, f := range files {
 		if r.isUntracked(f) {
-			if err := os.Remove(filepath.Join(r.WorkDir, f)); err != nil {
-				return fmt.Errorf("deleting untracked file %s: %w", f, err)
+			err := os.Remove(filepath.Join(r.WorkDir, f))
+			if errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("%s: %w", f, ErrFileGone))
+			} else if err != nil {
+				errs = append(errs, fmt.Errorf("deleting untracked file %s: %w", f, err))
 			}
 		} else {
 			toRestore = append(toRestore, f)
 		}
 	}
 
-	if len(toRestore) == 0 {
+	err := r.restoreFiles(toRestore, staged)
+	if errors.Is(err, ErrFileGone) && len(toRestore) > 1 {
+		// git restore changes nothing when any path is unknown, so go one
+		// path at a time to restore the rest and learn which ones are gone.
+		err = nil
+		for _, f := range toRestore {
+			if ferr := r.restoreFiles([]string{f}, staged); ferr != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f, ferr))
+			}
+		}
+	}
+	return errors.Join(append(errs, err)...)
+}
+
+func (r *GitRepo) restoreFiles(files []string, staged bool) error {
+	if len(files) == 0 {
 		return nil
 	}
 
@@ -274,8 +701,27 @@ func (r *GitRepo) RemoveFiles(files []string, staged bool) error {
 	if staged {
 		args = append(args, "--staged")
 	}
-	args = append(args, toRestore...)
+	args = append(args, "--")
+	args = append(args, files...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatPathCommandError("restore files", err, stdout, stderr)
+}
+
+// RevertToHead checks files out from HEAD, discarding both their staged and
+// unstaged changes. Files that are not in HEAD make git fail.
+func (r *GitRepo) RevertToHead(files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
 
+	args := append([]string{"checkout", "HEAD", "--"}, files...)
 	cmd := exec.Command("git", args...)
 	cmd.Dir = r.WorkDir
 	var stdout, stderr bytes.Buffer
@@ -283,7 +729,82 @@ func (r *GitRepo) RemoveFiles(files []string, staged bool) error {
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	return formatCommandError("restore files", err, stdout, stderr)
+	return formatPathCommandError("revert files to HEAD", err, stdout, stderr)
+}
+
+// ErrNotTracked is returned by DeleteTrackedFile for a file git doesn't
+// track; there is no deletion to stage.
+var ErrNotTracked = errors.New("file is not tracked")
+
+// RemoveOptions controls DeleteTrackedFile.
+type RemoveOptions struct {
+	// Cached only stops tracking the file, leaving it on disk.
+	Cached bool
+	// Recursive allows removing a directory and everything tracked in it.
+	Recursive bool
+	// Force removes files with uncommitted changes, which are lost.
+	Force bool
+}
+
+// DeleteTrackedFile removes path with git rm, staging the deletion. With
+// opts.Cached the file stays on disk as an untracked file.
+func (r *GitRepo) DeleteTrackedFile(path string, opts RemoveOptions) error {
+	if !opts.Recursive && r.isUntracked(path) {
+		return fmt.Errorf("%w: %s", ErrNotTracked, path)
+	}
+
+	args := []string{"rm", "--quiet"}
+	if opts.Cached {
+		args = append(args, "--cached")
+	}
+	if opts.Recursive {
+		args = append(args, "-r")
+	}
+	if opts.Force {
+		args = append(args, "--force")
+	}
+	cmd := exec.Command("git", append(args, "--", path)...)
+	cmd.Dir = r.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatPathCommandError("remove file", err, stdout, stderr)
+}
+
+// ErrDestinationExists is returned by MoveFile when something is already
+// at the destination path.
+var ErrDestinationExists = errors.New("destination already exists")
+
+// MoveFile renames a tracked file with git mv, so the change is staged as
+// a rename and history follows it. Missing parent directories of dst are
+// created first, since git mv won't.
+func (r *GitRepo) MoveFile(src, dst string) error {
+	if _, err := os.Lstat(filepath.Join(r.WorkDir, src)); err != nil {
+		return fmt.Errorf("move file: %w", ErrFileGone)
+	}
+	target := filepath.Join(r.WorkDir, dst)
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%w: %s", ErrDestinationExists, dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", dst, err)
+	}
+
+	cmd := exec.Command("git", "mv", "--", src, dst)
+	cmd.Dir = r.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatPathCommandError("move file", err, stdout, stderr)
+}
+
+func (r *GitRepo) pathExists(filePath string) bool {
+	_, err := os.Stat(filepath.Join(r.WorkDir, filePath))
+	return err == nil
 }
 
 func (r *GitRepo) isUntracked(filePath string) bool {
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit holding files and
+// returns it.
+func newTestRepo(t *testing.T, files map[string]string) *GitRepo {
+	t.Helper()
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	gitRun(t, dir, "config", "commit.gpgsign", "false")
+	writeFiles(t, dir, files)
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "--allow-empty", "-m", "initial")
+	return New(dir)
+}
+
+// gitRun runs git in dir and returns its output, failing the test on error.
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := gitOutput(dir, args...)
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, repo *GitRepo, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(repo.WorkDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestRemoveFilesVanishedPath(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	writeFiles(t, repo.WorkDir, map[string]string{
+		"a.txt":   "changed\n",
+		"b.txt":   "changed\n",
+		"new.txt": "new\n",
+	})
+
+	err := repo.RemoveFiles([]string{"a.txt", "gone.txt", "b.txt", "new.txt"}, false)
+	if !errors.Is(err, ErrFileGone) {
+		t.Fatalf("RemoveFiles error = %v, want ErrFileGone", err)
+	}
+	if !strings.Contains(err.Error(), "gone.txt") {
+		t.Errorf("error %q does not name gone.txt", err)
+	}
+
+	// The vanished path must not stop the others from being discarded.
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if got := readFile(t, repo, name); got == "changed\n" {
+			t.Errorf("%s was not restored", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo.WorkDir, "new.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("new.txt was not deleted: %v", err)
+	}
+}
+
+func TestRemoveFilesStaged(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"a.txt": "a\n"})
+	writeFiles(t, repo.WorkDir, map[string]string{"a.txt": "changed\n"})
+	gitRun(t, repo.WorkDir, "add", "a.txt")
+
+	if err := repo.RemoveFiles([]string{"a.txt"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if out := gitRun(t, repo.WorkDir, "diff", "--cached", "--name-only"); out != "" {
+		t.Errorf("still staged: %q", out)
+	}
+	if got := readFile(t, repo, "a.txt"); got != "changed\n" {
+		t.Errorf("worktree changed to %q, want it kept", got)
+	}
+}
+
+func TestRemoveFilesStagedRename(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"old.txt": "one\ntwo\nthree\n"})
+	gitRun(t, repo.WorkDir, "mv", "old.txt", "new.txt")
+
+	if err := repo.RemoveFiles([]string{"new.txt", "old.txt"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if out := gitRun(t, repo.WorkDir, "diff", "--cached", "--name-status"); out != "" {
+		t.Errorf("rename still partly staged:\n%s", out)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		output string
+		want   []string
+	}{
+		{"a\nb\n", []string{"a", "b", ""}},
+		{"a\r\nb\r\n", []string{"a", "b", ""}},
+		{"a\r\nb", []string{"a", "b"}},
+		{"mixed\r\nendings\n", []string{"mixed", "endings", ""}},
+		{"inner\rcr\r\n", []string{"inner\rcr", ""}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := splitLines(tt.output); !slices.Equal(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how network operations (fetch, pull, push) are
+// retried after transient failures.
+type RetryPolicy struct {
+	Attempts int           // total tries, including the first
+	Backoff  time.Duration // delay before the first retry, doubled each time
+}
+
+// DefaultRetryPolicy is copied into every GitRepo created by New.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: time.Second}
+
+// Verbose makes network operations report retry attempts on stderr.
+var Verbose bool
+
+// TerminalPrompts lets network operations ask for credentials on the
+// terminal. Leave it off when nobody is there to answer, so git fails fast
+// instead of waiting on a prompt forever.
+var TerminalPrompts bool
+
+// transientSignatures are stderr fragments that indicate a failure worth
+// retrying: the network dropped, not the remote refusing the request.
+var transientSignatures = []string{
+	"connection reset",
+	"timed out",
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"broken pipe",
+	"network is unreachable",
+}
+
+// permanentSignatures win over transientSignatures: retrying an auth or
+// non-fast-forward rejection only delays the inevitable.
+var permanentSignatures = []string{
+	"authentication failed",
+	"permission denied",
+	"could not read username",
+	"non-fast-forward",
+	"rejected",
+	"repository not found",
+}
+
+// isTransientNetworkError reports whether stderr from a failed network
+// command looks like a temporary connectivity problem.
+func isTransientNetworkError(stderr string) bool {
+	lower := strings.ToLower(stderr)
+	for _, sig := range permanentSignatures {
+		if strings.Contains(lower, sig) {
+			return false
+		}
+	}
+	for _, sig := range transientSignatures {
+		if strings.Contains(lower, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// networkHint pairs a stderr signature with a friendlier explanation.
+type networkHint struct {
+	signature string
+	message   string
+}
+
+// networkHints are checked in order; the first match wins.
+var networkHints = []networkHint{
+	{"permission denied (publickey)", "SSH key was rejected. Check that ssh-agent is running and has your key loaded (ssh-add -l), and that the key is registered with the remote."},
+	{"host key verification failed", "The remote's SSH host key is unknown or has changed. Connect once with ssh to verify it, or check known_hosts."},
+	{"could not open a connection to your authentication agent", "ssh-agent is not running. Start it with eval \"$(ssh-agent)\" and add your key with ssh-add."},
+	{"terminal prompts disabled", "Credentials are required. Run this from a terminal or configure a credential helper."},
+	{"authentication failed", "Authentication failed. Check your username and token, or your credential helper."},
+	{"repository not found", "The remote repository was not found, or you don't have access to it."},
+	{"could not resolve host", "Could not reach the remote host. Check your network connection and the remote URL."},
+}
+
+// classifyNetworkError maps git's stderr to a friendlier message, or ""
+// when nothing matches.
+func classifyNetworkError(stderr string) string {
+	lower := strings.ToLower(stderr)
+	for _, h := range networkHints {
+		if strings.Contains(lower, h.signature) {
+			return h.message
+		}
+	}
+	return ""
+}
+
+// explainNetworkError attaches a hint to err when the failure is
+// recognised. The raw output is still shown when Verbose is set.
+func explainNetworkError(err error, stderr string) error {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		cmdErr.Hint = classifyNetworkError(stderr)
+	}
+	return err
+}
+
+// runNetwork runs a git network command, retrying transient failures
+// according to repo.Retry. When progress is non-nil, --progress is passed
+// after the subcommand and each update is reported to it.
+func (repo *GitRepo) runNetwork(operation string, progress ProgressFunc, args ...string) error {
+	// Fetches, pulls and pushes all move remote-tracking branches.
+	defer repo.InvalidateBranchCache()
+
+	attempts := max(repo.Retry.Attempts, 1)
+	backoff := repo.Retry.Backoff
+
+	prompts := TerminalPrompts && !repo.NoPrompt
+	if progress != nil && len(args) > 0 {
+		args = append([]string{args[0], "--progress"}, args[1:]...)
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		cmd := exec.Command("git", args...)
+		cmd.Env = os.Environ()
+		cmd.Dir = repo.WorkDir
+		if prompts {
+			// Credential helpers may read from stdin; git's own prompt uses
+			// the terminal directly, so output can still be captured.
+			cmd.Stdin = os.Stdin
+		} else {
+			cmd.Env = append(cmd.Env, "GIT_TERMINAL_PROMPT=0")
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		var pw *progressWriter
+		if progress != nil {
+			pw = &progressWriter{buf: &stderr, onProgress: progress}
+			cmd.Stderr = pw
+		}
+
+		runErr := cmd.Run()
+		if pw != nil {
+			pw.Flush()
+		}
+		err = formatCommandError(operation, runErr, stdout, stderr)
+		if runErr != nil {
+			err = explainNetworkError(err, stderr.String())
+		}
+		if runErr == nil || attempt == attempts || !isTransientNetworkError(stderr.String()) {
+			break
+		}
+
+		if Verbose {
+			fmt.Fprintf(os.Stderr, "%s: attempt %d/%d failed (%s), retrying in %s\n",
+				operation, attempt, attempts, strings.TrimSpace(stderr.String()), backoff)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return err
+}
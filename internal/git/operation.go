@@ -0,0 +1,82 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Operation names a multi-step git command that can stop part way and wait
+// to be continued or aborted. Its value is the git subcommand.
+type Operation string
+
+const (
+	OpNone       Operation = ""
+	OpRebase     Operation = "rebase"
+	OpMerge      Operation = "merge"
+	OpCherryPick Operation = "cherry-pick"
+	OpRevert     Operation = "revert"
+)
+
+// ErrNoOperation is returned by ContinueOperation and AbortOperation when
+// there is nothing in progress.
+var ErrNoOperation = errors.New("no merge, rebase, cherry-pick or revert is in progress")
+
+// OperationInProgress reports which operation, if any, has stopped and is
+// waiting to be continued or aborted. A rebase wins over the others, since
+// the picks it makes along the way look like cherry-picks.
+func (repo *GitRepo) OperationInProgress() Operation {
+	if repo.RebaseInProgress() {
+		return OpRebase
+	}
+	if repo.MergeInProgress() {
+		return OpMerge
+	}
+	for _, pending := range []struct {
+		op   Operation
+		head string
+	}{{OpCherryPick, "CHERRY_PICK_HEAD"}, {OpRevert, "REVERT_HEAD"}} {
+		cmd := exec.Command("git", "rev-parse", "-q", "--verify", pending.head)
+		cmd.Dir = repo.WorkDir
+		if cmd.Run() == nil {
+			return pending.op
+		}
+	}
+	return OpNone
+}
+
+// ContinueOperation carries on with the operation in progress, once its
+// conflicts are resolved and staged, keeping git's prepared commit
+// messages. It returns the operation it continued; the error wraps
+// ErrMergeConflict if a later step conflicts too.
+func (repo *GitRepo) ContinueOperation() (Operation, error) {
+	op := repo.OperationInProgress()
+	if op == OpNone {
+		return op, ErrNoOperation
+	}
+	return op, repo.runOperation(op, "--continue")
+}
+
+// AbortOperation abandons the operation in progress, putting the branch
+// back where it was before it started, and returns the operation aborted.
+func (repo *GitRepo) AbortOperation() (Operation, error) {
+	op := repo.OperationInProgress()
+	if op == OpNone {
+		return op, ErrNoOperation
+	}
+	return op, repo.runOperation(op, "--abort")
+}
+
+func (repo *GitRepo) runOperation(op Operation, flag string) error {
+	cmd := exec.Command("git", string(op), flag)
+	cmd.Dir = repo.WorkDir
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatCommandError(flag[2:]+" "+string(op), err, stdout, stderr)
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Progress is one step of git's --progress output, e.g.
+// "Receiving objects: 45%".
+type Progress struct {
+	Phase   string
+	Percent int
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%s %d%%", p.Phase, p.Percent)
+}
+
+// ProgressFunc receives progress updates from a network operation.
+type ProgressFunc func(Progress)
+
+var progressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z ]+):\s+(\d+)%`)
+
+// parseProgress extracts a Progress from a single line of git's stderr.
+func parseProgress(line string) (Progress, bool) {
+	m := progressPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Progress{}, false
+	}
+	percent, err := strconv.Atoi(m[2])
+	if err != nil {
+		return Progress{}, false
+	}
+	return Progress{Phase: m[1], Percent: percent}, true
+}
+
+// progressWriter sits in front of a stderr buffer. Git redraws progress
+// lines with '\r'; those are reported to onProgress and dropped, so only
+// completed lines end up in the buffer used for error messages.
+type progressWriter struct {
+	buf        *bytes.Buffer
+	onProgress ProgressFunc
+	partial    []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\r' && b != '\n' {
+			w.partial = append(w.partial, b)
+			continue
+		}
+		line := string(w.partial)
+		w.partial = w.partial[:0]
+		if progress, ok := parseProgress(line); ok {
+			w.onProgress(progress)
+		}
+		if b == '\n' {
+			w.buf.WriteString(line)
+			w.buf.WriteByte('\n')
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any unterminated output to the buffer.
+func (w *progressWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.buf.Write(w.partial)
+		w.partial = w.partial[:0]
+	}
+}
@@ -0,0 +1,459 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RebaseActions are the todo commands that apply to a commit, in the order
+// the rebase picker cycles through them.
+var RebaseActions = []string{"pick", "reword", "edit", "squash", "fixup", "drop"}
+
+// rebaseAbbreviations maps the one-letter forms git writes when
+// rebase.abbreviateCommands is set.
+var rebaseAbbreviations = map[string]string{
+	"p": "pick", "r": "reword", "e": "edit", "s": "squash", "f": "fixup", "d": "drop",
+	"x": "exec", "b": "break", "l": "label", "t": "reset", "m": "merge", "u": "update-ref",
+}
+
+// IsCommit reports whether e picks a commit, as opposed to a command such
+// as exec or label that the todo carries along.
+func (e RebaseEntry) IsCommit() bool {
+	for _, a := range RebaseActions {
+		if e.Action == a {
+			return e.Hash != ""
+		}
+	}
+	return false
+}
+
+// ParseRebaseTodo reads the todo list git rebase -i hands to the sequence
+// editor. Comments and blank lines are dropped. Lines that don't name a
+// commit keep their arguments in Subject so FormatRebaseTodo can write them
+// back unchanged.
+func ParseRebaseTodo(content string) []RebaseEntry {
+	var entries []RebaseEntry
+	for _, line := range splitLines(content) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		action, rest, _ := strings.Cut(line, " ")
+		if full, ok := rebaseAbbreviations[action]; ok {
+			action = full
+		}
+		rest = strings.TrimSpace(rest)
+
+		entry := RebaseEntry{Action: action, Subject: rest}
+		for _, a := range RebaseActions {
+			if action == a {
+				hash, subject, _ := strings.Cut(rest, " ")
+				// Newer gits prefix the subject with "# ".
+				entry.Hash, entry.Subject = hash, strings.TrimPrefix(strings.TrimSpace(subject), "# ")
+				break
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// FormatRebaseTodo writes entries back out as a todo list, in order.
+func FormatRebaseTodo(entries []RebaseEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fields := []string{e.Action}
+		if e.Hash != "" {
+			fields = append(fields, e.Hash)
+		}
+		if e.Subject != "" {
+			fields = append(fields, e.Subject)
+		}
+		b.WriteString(strings.Join(fields, " ") + "\n")
+	}
+	return b.String()
+}
+
+// InteractiveRebaseCommand builds `git rebase -i` over the last count
+// commits, using sequenceEditor to edit the todo. When the branch has no
+// more than count commits the whole history is rebased with --root. The
+// caller runs it with the terminal attached, since reword and squash open
+// the commit message editor.
+func (repo *GitRepo) InteractiveRebaseCommand(count int, sequenceEditor string) *exec.Cmd {
+	base := fmt.Sprintf("HEAD~%d", count)
+	check := exec.Command("git", "rev-parse", "-q", "--verify", base+"^{commit}")
+	check.Dir = repo.WorkDir
+	if check.Run() != nil {
+		base = "--root"
+	}
+
+	cmd := exec.Command("git", "-c", "sequence.editor="+sequenceEditor, "rebase", "-i", base)
+	cmd.Dir = repo.WorkDir
+	return cmd
+}
+
+// RebaseInProgress reports whether a rebase has stopped part way, for
+// conflicts or at an edit, and is waiting to be continued or aborted.
+func (repo *GitRepo) RebaseInProgress() bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		cmd := exec.Command("git", "rev-parse", "--git-path", dir)
+		cmd.Dir = repo.WorkDir
+		out, err := cmd.Output()
+		if err != nil {
+			continue
+		}
+		path := strings.TrimSpace(string(out))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repo.WorkDir, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// AbortRebase abandons an in-progress rebase, putting the branch back where
+// it was before the rebase started.
+func (repo *GitRepo) AbortRebase() error {
+	cmd := exec.Command("git", "rebase", "--abort")
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatCommandError("abort rebase", err, stdout, stderr)
+}
+
+// ErrCommitPushed is returned by ReorderCommit and SquashIntoParent when a
+// commit they would rewrite is already on a remote branch.
+var ErrCommitPushed = errors.New("commit has already been pushed")
+
+// ErrCrossesMerge is returned when rewriting a commit would mean replaying
+// a merge, which a plain list of picks can't do.
+var ErrCrossesMerge = errors.New("rewriting would cross a merge commit")
+
+// ErrNoCommitToSwap is returned by ReorderCommit when the commit is already
+// the oldest or newest it can be.
+var ErrNoCommitToSwap = errors.New("no commit to swap with")
+
+// ErrReorderConflict is returned by ReorderCommit when the commits don't
+// apply in their new order. The rebase has been aborted by then, as it is
+// after any other failure.
+var ErrReorderConflict = errors.New("commits conflict in the new order")
+
+// IsPushed reports whether hash is on any remote-tracking branch.
+func (repo *GitRepo) IsPushed(hash string) bool {
+	cmd := exec.Command("git", "branch", "-r", "--contains", hash)
+	cmd.Dir = repo.WorkDir
+	out, err := cmd.Output()
+	// When in doubt, assume it is: callers use this to refuse or warn.
+	return err != nil || strings.TrimSpace(string(out)) != ""
+}
+
+// ReorderCommit swaps hash with its neighbour on the current branch: the
+// commit before it when direction is negative, the one after it otherwise.
+// It runs an interactive rebase with the two todo lines swapped, and
+// aborts it again if the commits conflict, so the branch is either
+// reordered or left as it was.
+func (repo *GitRepo) ReorderCommit(hash string, direction int) error {
+	chain, parent, err := repo.linearHistory(hash)
+	if err != nil {
+		return err
+	}
+	if direction < 0 {
+		if parent == "" {
+			return ErrNoCommitToSwap
+		}
+		grandparent, err := repo.firstParent(parent)
+		if err != nil {
+			return err
+		}
+		chain = append([]string{parent}, chain...)
+		parent = grandparent
+	} else if len(chain) < 2 {
+		return ErrNoCommitToSwap
+	}
+	if repo.IsPushed(chain[0]) {
+		return ErrCommitPushed
+	}
+
+	chain[0], chain[1] = chain[1], chain[0]
+	entries := pickAll(chain)
+
+	err = repo.runTodo(FormatRebaseTodo(entries), parent)
+	if err != nil && repo.RebaseInProgress() {
+		repo.AbortRebase()
+	}
+	if errors.Is(err, ErrMergeConflict) {
+		return fmt.Errorf("%w: %w", ErrReorderConflict, err)
+	}
+	return err
+}
+
+// DropCommit removes hash from the current branch by rebasing the commits
+// after it without it. If they conflict, the rebase is left in progress
+// and the error wraps ErrMergeConflict: resolve the files and continue
+// with ContinueRebase, or give up with AbortRebase. Whether hash has been
+// pushed is for the caller to check.
+func (repo *GitRepo) DropCommit(hash string) error {
+	chain, parent, err := repo.linearHistory(hash)
+	if err != nil {
+		return err
+	}
+
+	entries := pickAll(chain)
+	entries[0].Action = "drop"
+
+	err = repo.runTodo(FormatRebaseTodo(entries), parent)
+	if err != nil && !errors.Is(err, ErrMergeConflict) && repo.RebaseInProgress() {
+		repo.AbortRebase()
+	}
+	return err
+}
+
+// ErrRootCommit is returned by SquashIntoParent for a commit with no
+// parent.
+var ErrRootCommit = errors.New("the root commit has no parent to squash into")
+
+// SquashIntoParent folds hash into the commit before it and replays the
+// commits after it. keepMessage keeps only the parent's message, like a
+// fixup; otherwise the two messages are combined as git joins them,
+// without asking (SquashEditorCommand asks). It refuses when the parent is
+// already pushed, and aborts the rebase on any failure.
+func (repo *GitRepo) SquashIntoParent(hash string, keepMessage bool) error {
+	todo, base, err := repo.squashTodo(hash, keepMessage)
+	if err != nil {
+		return err
+	}
+	err = repo.runTodo(todo, base)
+	if err != nil && repo.RebaseInProgress() {
+		repo.AbortRebase()
+	}
+	return err
+}
+
+// SquashEditorCommand builds the rebase that squashes hash into its
+// parent, with the combined message opened in the editor. The caller runs
+// it with the terminal attached and hands its error to finish, which
+// cleans up and aborts the rebase if it failed, so an editor quit with an
+// empty message leaves the branch as it was.
+func (repo *GitRepo) SquashEditorCommand(hash string) (cmd *exec.Cmd, finish func(error) error, err error) {
+	todo, base, err := repo.squashTodo(hash, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd, cleanup, err := repo.todoCommand(todo, base)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The editor draws on stdout; git's complaints are kept for the error.
+	var stdout, stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	finish = func(err error) error {
+		cleanup()
+		if err != nil && repo.RebaseInProgress() {
+			repo.AbortRebase()
+		}
+		return formatCommandError("rebase", err, stdout, stderr)
+	}
+	return cmd, finish, nil
+}
+
+// squashTodo checks that hash can be folded into its parent and returns
+// the todo list that does it, with the commit to rebase onto.
+func (repo *GitRepo) squashTodo(hash string, keepMessage bool) (todo, base string, err error) {
+	chain, parent, err := repo.linearHistory(hash)
+	if err != nil {
+		return "", "", err
+	}
+	if parent == "" {
+		return "", "", ErrRootCommit
+	}
+	grandparent, err := repo.firstParent(parent)
+	if err != nil {
+		return "", "", err
+	}
+	if repo.IsPushed(parent) {
+		return "", "", ErrCommitPushed
+	}
+
+	entries := pickAll(append([]string{parent}, chain...))
+	entries[1].Action = "squash"
+	if keepMessage {
+		entries[1].Action = "fixup"
+	}
+	return FormatRebaseTodo(entries), grandparent, nil
+}
+
+// ContinueRebase carries on with a rebase that stopped on conflicts, once
+// they are resolved and staged. Commit messages are kept as they were. It
+// returns an error wrapping ErrMergeConflict if a later commit conflicts
+// too.
+func (repo *GitRepo) ContinueRebase() error {
+	cmd := exec.Command("git", "rebase", "--continue")
+	cmd.Dir = repo.WorkDir
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatCommandError("continue rebase", err, stdout, stderr)
+}
+
+// linearHistory resolves hash and lists the commits from it to HEAD,
+// oldest first, along with its parent ("" for a root commit). It fails
+// with ErrCrossesMerge unless that stretch of history is a straight line.
+func (repo *GitRepo) linearHistory(hash string) (chain []string, parent string, err error) {
+	full, err := repo.revParse(hash + "^{commit}")
+	if err != nil {
+		return nil, "", err
+	}
+	if !repo.isAncestor(full, "HEAD") {
+		return nil, "", fmt.Errorf("%s is not on the current branch", hash)
+	}
+
+	// Commits after full, newest first, each with its parents.
+	after, err := repo.revListParents("list commits", "--first-parent", full+"..HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	for _, c := range after {
+		if len(c) > 2 {
+			return nil, "", ErrCrossesMerge
+		}
+	}
+	if len(after) > 0 && after[len(after)-1][1] != full {
+		// full was merged in from a side branch.
+		return nil, "", ErrCrossesMerge
+	}
+	if parent, err = repo.firstParent(full); err != nil {
+		return nil, "", err
+	}
+
+	chain = []string{full}
+	for i := len(after) - 1; i >= 0; i-- {
+		chain = append(chain, after[i][0])
+	}
+	return chain, parent, nil
+}
+
+// firstParent returns hash's only parent, or "" for a root commit. Merge
+// commits fail with ErrCrossesMerge.
+func (repo *GitRepo) firstParent(hash string) (string, error) {
+	own, err := repo.revListParents("list commits", "-n1", hash)
+	if err != nil {
+		return "", err
+	}
+	switch len(own[0]) {
+	case 1:
+		return "", nil
+	case 2:
+		return own[0][1], nil
+	default:
+		return "", ErrCrossesMerge
+	}
+}
+
+// pickAll makes a todo list that picks each of hashes in turn.
+func pickAll(hashes []string) []RebaseEntry {
+	entries := make([]RebaseEntry, len(hashes))
+	for i, h := range hashes {
+		entries[i] = RebaseEntry{Action: "pick", Hash: h}
+	}
+	return entries
+}
+
+// runTodo runs an interactive rebase onto parent (the whole history when
+// it is "") with todo in place of the list git would have written. Commit
+// messages are taken as git writes them, without an editor.
+func (repo *GitRepo) runTodo(todo, parent string) error {
+	cmd, cleanup, err := repo.todoCommand(todo, parent)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	return formatCommandError("rebase", err, stdout, stderr)
+}
+
+// todoCommand builds the interactive rebase onto parent that uses todo as
+// its list. cleanup removes the file holding todo once the rebase is done.
+func (repo *GitRepo) todoCommand(todo, parent string) (cmd *exec.Cmd, cleanup func(), err error) {
+	tmpFile, err := os.CreateTemp("", "cgit-rebase-*.txt")
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpPath := tmpFile.Name()
+	if _, err := tmpFile.WriteString(todo); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return nil, nil, err
+	}
+	tmpFile.Close()
+
+	base := parent
+	if base == "" {
+		base = "--root"
+	}
+	// Normalise path separators for Git for Windows bash cp command
+	seqEditor := fmt.Sprintf("cp '%s'", strings.ReplaceAll(tmpPath, `\`, `/`))
+	cmd = exec.Command("git", "-c", "sequence.editor="+seqEditor, "rebase", "-i", base)
+	cmd.Dir = repo.WorkDir
+	return cmd, func() { os.Remove(tmpPath) }, nil
+}
+
+// revParse resolves rev to a full object name.
+func (repo *GitRepo) revParse(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "-q", rev)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("resolve "+rev, err, stdout, stderr)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// isAncestor reports whether a is reachable from b.
+func (repo *GitRepo) isAncestor(a, b string) bool {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", a, b)
+	cmd.Dir = repo.WorkDir
+	return cmd.Run() == nil
+}
+
+// revListParents runs git rev-list --parents and returns, per commit, its
+// hash followed by its parents' hashes.
+func (repo *GitRepo) revListParents(operation string, args ...string) ([][]string, error) {
+	cmd := exec.Command("git", append([]string{"rev-list", "--parents"}, args...)...)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError(operation, err, stdout, stderr)
+	}
+	var commits [][]string
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			commits = append(commits, fields)
+		}
+	}
+	return commits, nil
+}
@@ -0,0 +1,91 @@
+package git
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRebaseTodo(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []RebaseEntry
+	}{
+		{
+			name:    "picks",
+			content: "pick 1111111 first\npick 2222222 second\n",
+			want: []RebaseEntry{
+				{Action: "pick", Hash: "1111111", Subject: "first"},
+				{Action: "pick", Hash: "2222222", Subject: "second"},
+			},
+		},
+		{
+			name:    "abbreviations and comments",
+			content: "s 1111111 # squashed\n\n# Rebase 0000000..1111111 onto 0000000\nf 2222222 fixed\n",
+			want: []RebaseEntry{
+				{Action: "squash", Hash: "1111111", Subject: "squashed"},
+				{Action: "fixup", Hash: "2222222", Subject: "fixed"},
+			},
+		},
+		{
+			name:    "non-commit lines",
+			content: "exec make test\nbreak\n",
+			want: []RebaseEntry{
+				{Action: "exec", Subject: "make test"},
+				{Action: "break"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, eol := range []string{"\n", "\r\n"} {
+				content := strings.ReplaceAll(tt.content, "\n", eol)
+				if got := ParseRebaseTodo(content); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ParseRebaseTodo(%q) = %+v\nwant %+v", content, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSquashEditorCommand(t *testing.T) {
+	repo := newTestRepo(t, map[string]string{"a.txt": "a\n"})
+	for _, subject := range []string{"parent", "child"} {
+		writeFiles(t, repo.WorkDir, map[string]string{"a.txt": subject + "\n"})
+		gitRun(t, repo.WorkDir, "commit", "-q", "-am", subject)
+	}
+	before := gitRun(t, repo.WorkDir, "rev-parse", "HEAD")
+
+	// git runs the editor through the shell with the message file after
+	// it, so these write or empty that file.
+	run := func(editor string) error {
+		cmd, finish, err := repo.SquashEditorCommand("HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd.Env = append(os.Environ(), "GIT_EDITOR="+editor)
+		return finish(cmd.Run())
+	}
+
+	if err := run(": >"); err == nil {
+		t.Fatal("squash with an empty message succeeded")
+	}
+	if repo.RebaseInProgress() {
+		t.Fatal("failed squash left the rebase in progress")
+	}
+	if after := gitRun(t, repo.WorkDir, "rev-parse", "HEAD"); after != before {
+		t.Fatalf("failed squash moved HEAD from %s to %s", before, after)
+	}
+
+	if err := run("echo combined >"); err != nil {
+		t.Fatal(err)
+	}
+	if subject := strings.TrimSpace(gitRun(t, repo.WorkDir, "log", "-1", "--format=%s")); subject != "combined" {
+		t.Errorf("squashed commit's subject = %q, want the edited message", subject)
+	}
+	if count := strings.TrimSpace(gitRun(t, repo.WorkDir, "rev-list", "--count", "HEAD")); count != "2" {
+		t.Errorf("history has %s commits after the squash, want 2", count)
+	}
+}
@@ -2,9 +2,13 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -17,53 +21,213 @@ type RebaseEntry struct {
 
 type RepoStatus struct {
 	CurrentBranch string
+	// Upstream is the branch CurrentBranch tracks, e.g. "origin/main", or
+	// "" when it tracks nothing or the tracked branch is gone.
+	Upstream      string
+	Ahead, Behind int
 	StagedFiles   []FileStatus
 	UnstagedFiles []FileStatus
+	// UntrackedFiles are new files git does not know about yet; they are
+	// not part of UnstagedFiles.
+	UntrackedFiles []FileStatus
+	// IgnoredFiles is only filled by GetRepositoryStatusWithIgnored, since
+	// scanning ignored paths can be slow in large trees.
+	IgnoredFiles []FileStatus
 }
 
 type GitRepo struct {
 	WorkDir string
+	Retry   RetryPolicy
+	// NoPrompt disables credential prompts while a TUI owns the terminal.
+	NoPrompt bool
+	// SignOff adds a Signed-off-by trailer to the commits made by Commit,
+	// CommitAll, CommitEditorCommand and AmendCommit, like git commit -s.
+	SignOff bool
+
+	// gitDir is the worktree's git dir, looked up on first use.
+	gitDir string
+	// defaultBranch caches DefaultBranch.
+	defaultBranch string
 }
 
-func formatCommandError(operation string, err error, stdout, stderr bytes.Buffer) error {
-	if err == nil {
-		return nil
-	}
-	return fmt.Errorf("%s failed: %v\nStdout: %s\nStderr: %s",
-		operation, err, stdout.String(), stderr.String())
-}
+// DefaultSignOff is copied into every GitRepo created by New.
+var DefaultSignOff bool
 
 func New(workDir string) *GitRepo {
-	return &GitRepo{WorkDir: workDir}
+	return &GitRepo{WorkDir: workDir, Retry: DefaultRetryPolicy, SignOff: DefaultSignOff}
 }
 
-func (repo *GitRepo) Fetch() error {
-	cmd := exec.Command("git", "fetch", "origin")
+// TopLevel returns the root of the working tree.
+func (repo *GitRepo) TopLevel() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 	cmd.Dir = repo.WorkDir
-
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("find repository root", err, stdout, stderr)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
 
-	err := cmd.Run()
-	return formatCommandError("fetch", err, stdout, stderr)
+// ErrNoSuchDirectory is returned by NewChecked when workDir does not exist.
+var ErrNoSuchDirectory = errors.New("no such directory")
+
+// ErrNotDirectory is returned by NewChecked when workDir is a file.
+var ErrNotDirectory = errors.New("not a directory")
+
+// NewChecked is New for a directory the user typed: it fails up front when
+// workDir is missing or not a directory, instead of leaving every later
+// git call to fail on its own.
+func NewChecked(workDir string) (*GitRepo, error) {
+	info, err := os.Stat(workDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchDirectory, workDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrNotDirectory, workDir)
+	}
+	return New(workDir), nil
+}
+
+func (repo *GitRepo) Fetch() error {
+	return repo.FetchWithProgress(nil)
+}
+
+// FetchWithProgress fetches from origin, reporting git's progress to
+// onProgress as it arrives.
+func (repo *GitRepo) FetchWithProgress(onProgress ProgressFunc) error {
+	return repo.runNetwork("fetch", onProgress, "fetch", "origin")
 }
 
 func (repo *GitRepo) PullLatestRemote(branch string) error {
-	cmd := exec.Command("git", "pull", "origin", branch)
+	return repo.pull("origin", branch)
+}
+
+// pull runs git pull with args. It remembers how much of the current
+// branch's upstream HEAD had beforehand, keyed by the upstream's name, so
+// WhatsNew can list what the pull brought in. A pull that brought nothing
+// keeps the older mark.
+func (repo *GitRepo) pull(args ...string) error {
+	upstream, upstreamErr := repo.UpstreamName()
+	var before string
+	if upstreamErr == nil {
+		before = repo.mergeBase("HEAD", "@{upstream}")
+	}
+
+	err := repo.runNetwork("pull", nil, append([]string{"pull"}, args...)...)
+
+	if before != "" && repo.mergeBase("HEAD", "@{upstream}") != before {
+		repo.markLastPull(upstream, before)
+	}
+	return err
+}
+
+// mergeBase returns the best common ancestor of a and b, or "" when there
+// is none or either does not exist.
+func (repo *GitRepo) mergeBase(a, b string) string {
+	cmd := exec.Command("git", "merge-base", a, b)
 	cmd.Dir = repo.WorkDir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
 
+// lastPullRefPrefix namespaces the refs recording, per remote branch, the
+// last commit of it HEAD already had before the latest pull.
+const lastPullRefPrefix = "refs/cgit/last-pull/"
+
+func (repo *GitRepo) markLastPull(upstream, hash string) {
+	cmd := exec.Command("git", "update-ref", "-m", "cgit pull", lastPullRefPrefix+upstream, hash)
+	cmd.Dir = repo.WorkDir
+	// Best effort: losing the mark only makes WhatsNew fall back.
+	_ = cmd.Run()
+}
+
+// WhatsNew lists the commits the last pull of the current branch brought
+// in, plus anything fetched since, newest first. Before cgit has recorded
+// a pull it falls back to the upstream's previous position in the reflog,
+// which covers the last fetch; recorded reports which one was used.
+func (repo *GitRepo) WhatsNew() (commits []CommitInfo, recorded bool, err error) {
+	upstream, err := repo.UpstreamName()
+	if err != nil {
+		return nil, false, err
+	}
+
+	since, err := repo.ResolveRef(lastPullRefPrefix + upstream)
+	recorded = err == nil
+	if !recorded {
+		if since, err = repo.ResolveRef("@{upstream}@{1}"); err != nil {
+			// Nothing has moved the upstream since it was created.
+			return nil, false, nil
+		}
+	}
+	commits, err = repo.logCommits("new commits", since+"..@{upstream}")
+	return commits, recorded, err
+}
+
+// commitArgs builds the arguments for git commit, adding --signoff when
+// repo.SignOff is set.
+func (repo *GitRepo) commitArgs(args ...string) []string {
+	args = append([]string{"commit"}, args...)
+	if repo.SignOff {
+		args = append(args, "--signoff")
+	}
+	return args
+}
+
+// SignOffTrailer returns the trailer --signoff adds, built from the
+// committer identity, or "" when git doesn't know who that is.
+func (repo *GitRepo) SignOffTrailer() string {
+	cmd := exec.Command("git", "var", "GIT_COMMITTER_IDENT")
+	cmd.Dir = repo.WorkDir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	// The ident ends with a timestamp and timezone: "Name <email> 1700000000 +0100".
+	ident := strings.TrimSpace(string(out))
+	end := strings.LastIndex(ident, ">")
+	if end < 0 {
+		return ""
+	}
+	return "Signed-off-by: " + ident[:end+1]
+}
+
+func (repo *GitRepo) Commit(message string) error {
+	cmd := exec.Command("git", repo.commitArgs("-m", message)...)
+	os.Environ()
+	cmd.Dir = repo.WorkDir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	return formatCommandError("pull", err, stdout, stderr)
+	return formatCommandError("commit", err, stdout, stderr)
 }
 
-func (repo *GitRepo) Commit(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
-	os.Environ()
+// CommitEditorCommand builds a plain `git commit` (with -a when all is
+// set), which asks for the message in an editor. The caller runs it with
+// the terminal attached.
+func (repo *GitRepo) CommitEditorCommand(all bool) *exec.Cmd {
+	var args []string
+	if all {
+		args = append(args, "-a")
+	}
+	cmd := exec.Command("git", repo.commitArgs(args...)...)
+	cmd.Dir = repo.WorkDir
+	return cmd
+}
+
+// CommitAll stages every modified or deleted tracked file and commits, like
+// git commit -a. Untracked files are left alone.
+func (repo *GitRepo) CommitAll(message string) error {
+	cmd := exec.Command("git", repo.commitArgs("-a", "-m", message)...)
 	cmd.Dir = repo.WorkDir
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -76,6 +240,7 @@ func (repo *GitRepo) Commit(message string) error {
 type PushOptions struct {
 	ForceWithLease bool
 	SetUpstream    bool
+	Progress       ProgressFunc
 }
 
 func (repo *GitRepo) Push() error {
@@ -105,16 +270,7 @@ func (repo *GitRepo) PushWithOptions(opts PushOptions) error {
 		args = append(args, "--set-upstream")
 	}
 
-	pushCmd := exec.Command("git", args...)
-	pushCmd.Env = os.Environ()
-	pushCmd.Dir = repo.WorkDir
-
-	var stdout, stderr bytes.Buffer
-	pushCmd.Stdout = &stdout
-	pushCmd.Stderr = &stderr
-
-	err = pushCmd.Run()
-	return formatCommandError("push", err, stdout, stderr)
+	return repo.runNetwork("push", opts.Progress, args...)
 }
 
 func (repo *GitRepo) IsClean() (bool, error) {
@@ -129,26 +285,128 @@ func (repo *GitRepo) IsClean() (bool, error) {
 	return len(output) == 0, nil
 }
 
+// GetRepositoryStatus reads the branch, its upstream and ahead/behind
+// counts, and the file statuses from a single `git status`, so a refresh
+// costs one process instead of one per question.
 func (repo *GitRepo) GetRepositoryStatus() (*RepoStatus, error) {
-	status := &RepoStatus{}
+	return repo.repositoryStatus()
+}
 
-	// Get current branch
-	branch, err := repo.GetCurrentBranch()
-	if err != nil {
-		return nil, err
+// GetRepositoryStatusWithIgnored is GetRepositoryStatus with IgnoredFiles
+// filled in as well.
+func (repo *GitRepo) GetRepositoryStatusWithIgnored() (*RepoStatus, error) {
+	return repo.repositoryStatus("--ignored")
+}
+
+func (repo *GitRepo) repositoryStatus(extraArgs ...string) (*RepoStatus, error) {
+	args := append([]string{"status", "--porcelain=v2", "--branch"}, extraArgs...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("status", err, stdout, stderr)
 	}
-	status.CurrentBranch = branch
+	return parseStatusV2(stdout.String()), nil
+}
 
-	// Get file status
-	stagedFiles, unstagedFiles, err := repo.GetFileStatuses()
-	if err != nil {
-		return nil, err
+// parseStatusV2 reads `git status --porcelain=v2 --branch` output: "#
+// branch.*" headers, then one line per changed ("1"), renamed or copied
+// ("2"), unmerged ("u"), untracked ("?") and, with --ignored, ignored ("!")
+// path.
+func parseStatusV2(output string) *RepoStatus {
+	status := &RepoStatus{}
+	hasCounts, unmerged := false, false
+	for _, line := range splitLines(output) {
+		if header, ok := strings.CutPrefix(line, "# branch."); ok {
+			key, value, _ := strings.Cut(header, " ")
+			switch key {
+			case "head":
+				status.CurrentBranch = value
+				if value == "(detached)" {
+					// Match `git rev-parse --abbrev-ref HEAD`.
+					status.CurrentBranch = "HEAD"
+				}
+			case "upstream":
+				status.Upstream = value
+			case "ab":
+				hasCounts = true
+				if a, b, ok := strings.Cut(value, " "); ok {
+					status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(a, "+"))
+					status.Behind, _ = strconv.Atoi(strings.TrimPrefix(b, "-"))
+				}
+			}
+			continue
+		}
+
+		if path, ok := strings.CutPrefix(line, "? "); ok {
+			status.UntrackedFiles = append(status.UntrackedFiles, FileStatus{Path: unquotePath(path), Status: "?", WorkTree: true})
+			continue
+		}
+		if path, ok := strings.CutPrefix(line, "! "); ok {
+			status.IgnoredFiles = append(status.IgnoredFiles, FileStatus{Path: unquotePath(path), Status: "!"})
+			continue
+		}
+
+		// The path follows a fixed number of fields for each kind of entry.
+		var fields []string
+		var origPath string
+		switch {
+		case strings.HasPrefix(line, "1 "):
+			fields = strings.SplitN(line, " ", 9)
+		case strings.HasPrefix(line, "2 "):
+			fields = strings.SplitN(line, " ", 10)
+			if len(fields) == 10 {
+				var from string
+				fields[9], from, _ = strings.Cut(fields[9], "\t")
+				origPath = unquotePath(from)
+			}
+		case strings.HasPrefix(line, "u "):
+			fields = strings.SplitN(line, " ", 11)
+			unmerged = true
+		default:
+			continue
+		}
+		if len(fields) < 9 || len(fields[1]) != 2 {
+			continue
+		}
+		path := unquotePath(fields[len(fields)-1])
+		stageStatus, workTreeStatus := fields[1][0], fields[1][1]
+
+		if stageStatus != '.' {
+			status.StagedFiles = append(status.StagedFiles, FileStatus{
+				Path:     path,
+				Status:   string(stageStatus),
+				Staged:   true,
+				OrigPath: origPath,
+			})
+		}
+		if workTreeStatus != '.' {
+			status.UnstagedFiles = append(status.UnstagedFiles, FileStatus{
+				Path:     path,
+				Status:   string(workTreeStatus),
+				WorkTree: true,
+			})
+		}
 	}
 
-	status.StagedFiles = stagedFiles
-	status.UnstagedFiles = unstagedFiles
+	// An upstream without counts has been deleted on the remote.
+	if !hasCounts {
+		status.Upstream = ""
+	}
 
-	return status, nil
+	// Unmerged entries come last; put them in path order with the rest,
+	// as --porcelain=v1 lists them.
+	if unmerged {
+		byPath := func(files []FileStatus) func(i, j int) bool {
+			return func(i, j int) bool { return files[i].Path < files[j].Path }
+		}
+		sort.SliceStable(status.StagedFiles, byPath(status.StagedFiles))
+		sort.SliceStable(status.UnstagedFiles, byPath(status.UnstagedFiles))
+	}
+	return status
 }
 
 func (repo *GitRepo) Stash(message string) error {
@@ -189,7 +447,7 @@ func (repo *GitRepo) StashList() ([]StashEntry, error) {
 	}
 
 	var entries []StashEntry
-	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
 		if line == "" {
 			continue
 		}
@@ -241,12 +499,63 @@ func (repo *GitRepo) GetLastCommitMessage() (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// RecentSubjects returns the subjects of the last limit commits, newest
+// first, with repeats dropped.
+func (repo *GitRepo) RecentSubjects(limit int) ([]string, error) {
+	cmd := exec.Command("git", "log", "--format=%s", fmt.Sprintf("-n%d", limit))
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("recent commit subjects", err, stdout, stderr)
+	}
+
+	var subjects []string
+	seen := make(map[string]bool)
+	for _, s := range splitLines(stdout.String()) {
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			subjects = append(subjects, s)
+		}
+	}
+	return subjects, nil
+}
+
+// LastCommit returns the commit at HEAD. It fails in a repository with no
+// commits yet.
+func (repo *GitRepo) LastCommit() (CommitInfo, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%H|%h|%an|%ar|%s")
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return CommitInfo{}, formatCommandError("get last commit", err, stdout, stderr)
+	}
+	parts := strings.SplitN(strings.TrimSpace(stdout.String()), "|", 5)
+	if len(parts) != 5 {
+		return CommitInfo{}, fmt.Errorf("unexpected log output: %q", stdout.String())
+	}
+	return CommitInfo{
+		Hash:      parts[0],
+		ShortHash: parts[1],
+		Author:    parts[2],
+		Date:      parts[3],
+		Subject:   parts[4],
+	}, nil
+}
+
 func (repo *GitRepo) AmendCommit(message string, noEdit bool) error {
 	var args []string
 	if noEdit {
-		args = []string{"commit", "--amend", "--no-edit"}
+		args = repo.commitArgs("--amend", "--no-edit")
 	} else {
-		args = []string{"commit", "--amend", "-m", message}
+		args = repo.commitArgs("--amend", "-m", message)
 	}
 
 	cmd := exec.Command("git", args...)
@@ -261,8 +570,36 @@ func (repo *GitRepo) AmendCommit(message string, noEdit bool) error {
 	return formatCommandError("amend commit", err, stdout, stderr)
 }
 
-func (repo *GitRepo) ShowCommit(hash string) (string, error) {
-	cmd := exec.Command("git", "show", "--word-diff=color", hash)
+// ResolveRef returns the full hash ref points to, failing if it does not
+// name a commit.
+func (repo *GitRepo) ResolveRef(ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("unknown revision %q", ref)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// ShowCommit returns the colored `git show` output for ref.
+func (repo *GitRepo) ShowCommit(ref string) (string, error) {
+	return repo.showCommit(ref, true)
+}
+
+// ShowCommitPlain is ShowCommit without color, for piping to other tools.
+func (repo *GitRepo) ShowCommitPlain(ref string) (string, error) {
+	return repo.showCommit(ref, false)
+}
+
+func (repo *GitRepo) showCommit(ref string, color bool) (string, error) {
+	args := []string{"show", "--color=never", ref}
+	if color {
+		args = []string{"show", "--word-diff=color", ref}
+	}
+	cmd := exec.Command("git", args...)
 	cmd.Dir = repo.WorkDir
 
 	var stdout, stderr bytes.Buffer
@@ -276,8 +613,46 @@ func (repo *GitRepo) ShowCommit(hash string) (string, error) {
 	return stdout.String(), nil
 }
 
-func (repo *GitRepo) GetLog(limit int) (string, error) {
-	args := []string{"log", "--oneline", "--graph", "--decorate", fmt.Sprintf("-n%d", limit)}
+// LogLine is one row of the log graph: a commit, or a row that only
+// carries the graph's lines between commits.
+type LogLine struct {
+	Graph   string // the graph drawing left of the commit, e.g. "| * "
+	Hash    string // abbreviated hash, "" for graph-only rows
+	Refs    string // decorations, e.g. "HEAD -> main, origin/main"
+	Subject string
+}
+
+// logFieldSep separates the fields of each commit in GetLog's format, so
+// they can be told apart from the graph drawn before them.
+const logFieldSep = "\x1f"
+
+// GetLog lists the last limit commits with their graph, newest first. A
+// non-empty author keeps only commits whose author name or email contains
+// it. If the graph can't be parsed it falls back to a flat list, with
+// Graph left empty.
+func (repo *GitRepo) GetLog(limit int, author string) ([]LogLine, error) {
+	args := []string{"log", "--color=never", "--format=%x1f%h%x1f%D%x1f%s", fmt.Sprintf("-n%d", limit)}
+	if author != "" {
+		args = append(args, "--fixed-strings", "--author="+author)
+	}
+
+	output, err := repo.runLog(append(args, "--graph"))
+	if err != nil {
+		return nil, err
+	}
+	if lines, ok := parseLogGraph(output); ok {
+		return lines, nil
+	}
+
+	output, err = repo.runLog(args)
+	if err != nil {
+		return nil, err
+	}
+	lines, _ := parseLogGraph(output)
+	return lines, nil
+}
+
+func (repo *GitRepo) runLog(args []string) (string, error) {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = repo.WorkDir
 
@@ -292,6 +667,300 @@ func (repo *GitRepo) GetLog(limit int) (string, error) {
 	return stdout.String(), nil
 }
 
+// parseLogGraph splits git log --graph output into rows. ok is false when
+// a row doesn't look like graph drawing plus at most one commit, which
+// would make selecting a commit by row unreliable.
+func parseLogGraph(output string) (lines []LogLine, ok bool) {
+	for _, line := range splitLines(strings.TrimRight(output, "\n")) {
+		if line == "" {
+			continue
+		}
+		graph, fields, isCommit := strings.Cut(line, logFieldSep)
+		if strings.Trim(graph, " *|/\\_.-") != "" {
+			return nil, false
+		}
+		if !isCommit {
+			if strings.Contains(graph, "*") {
+				return nil, false
+			}
+			lines = append(lines, LogLine{Graph: graph})
+			continue
+		}
+
+		parts := strings.SplitN(fields, logFieldSep, 3)
+		if len(parts) != 3 || parts[0] == "" || strings.Count(graph, "*") > 1 {
+			return nil, false
+		}
+		lines = append(lines, LogLine{Graph: graph, Hash: parts[0], Refs: parts[1], Subject: parts[2]})
+	}
+	return lines, true
+}
+
+type CommitInfo struct {
+	Hash      string
+	ShortHash string
+	Author    string
+	Date      string
+	Subject   string
+	// Files lists the paths the commit touched, when the query asked for them.
+	Files []string
+}
+
+// FileHistory lists the commits that touched path, newest first, following
+// renames. Each entry's Files holds the file's path as of that commit.
+func (repo *GitRepo) FileHistory(path string, limit int) ([]CommitInfo, error) {
+	cmd := exec.Command("git", "log", "--follow", fmt.Sprintf("-n%d", limit),
+		"--format=%x1e%H|%h|%an|%ar|%s", "--name-only", "--", path)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("file history", err, stdout, stderr)
+	}
+
+	var commits []CommitInfo
+	for _, record := range strings.Split(stdout.String(), "\x1e") {
+		lines := splitLines(strings.TrimSpace(record))
+		parts := strings.SplitN(lines[0], "|", 5)
+		if len(parts) != 5 {
+			continue
+		}
+		info := CommitInfo{
+			Hash:      parts[0],
+			ShortHash: parts[1],
+			Author:    parts[2],
+			Date:      parts[3],
+			Subject:   parts[4],
+		}
+		for _, f := range lines[1:] {
+			if f = strings.TrimSpace(f); f != "" {
+				info.Files = append(info.Files, f)
+			}
+		}
+		commits = append(commits, info)
+	}
+	return commits, nil
+}
+
+// AuthorCommits lists up to limit commits reachable from HEAD whose author
+// name or email contains author, newest first.
+func (repo *GitRepo) AuthorCommits(author string, limit int) ([]CommitInfo, error) {
+	return repo.logCommits("commits by author", fmt.Sprintf("-n%d", limit), "--fixed-strings", "--author="+author, "HEAD")
+}
+
+// ErrNoUserEmail is returned by UserEmail when user.email is not set.
+var ErrNoUserEmail = errors.New("user.email is not set (set it with git config user.email)")
+
+// UserEmail returns the configured user.email, which identifies the
+// current user's commits.
+func (repo *GitRepo) UserEmail() (string, error) {
+	cmd := exec.Command("git", "config", "user.email")
+	cmd.Dir = repo.WorkDir
+	output, err := cmd.Output()
+	email := strings.TrimSpace(string(output))
+	if err != nil || email == "" {
+		return "", ErrNoUserEmail
+	}
+	return email, nil
+}
+
+// OutgoingCommits lists the commits a push of the current branch would
+// send, newest first: those after its upstream, or those on no remote
+// branch at all when it has no upstream yet.
+func (repo *GitRepo) OutgoingCommits() ([]CommitInfo, error) {
+	if repo.HasUpstream() {
+		return repo.logCommits("outgoing commits", "@{upstream}..HEAD")
+	}
+	return repo.logCommits("outgoing commits", "HEAD", "--not", "--remotes")
+}
+
+// IncomingCommits lists the commits on the current branch's upstream that
+// are not in HEAD yet, newest first, as of the last fetch.
+func (repo *GitRepo) IncomingCommits() ([]CommitInfo, error) {
+	if !repo.HasUpstream() {
+		return nil, ErrNoUpstream
+	}
+	return repo.logCommits("incoming commits", "HEAD..@{upstream}")
+}
+
+// logCommits runs git log over revs and parses one CommitInfo per commit.
+func (repo *GitRepo) logCommits(operation string, revs ...string) ([]CommitInfo, error) {
+	args := append([]string{"log", "--format=%H|%h|%an|%ar|%s"}, revs...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError(operation, err, stdout, stderr)
+	}
+
+	var commits []CommitInfo
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
+		parts := strings.SplitN(line, "|", 5)
+		if len(parts) != 5 {
+			continue
+		}
+		commits = append(commits, CommitInfo{
+			Hash:      parts[0],
+			ShortHash: parts[1],
+			Author:    parts[2],
+			Date:      parts[3],
+			Subject:   parts[4],
+		})
+	}
+	return commits, nil
+}
+
+// WIPCommits lists the outgoing commits whose subject matches pattern.
+func (repo *GitRepo) WIPCommits(pattern *regexp.Regexp) ([]CommitInfo, error) {
+	commits, err := repo.OutgoingCommits()
+	if err != nil {
+		return nil, err
+	}
+	var wip []CommitInfo
+	for _, c := range commits {
+		if pattern.MatchString(c.Subject) {
+			wip = append(wip, c)
+		}
+	}
+	return wip, nil
+}
+
+// FormatPatch writes one .patch file per commit in rangeSpec to outDir
+// with git format-patch and returns their paths, oldest first. A relative
+// outDir is taken from the repository's working directory.
+func (repo *GitRepo) FormatPatch(rangeSpec, outDir string) ([]string, error) {
+	cmd := exec.Command("git", "format-patch", "--output-directory", outDir, rangeSpec, "--")
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatCommandError("format patches", err, stdout, stderr)
+	}
+
+	var files []string
+	for _, line := range splitLines(stdout.String()) {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// BlameLine is one line of `git blame` output.
+type BlameLine struct {
+	Hash    string
+	Author  string
+	Summary string
+	LineNo  int
+	Text    string
+}
+
+// Uncommitted reports whether the line has changes that are not in any
+// commit yet, which blame shows as an all-zero hash.
+func (l BlameLine) Uncommitted() bool {
+	return l.Hash != "" && strings.Trim(l.Hash, "0") == ""
+}
+
+// isObjectHash reports whether s is a full object name: 40 hex digits, or
+// 64 in a SHA-256 repository.
+func isObjectHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ShortHash abbreviates Hash for display.
+func (l BlameLine) ShortHash() string {
+	if len(l.Hash) < 7 {
+		return l.Hash
+	}
+	return l.Hash[:7]
+}
+
+// Blame returns who last changed each line of path in the working tree.
+func (repo *GitRepo) Blame(path string) ([]BlameLine, error) {
+	cmd := exec.Command("git", "blame", "--porcelain", "--", path)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, formatPathCommandError("blame", err, stdout, stderr)
+	}
+	return parseBlame(stdout.String()), nil
+}
+
+// parseBlame parses `git blame --porcelain` output.
+func parseBlame(output string) []BlameLine {
+	// Porcelain output gives each commit's details only the first time it
+	// appears, so remember them for later lines.
+	type commitDetails struct{ author, summary string }
+	commits := make(map[string]*commitDetails)
+
+	var lines []BlameLine
+	var current BlameLine
+	for _, line := range splitLines(output) {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			if current.Hash == "" {
+				continue
+			}
+			if details := commits[current.Hash]; details != nil {
+				current.Author = details.author
+				current.Summary = details.summary
+			}
+			current.Text = line[1:]
+			lines = append(lines, current)
+		case strings.HasPrefix(line, "author "):
+			if details := commits[current.Hash]; details != nil {
+				details.author = strings.TrimPrefix(line, "author ")
+			}
+		case strings.HasPrefix(line, "summary "):
+			if details := commits[current.Hash]; details != nil {
+				details.summary = strings.TrimPrefix(line, "summary ")
+			}
+		default:
+			// A header: <hash> <original line> <final line> [<group size>]
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !isObjectHash(fields[0]) {
+				continue
+			}
+			lineNo, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			current = BlameLine{Hash: fields[0], LineNo: lineNo}
+			if commits[current.Hash] == nil {
+				commits[current.Hash] = &commitDetails{}
+			}
+		}
+	}
+	return lines
+}
+
+// ShowCommitFile returns the colored `git show` output for ref limited to path.
+func (repo *GitRepo) ShowCommitFile(ref, path string) (string, error) {
+	cmd := exec.Command("git", "show", "--word-diff=color", ref, "--", path)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("show commit file", err, stdout, stderr)
+	}
+	return stdout.String(), nil
+}
+
 func (repo *GitRepo) CherryPick(hash string) error {
 	cmd := exec.Command("git", "cherry-pick", hash)
 	cmd.Dir = repo.WorkDir
@@ -346,6 +1015,123 @@ func (repo *GitRepo) GetAheadBehind() (ahead, behind int, err error) {
 	return ahead, behind, nil
 }
 
+// UpstreamName returns the remote branch the current branch tracks, e.g.
+// "origin/main", or ErrNoUpstream.
+func (repo *GitRepo) UpstreamName() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	cmd.Dir = repo.WorkDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", ErrNoUpstream
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RemoteURL returns the fetch URL of the named remote.
+func (repo *GitRepo) RemoteURL(name string) (string, error) {
+	cmd := exec.Command("git", "remote", "get-url", name)
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("get remote url", err, stdout, stderr)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// WebURL turns a remote URL into the address of the repository's web
+// page. It understands scp-style ssh ("git@github.com:owner/repo.git"),
+// ssh:// and http(s) URLs, and fails for anything else, such as a local
+// path.
+func WebURL(remoteURL string) (string, error) {
+	web := "https://"
+	var host, path string
+	if scheme, rest, ok := strings.Cut(remoteURL, "://"); ok {
+		host, path, _ = strings.Cut(rest, "/")
+		if scheme == "http" {
+			web = "http://"
+		} else if scheme != "https" {
+			// An ssh port belongs to the ssh server, not the web one.
+			host, _, _ = strings.Cut(host, ":")
+		}
+	} else if h, p, ok := strings.Cut(remoteURL, ":"); ok && len(h) > 1 && !strings.Contains(h, "/") {
+		// Single letters are Windows drive letters, not hosts.
+		host, path = h, p
+	}
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", fmt.Errorf("cannot make a web address from remote URL %q", remoteURL)
+	}
+	return web + host + "/" + path, nil
+}
+
+// TreeURL is the web page for branch under a repository's WebURL. GitLab
+// puts it under /-/tree; GitHub, Gitea and most others use /tree.
+func TreeURL(webURL, branch string) string {
+	if strings.Contains(webURL, "gitlab") {
+		return webURL + "/-/tree/" + branch
+	}
+	return webURL + "/tree/" + branch
+}
+
+// CompareURL is the page that starts a pull (or merge) request from
+// branch into base. The host is guessed from webURL; anything that isn't
+// GitLab or Bitbucket gets GitHub's form, which Gitea also accepts.
+func CompareURL(webURL, base, branch string) string {
+	switch {
+	case strings.Contains(webURL, "gitlab"):
+		return webURL + "/-/merge_requests/new?merge_request%5Bsource_branch%5D=" + url.QueryEscape(branch) +
+			"&merge_request%5Btarget_branch%5D=" + url.QueryEscape(base)
+	case strings.Contains(webURL, "bitbucket"):
+		return webURL + "/pull-requests/new?source=" + url.QueryEscape(branch) + "&dest=" + url.QueryEscape(base)
+	default:
+		return webURL + "/compare/" + base + "..." + branch + "?expand=1"
+	}
+}
+
+// HasUpstream reports whether the current branch tracks a remote branch.
+func (repo *GitRepo) HasUpstream() bool {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	cmd.Dir = repo.WorkDir
+	return cmd.Run() == nil
+}
+
+// DiffUpstream diffs the current branch against its upstream as of the last
+// fetch. "incoming" shows what a pull would bring in (changes on the
+// upstream since the branches diverged); "outgoing" shows what a push would
+// send.
+func (repo *GitRepo) DiffUpstream(direction string) (string, error) {
+	var rangeSpec string
+	switch direction {
+	case "incoming":
+		rangeSpec = "HEAD...@{upstream}"
+	case "outgoing":
+		rangeSpec = "@{upstream}...HEAD"
+	default:
+		return "", fmt.Errorf("unknown direction %q (want incoming or outgoing)", direction)
+	}
+
+	if !repo.HasUpstream() {
+		return "", ErrNoUpstream
+	}
+
+	cmd := exec.Command("git", "diff", "--color=always", rangeSpec)
+	cmd.Dir = repo.WorkDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("diff upstream", err, stdout, stderr)
+	}
+	return stdout.String(), nil
+}
+
 func (repo *GitRepo) UndoLastCommit() error {
 	cmd := exec.Command("git", "reset", "HEAD~1", "--soft")
 	cmd.Dir = repo.WorkDir
@@ -365,7 +1151,7 @@ func (repo *GitRepo) GetRebaseCommits(limit int) ([]RebaseEntry, error) {
 		return nil, formatCommandError("get rebase commits", err, stdout, stderr)
 	}
 	var entries []RebaseEntry
-	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+	for _, line := range splitLines(strings.TrimSpace(stdout.String())) {
 		if line == "" {
 			continue
 		}
@@ -401,3 +1187,49 @@ func (repo *GitRepo) FullClean() error {
 	err = cleanCmd.Run()
 	return formatCommandError("clean -fd", err, cleanStdout, cleanStderr)
 }
+
+// RepoSize summarises the object database from git count-objects -vH, e.g.
+// "1.53 MiB in 1 pack (420 objects), 12.00 KiB loose (8 objects)".
+func (repo *GitRepo) RepoSize() (string, error) {
+	cmd := exec.Command("git", "count-objects", "-v", "-H")
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", formatCommandError("count objects", err, stdout, stderr)
+	}
+
+	stats := make(map[string]string)
+	for _, line := range splitLines(stdout.String()) {
+		if key, value, ok := strings.Cut(line, ": "); ok {
+			stats[key] = strings.TrimSpace(value)
+		}
+	}
+	packs := "packs"
+	if stats["packs"] == "1" {
+		packs = "pack"
+	}
+	return fmt.Sprintf("%s in %s %s (%s objects), %s loose (%s objects)",
+		stats["size-pack"], stats["packs"], packs, stats["in-pack"], stats["size"], stats["count"]), nil
+}
+
+// GC runs git gc to repack objects and prune unreachable ones. Aggressive
+// repacks harder, which is much slower but can shrink old repositories.
+func (repo *GitRepo) GC(aggressive bool) error {
+	args := []string{"gc", "--quiet"}
+	if aggressive {
+		args = append(args, "--aggressive")
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo.WorkDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return formatCommandError("gc", err, stdout, stderr)
+}
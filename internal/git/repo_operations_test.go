@@ -0,0 +1,213 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBlame(t *testing.T) {
+	sha1 := strings.Repeat("a1", 20)
+	sha256 := strings.Repeat("b2", 32)
+	zero256 := strings.Repeat("0", 64)
+	output := strings.Join([]string{
+		sha1 + " 1 1 2",
+		"author Ada",
+		"summary first",
+		"filename a.txt",
+		"\tone",
+		sha1 + " 2 2",
+		"\ttwo",
+		sha256 + " 1 3 1",
+		"author Grace",
+		"summary second",
+		"filename a.txt",
+		"\tthree",
+		zero256 + " 4 4 1",
+		"author Not Committed Yet",
+		"summary Version of a.txt from a.txt",
+		"filename a.txt",
+		"\tfour",
+		"",
+	}, "\n")
+
+	want := []BlameLine{
+		{Hash: sha1, Author: "Ada", Summary: "first", LineNo: 1, Text: "one"},
+		{Hash: sha1, Author: "Ada", Summary: "first", LineNo: 2, Text: "two"},
+		{Hash: sha256, Author: "Grace", Summary: "second", LineNo: 3, Text: "three"},
+		{Hash: zero256, Author: "Not Committed Yet", Summary: "Version of a.txt from a.txt", LineNo: 4, Text: "four"},
+	}
+	got := parseBlame(output)
+	if len(got) != len(want) {
+		t.Fatalf("parseBlame returned %d lines, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got[0].Uncommitted() || !got[3].Uncommitted() {
+		t.Errorf("Uncommitted() = %v, %v; want false, true", got[0].Uncommitted(), got[3].Uncommitted())
+	}
+}
+
+func TestParseBlameIgnoresStrayLines(t *testing.T) {
+	// Details or content before any header must not panic.
+	output := "author Ada\nsummary first\n\torphan\nnot-a-hash 1 1\n\tstill orphan\n"
+	if got := parseBlame(output); len(got) != 0 {
+		t.Errorf("parseBlame = %+v, want no lines", got)
+	}
+}
+
+func TestBlameSHA256Repo(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := gitOutput(dir, "init", "-q", "--object-format=sha256"); err != nil {
+		t.Skipf("git cannot create SHA-256 repositories: %v\n%s", err, out)
+	}
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	writeFiles(t, dir, map[string]string{"a.txt": "one\n"})
+	gitRun(t, dir, "add", "a.txt")
+	gitRun(t, dir, "commit", "-q", "-m", "add a")
+	writeFiles(t, dir, map[string]string{"a.txt": "one\ntwo\n"})
+
+	lines, err := New(dir).Blame("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Blame returned %d lines, want 2: %+v", len(lines), lines)
+	}
+	if len(lines[0].Hash) != 64 || lines[0].Summary != "add a" || lines[0].Uncommitted() {
+		t.Errorf("committed line = %+v", lines[0])
+	}
+	if !lines[1].Uncommitted() {
+		t.Errorf("new line = %+v, want uncommitted", lines[1])
+	}
+}
+
+func TestGetRepositoryStatusFillsEveryField(t *testing.T) {
+	repo := newTestClone(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	// Push one commit, then replace it locally: one ahead, one behind.
+	writeFiles(t, repo.WorkDir, map[string]string{"c.txt": "pushed\n"})
+	gitRun(t, repo.WorkDir, "add", "c.txt")
+	gitRun(t, repo.WorkDir, "commit", "-q", "-m", "pushed")
+	gitRun(t, repo.WorkDir, "push", "-q")
+	gitRun(t, repo.WorkDir, "reset", "-q", "--hard", "HEAD~1")
+	writeFiles(t, repo.WorkDir, map[string]string{"d.txt": "local\n"})
+	gitRun(t, repo.WorkDir, "add", "d.txt")
+	gitRun(t, repo.WorkDir, "commit", "-q", "-m", "local")
+
+	writeFiles(t, repo.WorkDir, map[string]string{"a.txt": "staged\n", "b.txt": "unstaged\n", "new.txt": "new\n", "debug.log": "ignored\n"})
+	gitRun(t, repo.WorkDir, "add", "a.txt")
+	writeFiles(t, repo.WorkDir, map[string]string{".git/info/exclude": "*.log\n"})
+
+	status, err := repo.GetRepositoryStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RepoStatus{
+		CurrentBranch:  "main",
+		Upstream:       "origin/main",
+		Ahead:          1,
+		Behind:         1,
+		StagedFiles:    []FileStatus{{Path: "a.txt", Status: "M", Staged: true}},
+		UnstagedFiles:  []FileStatus{{Path: "b.txt", Status: "M", WorkTree: true}},
+		UntrackedFiles: []FileStatus{{Path: "new.txt", Status: "?", WorkTree: true}},
+	}
+	if !reflect.DeepEqual(*status, want) {
+		t.Errorf("GetRepositoryStatus() = %+v\nwant %+v", *status, want)
+	}
+
+	status, err = repo.GetRepositoryStatusWithIgnored()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.IgnoredFiles = []FileStatus{{Path: "debug.log", Status: "!"}}
+	if !reflect.DeepEqual(*status, want) {
+		t.Errorf("GetRepositoryStatusWithIgnored() = %+v\nwant %+v", *status, want)
+	}
+}
+
+func TestParseStatusV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   RepoStatus
+	}{
+		{
+			name: "tracking branch",
+			output: "# branch.oid 1234\n# branch.head main\n# branch.upstream origin/main\n# branch.ab +2 -1\n" +
+				"1 M. N... 100644 100644 100644 aaa bbb staged.txt\n" +
+				"1 .D N... 100644 100644 000000 aaa aaa gone.txt\n" +
+				"? \"new file.txt\"\n",
+			want: RepoStatus{
+				CurrentBranch:  "main",
+				Upstream:       "origin/main",
+				Ahead:          2,
+				Behind:         1,
+				StagedFiles:    []FileStatus{{Path: "staged.txt", Status: "M", Staged: true}},
+				UnstagedFiles:  []FileStatus{{Path: "gone.txt", Status: "D", WorkTree: true}},
+				UntrackedFiles: []FileStatus{{Path: "new file.txt", Status: "?", WorkTree: true}},
+			},
+		},
+		{
+			name: "rename",
+			output: "# branch.head main\n" +
+				"2 RM N... 100644 100644 100644 aaa aaa R100 new.txt\told.txt\n",
+			want: RepoStatus{
+				CurrentBranch: "main",
+				StagedFiles:   []FileStatus{{Path: "new.txt", Status: "R", Staged: true, OrigPath: "old.txt"}},
+				UnstagedFiles: []FileStatus{{Path: "new.txt", Status: "M", WorkTree: true}},
+			},
+		},
+		{
+			name: "unmerged sorted with the rest",
+			output: "# branch.head main\n" +
+				"1 M. N... 100644 100644 100644 aaa bbb z.txt\n" +
+				"u UU N... 100644 100644 100644 100644 aaa bbb ccc b.txt\n",
+			want: RepoStatus{
+				CurrentBranch: "main",
+				StagedFiles: []FileStatus{
+					{Path: "b.txt", Status: "U", Staged: true},
+					{Path: "z.txt", Status: "M", Staged: true},
+				},
+				UnstagedFiles: []FileStatus{{Path: "b.txt", Status: "U", WorkTree: true}},
+			},
+		},
+		{
+			name:   "ignored",
+			output: "# branch.head main\n? new.txt\n! build/\n! \"debug log.txt\"\n",
+			want: RepoStatus{
+				CurrentBranch:  "main",
+				UntrackedFiles: []FileStatus{{Path: "new.txt", Status: "?", WorkTree: true}},
+				IgnoredFiles: []FileStatus{
+					{Path: "build/", Status: "!"},
+					{Path: "debug log.txt", Status: "!"},
+				},
+			},
+		},
+		{
+			name:   "detached",
+			output: "# branch.oid 1234\n# branch.head (detached)\n",
+			want:   RepoStatus{CurrentBranch: "HEAD"},
+		},
+		{
+			name:   "upstream gone",
+			output: "# branch.oid 1234\n# branch.head topic\n# branch.upstream origin/topic\n",
+			want:   RepoStatus{CurrentBranch: "topic"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GetFileStatuses reads its lists from here too, so this
+			// covers CRLF output for it as well.
+			for _, eol := range []string{"\n", "\r\n"} {
+				output := strings.ReplaceAll(tt.output, "\n", eol)
+				if got := parseStatusV2(output); !reflect.DeepEqual(*got, tt.want) {
+					t.Errorf("parseStatusV2(%q) = %+v\nwant %+v", output, *got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+type BlameViewerModel struct {
+	repo         *git.GitRepo
+	path         string
+	mode         Mode
+	lines        []git.BlameLine
+	currentIndex int
+	scrollOffset int
+	visibleLines int
+	width        int
+	height       int
+	message      string
+
+	diffViewer DiffViewerModel
+
+	titleStyle      lipgloss.Style
+	selectedStyle   lipgloss.Style
+	unselectedStyle lipgloss.Style
+	helpStyle       lipgloss.Style
+	dimStyle        lipgloss.Style
+}
+
+func NewBlameViewerModel(repo *git.GitRepo, path string, lines []git.BlameLine) BlameViewerModel {
+	return BlameViewerModel{
+		repo:  repo,
+		path:  path,
+		mode:  NormalMode,
+		lines: lines,
+
+		titleStyle:      TitlePinkStyle,
+		selectedStyle:   SelectedPeachStyle,
+		unselectedStyle: UnselectedStyle,
+		helpStyle:       HelpStyle,
+		dimStyle:        DimStyle,
+	}
+}
+
+func (m BlameViewerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m BlameViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.mode == DetailMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "q", "esc":
+				m.mode = NormalMode
+				return m, nil
+			}
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.visibleLines = msg.Height - 6
+		}
+		updatedViewer, viewCmd := m.diffViewer.Update(msg)
+		if dv, ok := updatedViewer.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+		return m, viewCmd
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.visibleLines = msg.Height - 6
+
+	case tea.KeyMsg:
+		m.message = ""
+		switch msg.String() {
+		case "q", "esc":
+			return m, tea.Quit
+
+		case "j", "down":
+			if len(m.lines) > 0 {
+				m.currentIndex = (m.currentIndex + 1) % len(m.lines)
+				m.adjustScrolling()
+			}
+
+		case "k", "up":
+			if len(m.lines) > 0 {
+				m.currentIndex = (m.currentIndex - 1 + len(m.lines)) % len(m.lines)
+				m.adjustScrolling()
+			}
+
+		case "ctrl+d":
+			m.currentIndex = min(m.currentIndex+m.visibleLines/2, len(m.lines)-1)
+			m.adjustScrolling()
+
+		case "ctrl+u":
+			m.currentIndex = max(m.currentIndex-m.visibleLines/2, 0)
+			m.adjustScrolling()
+
+		case "g", "home":
+			m.currentIndex = 0
+			m.scrollOffset = 0
+
+		case "G", "end":
+			if len(m.lines) > 0 {
+				m.currentIndex = len(m.lines) - 1
+				m.adjustScrolling()
+			}
+
+		case "enter":
+			if len(m.lines) == 0 {
+				return m, nil
+			}
+			line := m.lines[m.currentIndex]
+			if line.Uncommitted() {
+				m.message = fmt.Sprintf("Line %d has uncommitted changes; there is no commit to show yet.", line.LineNo)
+				return m, nil
+			}
+			return m, m.openCommit(line)
+		}
+	}
+
+	return m, nil
+}
+
+// openCommit shows the full diff of the commit that last changed line.
+func (m *BlameViewerModel) openCommit(line git.BlameLine) tea.Cmd {
+	repo := m.repo
+	m.diffViewer = NewContentViewerModel(repo, fmt.Sprintf("%s — %s", line.ShortHash(), line.Summary), func() (string, error) {
+		return repo.ShowCommit(line.Hash)
+	})
+	m.mode = DetailMode
+
+	cmds := []tea.Cmd{m.diffViewer.Init()}
+	if m.width > 0 && m.height > 0 {
+		updatedViewer, sizeCmd := m.diffViewer.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		if dv, ok := updatedViewer.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+		cmds = append(cmds, sizeCmd)
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m BlameViewerModel) View() string {
+	if m.mode == DetailMode {
+		return m.diffViewer.View()
+	}
+
+	var sections []string
+	sections = append(sections, m.titleStyle.Render(fmt.Sprintf("Blame of %s", m.path)))
+	if m.message != "" {
+		sections = append(sections, m.dimStyle.Render(m.message))
+	} else {
+		sections = append(sections, "")
+	}
+
+	startIdx := m.scrollOffset
+	endIdx := min(startIdx+m.visibleLines, len(m.lines))
+	numWidth := len(fmt.Sprint(len(m.lines)))
+
+	for i := startIdx; i < endIdx; i++ {
+		l := m.lines[i]
+		hash, author := l.ShortHash(), l.Author
+		if l.Uncommitted() {
+			hash, author = "-------", "uncommitted"
+		}
+		author = fmt.Sprintf("%-12.12s", author)
+		text := fmt.Sprintf("%*d  %s", numWidth, l.LineNo, strings.ReplaceAll(l.Text, "\t", "    "))
+
+		prefix := "  "
+		style := m.unselectedStyle
+		if i == m.currentIndex {
+			prefix = "> "
+			style = m.selectedStyle
+		}
+		sections = append(sections, fmt.Sprintf("%s%s %s %s", prefix, m.helpStyle.Render(hash), m.dimStyle.Render(author), style.Render(text)))
+	}
+
+	if len(m.lines) > m.visibleLines {
+		sections = append(sections, "")
+		sections = append(sections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(m.lines))))
+	}
+
+	sections = append(sections, "")
+	sections = append(sections, m.helpStyle.Render("j/k: navigate  ctrl+d/u: page  enter: show commit that introduced the line  g/G: top/bottom  q: back"))
+
+	return strings.Join(sections, "\n")
+}
+
+func (m *BlameViewerModel) adjustScrolling() {
+	if m.visibleLines <= 0 {
+		return
+	}
+	if m.currentIndex >= m.scrollOffset+m.visibleLines {
+		m.scrollOffset = m.currentIndex - m.visibleLines + 1
+	}
+	if m.currentIndex < m.scrollOffset {
+		m.scrollOffset = m.currentIndex
+	}
+	maxOffset := len(m.lines) - m.visibleLines
+	if maxOffset < 0 {
+		maxOffset = 0
+	}
+	if m.scrollOffset > maxOffset {
+		m.scrollOffset = maxOffset
+	}
+	if m.scrollOffset < 0 {
+		m.scrollOffset = 0
+	}
+}
+
+// StartBlameViewer shows who last changed each line of path.
+func StartBlameViewer(repo *git.GitRepo, path string) error {
+	lines, err := repo.Blame(path)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		fmt.Printf("%s is empty.\n", path)
+		return nil
+	}
+	m := NewBlameViewerModel(repo, path, lines)
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err = p.Run()
+	return err
+}
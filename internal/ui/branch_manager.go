@@ -14,6 +14,11 @@ type branchOpMsg struct {
 	err error
 }
 
+type mergedBranchesMsg struct {
+	branches []string
+	err      error
+}
+
 type branchRefreshMsg struct {
 	branches []git.BranchDetail
 	err      error
@@ -32,6 +37,12 @@ type BranchManagerModel struct {
 	showLastStatus bool
 	switched       bool // signals the caller to re-exec to pick up new branch
 
+	// Prune flow: 'p' selects the branches merged into base, space adjusts
+	// the selection and 'x' deletes it after y/n.
+	base         string
+	selected     map[string]bool
+	confirmPrune bool
+
 	titleStyle      lipgloss.Style
 	selectedStyle   lipgloss.Style
 	unselectedStyle lipgloss.Style
@@ -42,10 +53,12 @@ type BranchManagerModel struct {
 	dimStyle        lipgloss.Style
 }
 
-func NewBranchManagerModel(repo *git.GitRepo, branches []git.BranchDetail) BranchManagerModel {
+func NewBranchManagerModel(repo *git.GitRepo, branches []git.BranchDetail, base string) BranchManagerModel {
 	return BranchManagerModel{
 		repo:     repo,
 		branches: branches,
+		base:     base,
+		selected: make(map[string]bool),
 
 		titleStyle:      TitlePinkStyle,
 		selectedStyle:   SelectedPeachStyle,
@@ -68,6 +81,8 @@ func (m BranchManagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.visibleLines = msg.Height - 7
+		// A smaller window can leave the cursor below the list.
+		m.adjustScrolling()
 
 	case branchOpMsg:
 		if msg.err != nil {
@@ -78,6 +93,24 @@ func (m BranchManagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.showLastStatus = true
 		return m, m.refresh()
 
+	case mergedBranchesMsg:
+		if msg.err != nil {
+			m.lastStatus = fmt.Sprintf("✗ Listing merged branches failed: %v", msg.err)
+			m.showLastStatus = true
+			return m, nil
+		}
+		m.selected = make(map[string]bool)
+		for _, name := range msg.branches {
+			m.selected[name] = true
+		}
+		if len(msg.branches) == 0 {
+			m.lastStatus = fmt.Sprintf("✓ No branches are fully merged into %s", m.base)
+		} else {
+			m.lastStatus = fmt.Sprintf("✓ Selected %d branch(es) merged into %s; space: adjust  x: delete", len(msg.branches), m.base)
+		}
+		m.showLastStatus = true
+		return m, nil
+
 	case branchRefreshMsg:
 		if msg.err != nil {
 			m.lastStatus = fmt.Sprintf("✗ Refresh failed: %v", msg.err)
@@ -85,15 +118,52 @@ func (m BranchManagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.branches = msg.branches
+		for name := range m.selected {
+			if !m.hasBranch(name) {
+				delete(m.selected, name)
+			}
+		}
 		if m.currentIndex >= len(m.branches) {
 			m.currentIndex = max(0, len(m.branches)-1)
 		}
 
 	case tea.KeyMsg:
+		// A pending prune takes the next key as its answer.
+		if m.confirmPrune {
+			m.confirmPrune = false
+			if msg.String() != "y" {
+				m.lastStatus = "Prune canceled"
+				m.showLastStatus = true
+				return m, nil
+			}
+			return m, m.pruneBranches(m.selectedNames())
+		}
+
 		switch msg.String() {
 		case "q", "esc":
 			return m, tea.Quit
 
+		case "p":
+			return m, m.loadMerged()
+
+		case " ":
+			if len(m.branches) > 0 {
+				b := m.branches[m.currentIndex]
+				if b.Current {
+					return m, nil
+				}
+				if m.selected[b.Name] {
+					delete(m.selected, b.Name)
+				} else {
+					m.selected[b.Name] = true
+				}
+			}
+
+		case "x":
+			if len(m.selected) > 0 {
+				m.confirmPrune = true
+			}
+
 		case "j", "down":
 			if len(m.branches) > 0 {
 				m.currentIndex = (m.currentIndex + 1) % len(m.branches)
@@ -170,6 +240,8 @@ func (m BranchManagerModel) View() string {
 		marker := " "
 		if b.Current {
 			marker = m.currentStyle.Render("*")
+		} else if m.selected[b.Name] {
+			marker = m.errorStyle.Render("x")
 		}
 		meta := m.dimStyle.Render(fmt.Sprintf("  %s  %s  %s", b.Hash, b.Date, b.Subject))
 		line := fmt.Sprintf("%s%s %s%s", prefix, marker, nameStyle.Render(b.Name), meta)
@@ -182,7 +254,10 @@ func (m BranchManagerModel) View() string {
 	}
 
 	sections = append(sections, "")
-	sections = append(sections, m.helpStyle.Render("enter: switch  d: delete  D: force delete  j/k: navigate  q: quit"))
+	if m.confirmPrune {
+		sections = append(sections, m.errorStyle.Render(fmt.Sprintf("Delete %d selected branch(es)? y/N", len(m.selected))))
+	}
+	sections = append(sections, m.helpStyle.Render("enter: switch  d: delete  D: force delete  p: select merged  space: select  x: delete selected  j/k: navigate  q: quit"))
 
 	return strings.Join(sections, "\n")
 }
@@ -206,6 +281,52 @@ func (m BranchManagerModel) deleteBranch(name string, force bool) tea.Cmd {
 	}
 }
 
+// loadMerged finds the branches merged into the base branch, to be
+// selected for pruning.
+func (m BranchManagerModel) loadMerged() tea.Cmd {
+	return func() tea.Msg {
+		branches, err := m.repo.MergedBranches(m.base)
+		return mergedBranchesMsg{branches: branches, err: err}
+	}
+}
+
+// pruneBranches deletes names with git branch -d, so a branch that turns
+// out to have unmerged work is kept and reported instead of lost.
+func (m BranchManagerModel) pruneBranches(names []string) tea.Cmd {
+	return func() tea.Msg {
+		var failed []string
+		for _, name := range names {
+			if err := m.repo.DeleteBranch(name); err != nil {
+				failed = append(failed, name)
+			}
+		}
+		op := fmt.Sprintf("Deleted %d branch(es)", len(names)-len(failed))
+		if len(failed) > 0 {
+			return branchOpMsg{op: op, err: fmt.Errorf("could not delete %s", strings.Join(failed, ", "))}
+		}
+		return branchOpMsg{op: op}
+	}
+}
+
+func (m BranchManagerModel) selectedNames() []string {
+	var names []string
+	for _, b := range m.branches {
+		if m.selected[b.Name] {
+			names = append(names, b.Name)
+		}
+	}
+	return names
+}
+
+func (m BranchManagerModel) hasBranch(name string) bool {
+	for _, b := range m.branches {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (m BranchManagerModel) refresh() tea.Cmd {
 	return func() tea.Msg {
 		branches, err := m.repo.GetBranchDetails()
@@ -235,7 +356,9 @@ func (m *BranchManagerModel) adjustScrolling() {
 	}
 }
 
-func StartBranchManager(repo *git.GitRepo) error {
+// StartBranchManager opens the branch manager. base is the branch the
+// prune flow checks for merged branches.
+func StartBranchManager(repo *git.GitRepo, base string) error {
 	branches, err := repo.GetBranchDetails()
 	if err != nil {
 		return err
@@ -244,7 +367,7 @@ func StartBranchManager(repo *git.GitRepo) error {
 		fmt.Println("No branches found.")
 		return nil
 	}
-	m := NewBranchManagerModel(repo, branches)
+	m := NewBranchManagerModel(repo, branches, base)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err = p.Run()
 	return err
@@ -29,10 +29,15 @@ type BranchSwitcherModel struct {
 	filteredIndices []int
 	searchSelected  int
 
+	// Outcome, read by SwitchBranches after the program exits
+	switchedTo string
+	err        error
+
 	// Styles
 	titleStyle      lipgloss.Style
 	selectedStyle   lipgloss.Style
 	unselectedStyle lipgloss.Style
+	helpStyle       lipgloss.Style
 }
 
 func (m BranchSwitcherModel) Init() tea.Cmd {
@@ -56,10 +61,16 @@ func (m BranchSwitcherModel) renderBranches(i int) string {
 func (m BranchSwitcherModel) View() string {
 	var sections []string
 
-	if m.mode != SearchMode {
+	switch m.mode {
+	case NormalMode:
 		title := m.titleStyle.Render("Select a branch")
 
 		sections = append(sections, title)
+		if len(m.branches) == 0 {
+			sections = append(sections, m.unselectedStyle.Render("No branches found"))
+			return strings.Join(sections, "\n")
+		}
+
 		startIdx := m.scrollOffset
 		endIdx := min(startIdx+m.visibleLines, len(m.branches))
 
@@ -67,8 +78,28 @@ func (m BranchSwitcherModel) View() string {
 		for i := startIdx; i < endIdx; i++ {
 			sections = append(sections, m.renderBranches(i))
 		}
+		if len(m.branches) > m.visibleLines {
+			sections = append(sections, "")
+			sections = append(sections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(m.branches))))
+		}
 
-	} else {
+	case SearchResultsMode:
+		title := m.titleStyle.Render(fmt.Sprintf("Results for \"%s\" (%d matches)", m.searchQuery, len(m.filteredIndices)))
+		sections = append(sections, title)
+
+		startIdx := m.scrollOffset
+		endIdx := min(startIdx+m.visibleLines, len(m.filteredIndices))
+
+		// Render only the branches that matched the search
+		for _, idx := range m.filteredIndices[startIdx:endIdx] {
+			sections = append(sections, m.renderBranches(idx))
+		}
+		if len(m.filteredIndices) > m.visibleLines {
+			sections = append(sections, "")
+			sections = append(sections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(m.filteredIndices))))
+		}
+
+	default:
 		searchTitle := m.titleStyle.Render("Search branches:")
 		sections = append(sections, searchTitle)
 		sections = append(sections, m.searchInput.View())
@@ -80,7 +111,12 @@ func (m BranchSwitcherModel) View() string {
 				resultsTitle := m.titleStyle.Render(fmt.Sprintf("Results (%d matches)", len(m.filteredIndices)))
 				sections = append(sections, resultsTitle)
 
-				for _, idx := range m.filteredIndices {
+				// Only as many as fit; enter browses them all.
+				shown := m.filteredIndices
+				if m.visibleLines > 0 && len(shown) > m.visibleLines-2 {
+					shown = shown[:max(m.visibleLines-2, 1)]
+				}
+				for _, idx := range shown {
 					if idx >= len(m.branches) {
 						continue
 					}
@@ -88,6 +124,9 @@ func (m BranchSwitcherModel) View() string {
 					// Render branches
 					sections = append(sections, m.renderBranches(idx))
 				}
+				if more := len(m.filteredIndices) - len(shown); more > 0 {
+					sections = append(sections, m.helpStyle.Render(fmt.Sprintf("… and %d more (enter to browse them)", more)))
+				}
 			}
 		} else {
 			sections = append(sections, m.unselectedStyle.Render("Type to search..."))
@@ -103,7 +142,7 @@ func NewBranchBranchSwitcherModel(repo *git.GitRepo, remote bool) BranchSwitcher
 	searchInput.CharLimit = 100
 	searchInput.Width = 50
 
-	branches, err := repo.GetAllBranches(remote)
+	branches, err := repo.SwitcherBranches(remote)
 
 	if err != nil {
 		fmt.Printf("Error initializing branch viewer %s", err)
@@ -120,6 +159,7 @@ func NewBranchBranchSwitcherModel(repo *git.GitRepo, remote bool) BranchSwitcher
 		titleStyle:      TitlePeachStyle,
 		selectedStyle:   SelectedPeachStyle,
 		unselectedStyle: UnselectedBoldStyle,
+		helpStyle:       HelpStyle,
 	}
 }
 
@@ -131,36 +171,20 @@ func (m BranchSwitcherModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "esc":
-				m.mode = NormalMode
-				m.searchInput.SetValue("")
-				m.searchQuery = ""
-				m.filteredIndices = nil
-				allBranches, err := m.repo.GetAllBranches(m.remote)
-
-				if err != nil {
-					return m, nil
-				}
-
-				m.branches = allBranches
+				m.clearSearch()
 				return m, nil
 			case "enter":
+				m.searchInput.Blur()
 				if len(m.filteredIndices) > 0 {
-					filteredBranches := make([]string, len(m.filteredIndices))
-					for i, idx := range m.filteredIndices {
-						filteredBranches[i] = m.branches[idx]
-					}
-					m.branches = filteredBranches
-				} else if m.searchQuery == "" {
-					allBranches, err := m.repo.GetAllBranches(m.remote)
-
-					if err != nil {
-						return m, nil
-					}
-
-					m.branches = allBranches
+					// Keep the full list and navigate the matches by position,
+					// keeping currentIndex pointed at the underlying branch.
+					m.mode = SearchResultsMode
+					m.searchSelected = 0
+					m.currentIndex = m.filteredIndices[0]
+				} else {
+					m.clearSearch()
 				}
-				m.mode = NormalMode
-				m.currentIndex = 0
+				m.scrollOffset = 0
 				return m, nil
 			}
 		}
@@ -182,28 +206,54 @@ func (m BranchSwitcherModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.visibleLines = msg.Height - 6
+		// A smaller window can leave the cursor below the list.
+		m.adjustScrolling()
 
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "q", "esc":
+		case "q":
+			return m, tea.Quit
+
+		case "esc":
+			if m.mode == SearchResultsMode {
+				m.clearSearch()
+				return m, nil
+			}
 			return m, tea.Quit
 
 		case "j":
-			if len(m.branches) > 0 {
+			if m.mode == SearchResultsMode {
+				if len(m.filteredIndices) > 0 {
+					m.searchSelected = (m.searchSelected + 1) % len(m.filteredIndices)
+					m.currentIndex = m.filteredIndices[m.searchSelected]
+					m.adjustScrolling()
+				}
+			} else if len(m.branches) > 0 {
 				m.currentIndex = (m.currentIndex + 1) % len(m.branches)
 				m.adjustScrolling()
 			}
 
 		case "k":
-			if len(m.branches) > 0 {
+			if m.mode == SearchResultsMode {
+				if len(m.filteredIndices) > 0 {
+					m.searchSelected = (m.searchSelected - 1 + len(m.filteredIndices)) % len(m.filteredIndices)
+					m.currentIndex = m.filteredIndices[m.searchSelected]
+					m.adjustScrolling()
+				}
+			} else if len(m.branches) > 0 {
 				m.currentIndex = (m.currentIndex - 1 + len(m.branches)) % len(m.branches)
 				m.adjustScrolling()
 			}
 
 		case "enter":
+			if m.currentIndex < 0 || m.currentIndex >= len(m.branches) {
+				return m, nil
+			}
+
 			isClean, err := m.repo.IsClean()
 			if err != nil {
-				return m, nil
+				m.err = err
+				return m, tea.Quit
 			}
 
 			branch := m.branches[m.currentIndex]
@@ -212,17 +262,18 @@ func (m BranchSwitcherModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				err = m.repo.Stash("Dirty working directory while switching to " + branch)
 
 				if err != nil {
-					return m, nil
+					m.err = err
+					return m, tea.Quit
 				}
 			}
 
 			err = m.repo.SwitchBranch(branch)
 			if err != nil {
-				return m, nil
+				m.err = err
+				return m, tea.Quit
 			}
 
-			fmt.Printf("Successfully switched to branch '%s'.\n", branch)
-
+			m.switchedTo = branch
 			return m, tea.Quit
 
 		case "/":
@@ -230,12 +281,50 @@ func (m BranchSwitcherModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.searchInput.Focus()
 			m.searchInput.SetValue(m.searchQuery)
 			return m, nil
+
+		case "r":
+			if err := m.refresh(); err != nil {
+				m.err = err
+				return m, tea.Quit
+			}
 		}
 	}
 
 	return m, cmd
 }
 
+// refresh rereads the branch list from git, bypassing the branch cache.
+func (m *BranchSwitcherModel) refresh() error {
+	m.repo.InvalidateBranchCache()
+	branches, err := m.repo.SwitcherBranches(m.remote)
+	if err != nil {
+		return err
+	}
+	m.branches = branches
+	m.currentIndex = min(m.currentIndex, max(len(branches)-1, 0))
+	if m.mode == SearchResultsMode {
+		m.performSearch()
+		if len(m.filteredIndices) == 0 {
+			m.clearSearch()
+			return nil
+		}
+		m.currentIndex = m.filteredIndices[0]
+	}
+	m.adjustScrolling()
+	return nil
+}
+
+// clearSearch drops the active filter and returns to the full branch list.
+func (m *BranchSwitcherModel) clearSearch() {
+	m.mode = NormalMode
+	m.searchInput.Blur()
+	m.searchInput.SetValue("")
+	m.searchQuery = ""
+	m.filteredIndices = nil
+	m.searchSelected = 0
+	m.adjustScrolling()
+}
+
 func (m *BranchSwitcherModel) performSearch() {
 	if m.searchQuery == "" {
 		m.filteredIndices = nil
@@ -256,18 +345,24 @@ func (m *BranchSwitcherModel) performSearch() {
 	m.searchSelected = 0
 }
 
-func SwitchBranches(repo *git.GitRepo, remote bool) ([]string, error) {
+// SwitchBranches runs the branch switcher and returns the branch that was
+// checked out, or "" if the user cancelled.
+func SwitchBranches(repo *git.GitRepo, remote bool) (string, error) {
 	m := NewBranchBranchSwitcherModel(repo, remote)
 
 	program := tea.NewProgram(m, tea.WithAltScreen())
 
-	_, err := program.Run()
+	finalModel, err := program.Run()
 
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+
+	if bm, ok := finalModel.(BranchSwitcherModel); ok {
+		return bm.switchedTo, bm.err
 	}
 
-	return []string{}, nil
+	return "", nil
 }
 
 func (m *BranchSwitcherModel) adjustScrolling() {
@@ -275,18 +370,25 @@ func (m *BranchSwitcherModel) adjustScrolling() {
 		return
 	}
 
+	// In results mode the visible list is the filtered set, so scroll by
+	// position within the matches rather than by branch index.
+	pos, total := m.currentIndex, len(m.branches)
+	if m.mode == SearchResultsMode {
+		pos, total = m.searchSelected, len(m.filteredIndices)
+	}
+
 	// If current item is below visible area, scroll down
-	if m.currentIndex >= m.scrollOffset+m.visibleLines {
-		m.scrollOffset = m.currentIndex - m.visibleLines + 1
+	if pos >= m.scrollOffset+m.visibleLines {
+		m.scrollOffset = pos - m.visibleLines + 1
 	}
 
 	// If current item is above visible area, scroll up
-	if m.currentIndex < m.scrollOffset {
-		m.scrollOffset = m.currentIndex
+	if pos < m.scrollOffset {
+		m.scrollOffset = pos
 	}
 
 	// Ensure we don't scroll past the end
-	maxOffset := len(m.branches) - m.visibleLines
+	maxOffset := total - m.visibleLines
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
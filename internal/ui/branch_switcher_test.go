@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBranchSwitcherScrollsToLaterBranches(t *testing.T) {
+	repo := newStatusRepo(t, nil, nil)
+	// branch-01 to branch-19 and main make 20, listed in name order.
+	for i := 1; i < 20; i++ {
+		cmd := exec.Command("git", "branch", fmt.Sprintf("branch-%02d", i))
+		cmd.Dir = repo.WorkDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git branch: %v\n%s", err, out)
+		}
+	}
+	var model tea.Model = NewBranchBranchSwitcherModel(repo, false)
+	if n := len(model.(BranchSwitcherModel).branches); n != 20 {
+		t.Fatalf("switcher lists %d branches, want 20", n)
+	}
+	// Room for 6 branches.
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 80, Height: 12})
+	for i := 0; i < 14; i++ {
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	}
+
+	m := model.(BranchSwitcherModel)
+	if got := m.branches[m.currentIndex]; got != "branch-15" {
+		t.Fatalf("after 14 steps %q is selected, want branch-15", got)
+	}
+	view := m.View()
+	if !strings.Contains(view, ">  branch-15") {
+		t.Errorf("branch-15 is not shown selected:\n%s", view)
+	}
+	if strings.Contains(view, "branch-01") {
+		t.Errorf("list did not scroll past the first branches:\n%s", view)
+	}
+}
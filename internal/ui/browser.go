@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+// ErrNoBrowser is returned by OpenURL when there is no way to open a
+// browser, e.g. over ssh. Callers should print the URL instead.
+var ErrNoBrowser = errors.New("no browser available")
+
+// RemoteWebURL returns the web page of the origin remote, or of the
+// current branch on it when branch is set.
+func RemoteWebURL(repo *git.GitRepo, branch bool) (string, error) {
+	remote, err := repo.RemoteURL("origin")
+	if err != nil {
+		return "", err
+	}
+	url, err := git.WebURL(remote)
+	if err != nil || !branch {
+		return url, err
+	}
+	name, err := repo.GetCurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	return git.TreeURL(url, name), nil
+}
+
+// OpenURL opens url in the default browser with the OS opener.
+func OpenURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		if _, err := exec.LookPath("xdg-open"); err != nil {
+			return ErrNoBrowser
+		}
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Run(); err != nil {
+		return ErrNoBrowser
+	}
+	return nil
+}
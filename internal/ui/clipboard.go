@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+// CopyBranchName puts the current branch name on the clipboard, or with
+// remote the upstream it tracks (e.g. "origin/feature"), and returns what
+// was copied.
+func CopyBranchName(repo *git.GitRepo, remote bool) (string, error) {
+	var name string
+	var err error
+	if remote {
+		name, err = repo.UpstreamName()
+	} else {
+		name, err = repo.GetCurrentBranch()
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := clipboard.WriteAll(name); err != nil {
+		return "", fmt.Errorf("copy to clipboard: %w", err)
+	}
+	return name, nil
+}
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PaletteCommands lists the command names offered for completion in the
+// command palette. cmd fills it in, since ui cannot import the command tree.
+var PaletteCommands func() []string
+
+// PaletteCommand builds the process for a line typed into the command
+// palette. interactive reports whether it needs the terminal (it opens a
+// TUI, an editor or a prompt) rather than having its output captured.
+var PaletteCommand func(input string) (cmd *exec.Cmd, interactive bool, err error)
+
+type paletteResultMsg struct {
+	input  string
+	output string
+	err    error
+}
+
+type commandPalette struct {
+	input   textinput.Model
+	matches []string
+}
+
+func newCommandPalette() commandPalette {
+	ti := textinput.New()
+	ti.Prompt = ":"
+	ti.Placeholder = "command"
+	ti.CharLimit = 200
+	ti.Width = 60
+	return commandPalette{input: ti}
+}
+
+func (p *commandPalette) open() tea.Cmd {
+	p.input.SetValue("")
+	p.input.Focus()
+	p.updateMatches()
+	return textinput.Blink
+}
+
+func (p *commandPalette) close() {
+	p.input.Blur()
+}
+
+func (p *commandPalette) update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	p.updateMatches()
+	return cmd
+}
+
+// updateMatches fuzzy-matches the first word against the command names
+// until an argument has been started.
+func (p *commandPalette) updateMatches() {
+	p.matches = nil
+	if PaletteCommands == nil || strings.Contains(p.input.Value(), " ") {
+		return
+	}
+	query := strings.ToLower(p.input.Value())
+	for _, name := range PaletteCommands() {
+		if fuzzyMatchStr(name, query) {
+			p.matches = append(p.matches, name)
+		}
+	}
+}
+
+// complete replaces the typed command name with the best match.
+func (p *commandPalette) complete() {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.input.SetValue(p.matches[0] + " ")
+	p.input.CursorEnd()
+	p.updateMatches()
+}
+
+// run executes the typed command, handing over the terminal when the
+// command needs it and capturing its output otherwise.
+func (p commandPalette) run() tea.Cmd {
+	input := strings.TrimSpace(p.input.Value())
+	if input == "" || PaletteCommand == nil {
+		return nil
+	}
+	cmd, interactive, err := PaletteCommand(input)
+	if err != nil {
+		return func() tea.Msg {
+			return paletteResultMsg{input: input, err: err}
+		}
+	}
+	if interactive {
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return paletteResultMsg{input: input, err: err}
+		})
+	}
+	// An empty stdin tells the command nobody can answer prompts.
+	cmd.Stdin = strings.NewReader("")
+	return func() tea.Msg {
+		output, err := cmd.CombinedOutput()
+		return paletteResultMsg{input: input, output: string(output), err: err}
+	}
+}
+
+func (p commandPalette) view(helpStyle lipgloss.Style) string {
+	lines := []string{p.input.View()}
+	if len(p.matches) > 0 {
+		shown := p.matches[:min(len(p.matches), 8)]
+		lines = append(lines, helpStyle.Render("  "+strings.Join(shown, "  ")))
+	}
+	lines = append(lines, helpStyle.Render("tab: complete  enter: run  esc: cancel"))
+	return strings.Join(lines, "\n")
+}
+
+// paletteMessage summarises a command's result for the message area,
+// keeping the last few lines of its output.
+func paletteMessage(msg paletteResultMsg) string {
+	output := strings.TrimSpace(msg.output)
+	if output == "" {
+		if msg.err != nil {
+			return fmt.Sprintf("✗ %s: %v", msg.input, msg.err)
+		}
+		return fmt.Sprintf("✓ %s", msg.input)
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) > 5 {
+		lines = lines[len(lines)-5:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -2,10 +2,14 @@ package ui
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
@@ -14,8 +18,23 @@ type CommitInputModel struct {
 	textInput textinput.Model
 	committed bool
 	amend     bool
+	all       bool // stage tracked changes first, like git commit -a
 	err       error
 
+	// Recent subjects offered with up/down, loaded on first use. recentIdx
+	// is -1 while showing the user's own draft.
+	recent       []string
+	recentLoaded bool
+	recentIdx    int
+	draft        string
+
+	// subjectLimit is the subject length past which the character count
+	// turns into a warning; 0 hides the count.
+	subjectLimit int
+
+	// signOffTrailer is what repo.SignOff adds, shown while it is on.
+	signOffTrailer string
+
 	// When true, the model is embedded inside another TUI and must not call
 	// tea.Quit on its own — the parent observes committed/canceled and
 	// transitions away from the modal itself.
@@ -41,11 +60,14 @@ func NewCommitInputModel(repo *git.GitRepo) CommitInputModel {
 	ti.Width = 50
 
 	return CommitInputModel{
-		repo:       repo,
-		textInput:  ti,
-		titleStyle: TitlePinkStyle,
-		errorStyle: ErrorStyle,
-		helpStyle:  HelpStyle,
+		repo:           repo,
+		textInput:      ti,
+		recentIdx:      -1,
+		subjectLimit:   config.Load().SubjectMaxLength,
+		signOffTrailer: repo.SignOffTrailer(),
+		titleStyle:     TitlePinkStyle,
+		errorStyle:     ErrorStyle,
+		helpStyle:      HelpStyle,
 	}
 }
 
@@ -73,6 +95,18 @@ func (m CommitInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, m.commitWithMessage(message)
 
+		case "up", "ctrl+p":
+			m.cycleRecent(1)
+			return m, nil
+
+		case "ctrl+s":
+			m.repo.SignOff = !m.repo.SignOff
+			return m, nil
+
+		case "down", "ctrl+n":
+			m.cycleRecent(-1)
+			return m, nil
+
 		default:
 			m.textInput, cmd = m.textInput.Update(msg)
 			return m, cmd
@@ -108,17 +142,26 @@ func (m CommitInputModel) View() string {
 
 	// Title
 	titleText := "Commit Changes"
-	helpText := "enter: commit | esc: cancel"
+	helpText := "enter: commit | ↑/↓: recent messages | ctrl+s: sign-off | esc: cancel"
 	if m.amend {
 		titleText = "Amend Last Commit"
-		helpText = "enter: amend | esc: cancel"
+		helpText = "enter: amend | ↑/↓: recent messages | ctrl+s: sign-off | esc: cancel"
+	} else if m.all {
+		titleText = "Commit All Tracked Changes"
 	}
 	sections = append(sections, m.titleStyle.Render(titleText))
 	sections = append(sections, "")
 
 	// Input
 	sections = append(sections, m.textInput.View())
-	sections = append(sections, "")
+	sections = append(sections, m.lengthHint())
+	if m.repo.SignOff {
+		trailer := m.signOffTrailer
+		if trailer == "" {
+			trailer = "Signed-off-by: (committer identity not set)"
+		}
+		sections = append(sections, DimStyle.Render("+ "+trailer))
+	}
 
 	// Help
 	help := m.helpStyle.Render(helpText)
@@ -127,11 +170,50 @@ func (m CommitInputModel) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
+// cycleRecent fills the input with an older (step 1) or newer (step -1)
+// recent subject. Stepping past the newest brings back what the user had
+// typed.
+func (m *CommitInputModel) cycleRecent(step int) {
+	if !m.recentLoaded {
+		m.recentLoaded = true
+		m.recent, _ = m.repo.RecentSubjects(20)
+	}
+	next := m.recentIdx + step
+	if next < -1 || next >= len(m.recent) {
+		return
+	}
+	if m.recentIdx == -1 {
+		m.draft = m.textInput.Value()
+	}
+	m.recentIdx = next
+	if next == -1 {
+		m.textInput.SetValue(m.draft)
+	} else {
+		m.textInput.SetValue(m.recent[next])
+	}
+	m.textInput.CursorEnd()
+}
+
+// lengthHint counts the subject's characters, dimly while it fits within
+// subjectLimit and as a warning once it doesn't.
+func (m CommitInputModel) lengthHint() string {
+	n := utf8.RuneCountInString(m.textInput.Value())
+	if m.subjectLimit <= 0 || n == 0 {
+		return ""
+	}
+	if n > m.subjectLimit {
+		return m.errorStyle.Render(fmt.Sprintf("%d/%d — longer than the subject length limit", n, m.subjectLimit))
+	}
+	return DimStyle.Render(fmt.Sprintf("%d/%d", n, m.subjectLimit))
+}
+
 func (m CommitInputModel) commitWithMessage(message string) tea.Cmd {
 	return func() tea.Msg {
 		var err error
 		if m.amend {
 			err = m.repo.AmendCommit(message, false)
+		} else if m.all {
+			err = m.repo.CommitAll(message)
 		} else {
 			err = m.repo.Commit(message)
 		}
@@ -143,7 +225,41 @@ func (m CommitInputModel) commitWithMessage(message string) tea.Cmd {
 }
 
 func StartCommitInput(repo *git.GitRepo) error {
+	return runCommitInput(NewCommitInputModel(repo))
+}
+
+// StartCommitAllInput asks for a message, then commits every tracked
+// change whether or not it was staged.
+func StartCommitAllInput(repo *git.GitRepo) error {
 	m := NewCommitInputModel(repo)
+	m.all = true
+	return runCommitInput(m)
+}
+
+// editorCommitCommand is git commit with the message written in an
+// editor: config editor when set, otherwise whatever git would pick.
+func editorCommitCommand(repo *git.GitRepo, all bool) *exec.Cmd {
+	cmd := repo.CommitEditorCommand(all)
+	if editor := config.Load().Editor; editor != "" {
+		cmd.Env = append(os.Environ(), "GIT_EDITOR="+editor)
+	}
+	return cmd
+}
+
+// StartEditorCommit commits with the message written in an editor instead
+// of the inline prompt, for commit_use_editor.
+func StartEditorCommit(repo *git.GitRepo, all bool) error {
+	cmd := editorCommitCommand(repo, all)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
+
+func runCommitInput(m CommitInputModel) error {
 	p := tea.NewProgram(m)
 	model, err := p.Run()
 	if err != nil {
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
@@ -17,14 +19,26 @@ type conflictResolvedMsg struct {
 }
 
 type conflictRefreshMsg struct {
-	files []git.FileStatus
-	err   error
+	files  []git.FileStatus
+	counts map[string]int
+	staged []string // files staged because their last markers were removed
+	err    error
 }
 
+// ConflictsPickerModel lists every file that was conflicted when it
+// opened. Resolved files stay in the list, marked done, and the merge can
+// only be finished once none are left.
 type ConflictsPickerModel struct {
 	repo         *git.GitRepo
 	files        []git.FileStatus
+	counts       map[string]int  // conflict regions left per file
+	resolved     map[string]bool // files no longer conflicted
+	finished     bool            // the user asked to finish the merge
+	confirmAll   string          // side awaiting y/N to resolve every file, "" if none
+	preview      string          // side being previewed for the current file, "" if none
 	currentIndex int
+	conflict     int   // conflict within the current file; -1 means its last
+	markers      []int // line of each conflict in the current file
 	width        int
 	height       int
 
@@ -44,8 +58,10 @@ type ConflictsPickerModel struct {
 
 func NewConflictsPickerModel(repo *git.GitRepo, files []git.FileStatus) ConflictsPickerModel {
 	m := ConflictsPickerModel{
-		repo:  repo,
-		files: files,
+		repo:     repo,
+		files:    files,
+		counts:   make(map[string]int),
+		resolved: make(map[string]bool),
 
 		titleStyle:      TitlePinkStyle,
 		selectedStyle:   SelectedPeachStyle,
@@ -65,11 +81,28 @@ func NewConflictsPickerModel(repo *git.GitRepo, files []git.FileStatus) Conflict
 
 func (m ConflictsPickerModel) Init() tea.Cmd {
 	if len(m.files) > 0 {
-		return m.loadCurrentContent()
+		return tea.Batch(m.loadCurrentContent(), m.refresh())
 	}
 	return nil
 }
 
+// remaining returns how many files still have conflicts.
+func (m ConflictsPickerModel) remaining() int {
+	return len(m.files) - len(m.resolved)
+}
+
+// nextUnresolved moves the cursor to the first unresolved file at or after
+// it, wrapping around.
+func (m *ConflictsPickerModel) nextUnresolved() {
+	for i := range m.files {
+		idx := (m.currentIndex + i) % len(m.files)
+		if !m.resolved[m.files[idx].Path] {
+			m.currentIndex = idx
+			return
+		}
+	}
+}
+
 func (m ConflictsPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -89,6 +122,14 @@ func (m ConflictsPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if dv, ok := updatedDiff.(DiffViewerModel); ok {
 			m.diffViewer = dv
 		}
+		if m.preview != "" {
+			return m, diffCmd
+		}
+		m.markers = conflictMarkerLines(msg.content)
+		if m.conflict < 0 || m.conflict >= len(m.markers) {
+			m.conflict = max(len(m.markers)-1, 0)
+		}
+		m.scrollToConflict()
 		return m, diffCmd
 
 	case conflictResolvedMsg:
@@ -106,14 +147,39 @@ func (m ConflictsPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.showLastStatus = true
 			return m, nil
 		}
-		m.files = msg.files
-		if len(m.files) == 0 {
-			fmt.Println("\nAll conflicts resolved!")
-			return m, tea.Quit
+		conflicted := make(map[string]bool, len(msg.files))
+		for _, f := range msg.files {
+			conflicted[f.Path] = true
+		}
+		for _, f := range m.files {
+			if conflicted[f.Path] {
+				delete(m.resolved, f.Path)
+			} else {
+				m.resolved[f.Path] = true
+			}
+			delete(conflicted, f.Path)
+		}
+		// Files that became conflicted since the list was built.
+		for _, f := range msg.files {
+			if conflicted[f.Path] {
+				m.files = append(m.files, f)
+			}
+		}
+		m.counts = msg.counts
+		if len(msg.staged) > 0 {
+			m.lastStatus = fmt.Sprintf("✓ Staged %s (no conflict markers left)", strings.Join(msg.staged, ", "))
+			m.showLastStatus = true
+		}
+
+		if m.remaining() == 0 {
+			m.lastStatus = "✓ All conflicts resolved — press f to finish the merge"
+			m.showLastStatus = true
+			return m, nil
 		}
-		if m.currentIndex >= len(m.files) {
-			m.currentIndex = len(m.files) - 1
+		if m.resolved[m.files[m.currentIndex].Path] {
+			m.nextUnresolved()
 		}
+		// Reload even an unresolved file: it may have been edited.
 		return m, m.loadCurrentContent()
 
 	case tea.KeyMsg:
@@ -133,10 +199,61 @@ func (m ConflictsPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		// A previewed resolution is applied or dropped by the next key.
+		if m.preview != "" {
+			side := m.preview
+			filePath := m.files[m.currentIndex].Path
+			switch msg.String() {
+			case "y", "enter":
+				m.preview = ""
+				if side == "theirs" {
+					return m, m.resolveTheirs(filePath)
+				}
+				return m, m.resolveOurs(filePath)
+			case "n", "esc", "q":
+				m.preview = ""
+				conflict := m.conflict
+				cmd := m.loadCurrentContent()
+				m.conflict = conflict
+				return m, cmd
+			}
+			return m, nil
+		}
+
+		// A pending resolve-all takes the next key as its answer.
+		if m.confirmAll != "" {
+			side := m.confirmAll
+			m.confirmAll = ""
+			if msg.String() != "y" {
+				m.lastStatus = "Canceled"
+				m.showLastStatus = true
+				return m, nil
+			}
+			return m, m.resolveAll(side)
+		}
+
+		switch msg.String() {
+		case "o", "t", "e", "m":
+			if len(m.files) > 0 && m.resolved[m.files[m.currentIndex].Path] {
+				m.lastStatus = fmt.Sprintf("✓ %s is already resolved", m.files[m.currentIndex].Path)
+				m.showLastStatus = true
+				return m, nil
+			}
+		}
+
 		switch msg.String() {
 		case "q", "esc":
 			return m, tea.Quit
 
+		case "f":
+			if m.remaining() > 0 {
+				m.lastStatus = fmt.Sprintf("✗ %d file(s) still have conflicts", m.remaining())
+				m.showLastStatus = true
+				return m, nil
+			}
+			m.finished = true
+			return m, tea.Quit
+
 		case "j", "down":
 			if len(m.files) > 0 {
 				m.currentIndex = (m.currentIndex + 1) % len(m.files)
@@ -151,28 +268,61 @@ func (m ConflictsPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "o":
 			if len(m.files) > 0 {
-				filePath := m.files[m.currentIndex].Path
-				return m, m.resolveOurs(filePath)
+				return m, m.showPreview("ours")
 			}
 
 		case "t":
 			if len(m.files) > 0 {
-				filePath := m.files[m.currentIndex].Path
-				return m, m.resolveTheirs(filePath)
+				return m, m.showPreview("theirs")
+			}
+
+		case "r":
+			return m, m.refresh()
+
+		case "n":
+			return m, m.nextConflict()
+
+		case "p":
+			return m, m.prevConflict()
+
+		case "O":
+			if m.remaining() > 0 {
+				m.confirmAll = "ours"
+			}
+
+		case "T":
+			if m.remaining() > 0 {
+				m.confirmAll = "theirs"
 			}
 
 		case "e":
 			if len(m.files) > 0 {
 				filePath := m.files[m.currentIndex].Path
-				editor := resolveEditor()
-				editorCmd := exec.Command(editor, filePath)
+				line := 0
+				if m.conflict < len(m.markers) {
+					line = m.markers[m.conflict] + 1
+				}
+				repo := m.repo
+				editorCmd := editorCommand(filePath, line)
+				editorCmd.Dir = repo.WorkDir
 				return m, tea.ExecProcess(editorCmd, func(err error) tea.Msg {
 					if err != nil {
 						return conflictResolvedMsg{filePath: filePath, err: err}
 					}
-					addCmd := exec.Command("git", "add", filePath)
-					addErr := addCmd.Run()
-					return conflictResolvedMsg{filePath: filePath, err: addErr}
+					// Leftover markers keep the file flagged as conflicted.
+					return conflictResolvedMsg{filePath: filePath, err: repo.StageIfResolved(filePath)}
+				})
+			}
+
+		case "m":
+			if len(m.files) > 0 {
+				filePath := m.files[m.currentIndex].Path
+				repo := m.repo
+				return m, tea.ExecProcess(repo.MergetoolCommand(filePath), func(err error) tea.Msg {
+					if err != nil {
+						return conflictResolvedMsg{filePath: filePath, err: err}
+					}
+					return conflictResolvedMsg{filePath: filePath, err: repo.StageIfResolved(filePath)}
 				})
 			}
 		}
@@ -189,7 +339,11 @@ func (m ConflictsPickerModel) View() string {
 
 	// ── Left panel ────────────────────────────────────────────────────────
 	var left []string
-	left = append(left, m.titleStyle.Render(fmt.Sprintf("Merge Conflicts (%d remaining)", len(m.files))))
+	title := m.titleStyle.Render(fmt.Sprintf("Merge Conflicts (%d of %d resolved)", len(m.resolved), len(m.files)))
+	if pos, total := m.conflictPosition(); pos > 0 {
+		title += m.helpStyle.Render(fmt.Sprintf("  conflict %d of %d", pos, total))
+	}
+	left = append(left, title)
 
 	if m.showLastStatus {
 		style := m.successStyle
@@ -199,6 +353,16 @@ func (m ConflictsPickerModel) View() string {
 		left = append(left, style.Render(m.lastStatus))
 	}
 
+	if m.confirmAll != "" {
+		left = append(left, m.errorStyle.Render(fmt.Sprintf(
+			"Take %s for all %d conflicted file(s)? y/N", m.confirmAll, m.remaining())))
+	}
+	if m.preview != "" {
+		left = append(left, m.errorStyle.Render(fmt.Sprintf(
+			"Take %s for %s? The preview shows the change. y/enter: apply  n/esc: cancel",
+			m.preview, m.files[m.currentIndex].Path)))
+	}
+
 	left = append(left, "")
 
 	for i, f := range m.files {
@@ -208,13 +372,113 @@ func (m ConflictsPickerModel) View() string {
 			prefix = "> "
 			style = m.selectedStyle
 		}
-		left = append(left, style.Render(fmt.Sprintf("%s[%s] %s", prefix, f.Status, f.Path)))
+		if m.resolved[f.Path] {
+			left = append(left, style.Render(fmt.Sprintf("%s[✓]  %s", prefix, f.Path))+m.helpStyle.Render("  resolved"))
+			continue
+		}
+		detail := ""
+		if n := m.counts[f.Path]; n == 1 {
+			detail = "  1 conflict"
+		} else if n > 1 {
+			detail = fmt.Sprintf("  %d conflicts", n)
+		}
+		left = append(left, style.Render(fmt.Sprintf("%s[%s] %s", prefix, f.Status, f.Path))+m.helpStyle.Render(detail))
+	}
+
+	left = append(left, "")
+	if m.remaining() == 0 {
+		left = append(left, m.helpStyle.Render("j/k: navigate  f: finish merge  q: quit"))
+	} else {
+		left = append(left, m.helpStyle.Render("j/k: navigate  n/p: next/prev conflict  o/t: ours/theirs  O/T: all ours/theirs  e: edit  m: mergetool  r: refresh  q: quit"))
 	}
 
 	leftPanel := lipgloss.NewStyle().Width(leftWidth).Render(strings.Join(left, "\n"))
 	separator := m.separatorStyle.Render(strings.Repeat("│\n", m.height))
 
-	return lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, separator, m.diffViewer.View())
+	return lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, separator, m.diffViewer.paneView())
+}
+
+// conflictMarkerLines returns the line index of each conflict's opening
+// marker in content.
+func conflictMarkerLines(content string) []int {
+	var lines []int
+	for i, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") {
+			lines = append(lines, i)
+		}
+	}
+	return lines
+}
+
+// scrollToConflict brings the current conflict into view, with a little
+// context above it.
+func (m *ConflictsPickerModel) scrollToConflict() {
+	if m.conflict < len(m.markers) {
+		m.diffViewer.viewport.SetYOffset(max(m.markers[m.conflict]-2, 0))
+	}
+}
+
+// nextConflict moves to the next conflict, crossing into the next file
+// that still has conflicts once the current one runs out.
+func (m *ConflictsPickerModel) nextConflict() tea.Cmd {
+	if m.conflict+1 < len(m.markers) && !m.resolved[m.files[m.currentIndex].Path] {
+		m.conflict++
+		m.scrollToConflict()
+		return nil
+	}
+	for i := 1; i <= len(m.files); i++ {
+		idx := (m.currentIndex + i) % len(m.files)
+		if m.hasConflicts(idx) {
+			m.currentIndex = idx
+			return m.loadCurrentContent()
+		}
+	}
+	return nil
+}
+
+// prevConflict moves to the previous conflict, crossing back into the
+// previous file that still has conflicts and landing on its last one.
+func (m *ConflictsPickerModel) prevConflict() tea.Cmd {
+	if m.conflict > 0 && !m.resolved[m.files[m.currentIndex].Path] {
+		m.conflict--
+		m.scrollToConflict()
+		return nil
+	}
+	for i := 1; i <= len(m.files); i++ {
+		idx := (m.currentIndex - i + len(m.files)) % len(m.files)
+		if m.hasConflicts(idx) {
+			m.currentIndex = idx
+			cmd := m.loadCurrentContent()
+			m.conflict = -1
+			return cmd
+		}
+	}
+	return nil
+}
+
+// hasConflicts reports whether the file at idx is unresolved and has
+// conflict regions left.
+func (m ConflictsPickerModel) hasConflicts(idx int) bool {
+	path := m.files[idx].Path
+	return !m.resolved[path] && m.counts[path] > 0
+}
+
+// conflictPosition returns the current conflict's place among all the
+// conflicts left, or 0 when the current file has none.
+func (m ConflictsPickerModel) conflictPosition() (pos, total int) {
+	for i, f := range m.files {
+		if m.resolved[f.Path] {
+			continue
+		}
+		if i < m.currentIndex {
+			pos += m.counts[f.Path]
+		}
+		total += m.counts[f.Path]
+	}
+	if len(m.files) == 0 || !m.hasConflicts(m.currentIndex) || len(m.markers) == 0 {
+		return 0, total
+	}
+	return pos + min(m.conflict, m.counts[m.files[m.currentIndex].Path]-1) + 1, total
 }
 
 // loadCurrentContent loads the conflict content for the currently selected file.
@@ -222,17 +486,11 @@ func (m *ConflictsPickerModel) loadCurrentContent() tea.Cmd {
 	if len(m.files) == 0 {
 		return nil
 	}
+	m.conflict = 0
+	m.markers = nil
 	filePath := m.files[m.currentIndex].Path
 	m.diffViewer = NewDiffViewerModel(m.repo, filePath)
-	if m.width > 0 {
-		leftWidth := m.width / 2
-		rightWidth := m.width - leftWidth - 1
-		sizeMsg := tea.WindowSizeMsg{Width: rightWidth, Height: m.height}
-		updatedDiff, _ := m.diffViewer.Update(sizeMsg)
-		if dv, ok := updatedDiff.(DiffViewerModel); ok {
-			m.diffViewer = dv
-		}
-	}
+	m.sizeDiffViewer()
 	repo := m.repo
 	return func() tea.Msg {
 		content, err := repo.GetConflictContent(filePath)
@@ -240,6 +498,33 @@ func (m *ConflictsPickerModel) loadCurrentContent() tea.Cmd {
 	}
 }
 
+// sizeDiffViewer fits a freshly built content panel to the right half.
+func (m *ConflictsPickerModel) sizeDiffViewer() {
+	if m.width <= 0 {
+		return
+	}
+	leftWidth := m.width / 2
+	rightWidth := m.width - leftWidth - 1
+	sizeMsg := tea.WindowSizeMsg{Width: rightWidth, Height: m.height}
+	updatedDiff, _ := m.diffViewer.Update(sizeMsg)
+	if dv, ok := updatedDiff.(DiffViewerModel); ok {
+		m.diffViewer = dv
+	}
+}
+
+// showPreview replaces the content panel with the change taking side would
+// make to the current file, and waits for confirmation.
+func (m *ConflictsPickerModel) showPreview(side string) tea.Cmd {
+	filePath := m.files[m.currentIndex].Path
+	repo := m.repo
+	m.preview = side
+	m.diffViewer = NewContentViewerModel(repo, fmt.Sprintf("%s, taking %s", filePath, side), func() (string, error) {
+		return repo.ResolutionPreview(filePath, side)
+	})
+	m.sizeDiffViewer()
+	return m.diffViewer.Init()
+}
+
 func (m ConflictsPickerModel) resolveOurs(filePath string) tea.Cmd {
 	return func() tea.Msg {
 		err := m.repo.ResolveConflictOurs(filePath)
@@ -254,16 +539,83 @@ func (m ConflictsPickerModel) resolveTheirs(filePath string) tea.Cmd {
 	}
 }
 
+// resolveAll takes one side for every file that still has conflicts,
+// stopping at the first failure.
+func (m ConflictsPickerModel) resolveAll(side string) tea.Cmd {
+	var paths []string
+	for _, f := range m.files {
+		if !m.resolved[f.Path] {
+			paths = append(paths, f.Path)
+		}
+	}
+	repo := m.repo
+	return func() tea.Msg {
+		resolved, err := repo.ResolveConflicts(paths, side)
+		if err != nil {
+			return conflictResolvedMsg{filePath: fmt.Sprintf("resolved %d of %d file(s)", len(resolved), len(paths)), err: err}
+		}
+		return conflictResolvedMsg{filePath: fmt.Sprintf("%d file(s) with %s", len(paths), side)}
+	}
+}
+
+// refresh reloads the conflicted files and their conflict counts. A
+// content conflict whose markers have all been removed since the last
+// refresh, e.g. in an editor outside cgit, is staged so git sees it as
+// resolved. Files that never had markers, like binary conflicts, are
+// left for the user to decide.
 func (m ConflictsPickerModel) refresh() tea.Cmd {
+	repo := m.repo
+	prev := m.counts
 	return func() tea.Msg {
-		files, err := m.repo.GetConflictedFiles()
-		return conflictRefreshMsg{files: files, err: err}
+		files, err := repo.GetConflictedFiles()
+		if err != nil {
+			return conflictRefreshMsg{err: err}
+		}
+		msg := conflictRefreshMsg{counts: make(map[string]int, len(files))}
+		for _, f := range files {
+			// A count is only a hint; leave it out if the file can't be read.
+			n, err := repo.ConflictCount(f.Path)
+			if err != nil {
+				msg.files = append(msg.files, f)
+				continue
+			}
+			contentConflict := f.Status == "UU" || f.Status == "AA"
+			if n == 0 && prev[f.Path] > 0 && contentConflict && repo.StageIfResolved(f.Path) == nil {
+				msg.staged = append(msg.staged, f.Path)
+				continue
+			}
+			msg.files = append(msg.files, f)
+			msg.counts[f.Path] = n
+		}
+		return msg
+	}
+}
+
+// lineEditors accept +N to open a file at line N.
+var lineEditors = map[string]bool{
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "micro": true, "kak": true,
+}
+
+// editorCommand opens filePath in the user's editor, at line when it is
+// positive and the editor is known to support that. The editor setting
+// may carry its own arguments, like "code --wait".
+func editorCommand(filePath string, line int) *exec.Cmd {
+	fields := strings.Fields(resolveEditor())
+	args := fields[1:]
+	if line > 0 && lineEditors[filepath.Base(fields[0])] {
+		args = append(args, fmt.Sprintf("+%d", line))
 	}
+	args = append(args, filePath)
+	return exec.Command(fields[0], args...)
 }
 
-// resolveEditor returns the best available editor, preferring $EDITOR then nvim, vim, vi.
+// resolveEditor returns the best available editor, preferring the editor
+// setting, then $EDITOR, then nvim, vim, vi.
 func resolveEditor() string {
-	if e := os.Getenv("EDITOR"); e != "" {
+	if e := strings.TrimSpace(config.Load().Editor); e != "" {
+		return e
+	}
+	if e := strings.TrimSpace(os.Getenv("EDITOR")); e != "" {
 		return e
 	}
 	for _, candidate := range []string{"nvim", "vim", "vi"} {
@@ -274,18 +626,27 @@ func resolveEditor() string {
 	return "vi" // last resort — let the OS error surface naturally
 }
 
-func StartConflictsPicker(repo *git.GitRepo) error {
+// StartConflictsPicker runs the conflict resolver. finish reports whether
+// the merge should be completed: either nothing was conflicted, or every
+// file was resolved and the user chose to finish.
+func StartConflictsPicker(repo *git.GitRepo) (finish bool, err error) {
 	files, err := repo.GetConflictedFiles()
 	if err != nil {
-		return err
+		return false, err
 	}
 	if len(files) == 0 {
 		fmt.Println("No conflicts.")
-		return nil
+		return true, nil
 	}
 
 	m := NewConflictsPickerModel(repo, files)
 	p := tea.NewProgram(m, tea.WithAltScreen())
-	_, err = p.Run()
-	return err
+	model, err := p.Run()
+	if err != nil {
+		return false, err
+	}
+	if finalModel, ok := model.(ConflictsPickerModel); ok {
+		return finalModel.finished, nil
+	}
+	return false, nil
 }
@@ -0,0 +1,47 @@
+package ui
+
+// countPrefix collects a vim-style count typed before a motion, like the 5
+// in 5j. A view keeps one, offers it each key with add, and takes the
+// count for whatever key comes next.
+type countPrefix struct {
+	n int
+}
+
+// add consumes key if it is the next digit of a count. As in vim, a count
+// can't start with 0, but 0 extends one, so "10j" works.
+func (c *countPrefix) add(key string) bool {
+	if len(key) != 1 || key[0] < '0' || key[0] > '9' || key[0] == '0' && c.n == 0 {
+		return false
+	}
+	c.n = min(c.n*10+int(key[0]-'0'), 99999)
+	return true
+}
+
+// take returns the count typed so far, 0 if none, and starts over.
+func (c *countPrefix) take() int {
+	n := c.n
+	c.n = 0
+	return n
+}
+
+// countedStep moves cursor one row (dir is 1 or -1) through n rows, or
+// count rows when a count was typed. A single step wraps around the ends
+// as j/k always have; a counted one stops at them, as in vim.
+func countedStep(cursor, n, dir, count int) int {
+	if n == 0 {
+		return 0
+	}
+	if count == 0 {
+		return (cursor + dir + n) % n
+	}
+	return max(min(cursor+dir*count, n-1), 0)
+}
+
+// countedRow is where G lands: the last of n rows, or row count (counting
+// from 1) when a count was typed.
+func countedRow(n, count int) int {
+	if count == 0 || count > n {
+		return max(n-1, 0)
+	}
+	return count - 1
+}
@@ -1,21 +1,52 @@
 package ui
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
 type DiffViewerModel struct {
 	repo     *git.GitRepo
 	filePath string
-	content  string
 	viewport viewport.Model
 	ready    bool
 	err      error
 
 	staged bool
+	// labelSide names the side being shown, staged or unstaged, in the
+	// title, for views that let tab switch between them.
+	labelSide bool
+	// origPath is where a renamed file came from, so the diff shows the
+	// rename instead of a new file.
+	origPath string
+	// context is the -U<n> value for file diffs; negative uses git's default.
+	context int
+	// fullFile renders the whole file with change markers instead of hunks.
+	fullFile     bool
+	fullFileNote string
+	// rows holds the full-file rendering unstyled; only the rows on screen
+	// are styled, in View, so huge files open without a stall.
+	rows []fullFileRow
+	// lines holds the content for the hunk view. Like rows, only the ones
+	// on screen are handed to the viewport.
+	lines []string
+
+	// load overrides how content is fetched; nil means a file diff of filePath.
+	load         func() (string, error)
+	emptyMessage string
+
+	// streams is shared by copies of the model, so loadDiff can record the
+	// stream it starts and Update can tell a stale stream from the current.
+	streams *diffStreamSlot
+	// loading is set while a streamed diff is still arriving.
+	loading bool
 
 	// Styles
 	titleStyle   lipgloss.Style
@@ -28,7 +59,32 @@ type DiffViewerModel struct {
 
 type diffLoadedMsg struct {
 	content string
+	rows    []fullFileRow // set instead of content for a full-file rendering
 	err     error
+	// note is appended to the title, e.g. to explain a full-file fallback.
+	note string
+	// stream is set when content is one piece of a streamed diff; more
+	// pieces follow while more is true.
+	stream *diffStream
+	more   bool
+}
+
+// diffStream is a file diff being read from git in the background.
+type diffStream struct {
+	ch      chan diffLoadedMsg
+	cancel  context.CancelFunc
+	started bool // the first piece has replaced the previous content
+}
+
+type diffStreamSlot struct {
+	current *diffStream
+}
+
+// fullFileRow is one line of a full-file rendering.
+type fullFileRow struct {
+	lineNo int  // line number in the new file; 0 for removed lines
+	kind   byte // '+', '-' or ' '
+	text   string
 }
 
 func NewDiffViewerModel(repo *git.GitRepo, filePath string) DiffViewerModel {
@@ -39,6 +95,8 @@ func NewDiffViewerModel(repo *git.GitRepo, filePath string) DiffViewerModel {
 		repo:     repo,
 		filePath: filePath,
 		viewport: vp,
+		context:  -1,
+		streams:  &diffStreamSlot{},
 
 		titleStyle:   lipgloss.NewStyle().Foreground(colorPink),
 		addedStyle:   lipgloss.NewStyle().Foreground(colorGreen),
@@ -46,10 +104,18 @@ func NewDiffViewerModel(repo *git.GitRepo, filePath string) DiffViewerModel {
 		contextStyle: lipgloss.NewStyle().Foreground(colorGray),
 		headerStyle:  lipgloss.NewStyle().Foreground(colorCyan),
 		errorStyle:   lipgloss.NewStyle().Foreground(colorRed),
-
 	}
 }
 
+// NewContentViewerModel builds a diff viewer whose content comes from load
+// rather than a file diff. title is shown in place of the file path.
+func NewContentViewerModel(repo *git.GitRepo, title string, load func() (string, error)) DiffViewerModel {
+	m := NewDiffViewerModel(repo, title)
+	m.load = load
+	m.emptyMessage = "No differences."
+	return m
+}
+
 func (m DiffViewerModel) Init() tea.Cmd {
 	return m.loadDiff()
 }
@@ -69,21 +135,27 @@ func (m DiffViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - headerHeight
 		}
 
-		if m.content != "" {
-			m.viewport.SetContent(m.formatDiff(m.content))
+		if m.lines != nil || m.rows != nil {
+			m.setViewportContent()
 		}
 
 	case diffLoadedMsg:
-		m.content = msg.content
+		if msg.stream != nil {
+			return m.appendChunk(msg)
+		}
+		m.loading = false
+		m.lines = strings.Split(m.formatDiff(normalizeNewlines(msg.content)), "\n")
+		m.rows = msg.rows
 		m.err = msg.err
+		m.fullFileNote = msg.note
 		if m.ready && m.err == nil {
-			formatted := m.formatDiff(m.content)
-			m.viewport.SetContent(formatted)
+			m.setViewportContent()
 		}
 
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "esc":
+			m.Close()
 			return m, tea.Quit
 
 		case "j", "down":
@@ -109,6 +181,37 @@ func (m DiffViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "G", "end":
 			m.viewport.GotoBottom()
+
+		case "+", "=":
+			if m.load == nil && m.context != git.WholeFileContext {
+				m.context = max(m.context, defaultDiffContext) + 1
+				return m, m.loadDiff()
+			}
+
+		case "-":
+			if m.load == nil && m.context != 0 {
+				if m.context < 0 || m.context == git.WholeFileContext {
+					m.context = defaultDiffContext
+				}
+				m.context--
+				return m, m.loadDiff()
+			}
+
+		case "F":
+			if m.load == nil {
+				m.fullFile = !m.fullFile
+				return m, m.loadDiff()
+			}
+
+		case "a":
+			if m.load == nil {
+				if m.context == git.WholeFileContext {
+					m.context = -1
+				} else {
+					m.context = git.WholeFileContext
+				}
+				return m, m.loadDiff()
+			}
 		}
 	}
 
@@ -127,22 +230,303 @@ func (m DiffViewerModel) View() string {
 		return "Loading diff..."
 	}
 
-	title := m.titleStyle.Render("Diff Viewer - " + m.filePath)
-	return lipgloss.JoinVertical(lipgloss.Left, title, m.viewport.View())
+	title := m.titleStyle.Render("Diff Viewer - " + m.filePath + m.sideLabel() + m.contextLabel())
+	if m.loading {
+		m.viewport.Height = max(m.viewport.Height-1, 0)
+		return lipgloss.JoinVertical(lipgloss.Left, title, m.viewportView(), m.contextStyle.Render("loading…"))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, title, m.viewportView())
+}
+
+// paneView renders the viewer as the pane beside a list, with its title
+// dimmed: the list is what takes the keys.
+func (m DiffViewerModel) paneView() string {
+	m.titleStyle = DimStyle
+	return m.View()
+}
+
+// setViewportContent sizes the viewport for the content. Its lines, or the
+// full-file rows, are stood in for by empty lines, which keep its scrolling
+// right without measuring or styling anything yet. While a diff streams in,
+// the stand-ins are grown by doubling rather than per piece, so loading
+// stays linear; the last piece sizes them exactly.
+func (m *DiffViewerModel) setViewportContent() {
+	n := len(m.lines)
+	if m.rows != nil {
+		n = len(m.rows)
+	}
+	if m.loading {
+		if m.viewport.TotalLineCount() >= n {
+			return
+		}
+		n *= 2
+	}
+	m.viewport.SetContent(strings.Repeat("\n", max(n-1, 0)))
+}
+
+// viewportView renders the viewport with just the lines, or styled
+// full-file rows, that are on screen.
+func (m DiffViewerModel) viewportView() string {
+	var visible []string
+	if m.rows != nil {
+		top := min(m.viewport.YOffset, len(m.rows))
+		bottom := min(top+m.viewport.Height, len(m.rows))
+		visible = make([]string, 0, bottom-top)
+		for _, row := range m.rows[top:bottom] {
+			visible = append(visible, m.renderRow(row))
+		}
+	} else {
+		top := min(m.viewport.YOffset, len(m.lines))
+		visible = m.lines[top:min(top+m.viewport.Height, len(m.lines))]
+	}
+	window := m.viewport
+	window.SetContent(strings.Join(visible, "\n"))
+	window.SetYOffset(0)
+	return window.View()
 }
 
 func (m DiffViewerModel) loadDiff() tea.Cmd {
+	m.Close()
+	if m.load != nil {
+		load := m.load
+		return func() tea.Msg {
+			content, err := load()
+			return diffLoadedMsg{content: content, err: err}
+		}
+	}
+	if m.fullFile {
+		return m.loadFullFile()
+	}
+	return m.streamDiff()
+}
+
+// streamDiff reads the file diff in the background and delivers it in
+// pieces, so the top of a huge diff shows while the rest is still loading.
+func (m DiffViewerModel) streamDiff() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &diffStream{ch: make(chan diffLoadedMsg, 16), cancel: cancel}
+	m.streams.current = stream
+
+	repo, filePath := m.repo, m.filePath
+	opts := git.DiffOptions{Staged: m.staged, Context: m.context, OrigPath: m.origPath}
+	go func() {
+		defer close(stream.ch)
+		send := func(msg diffLoadedMsg) {
+			select {
+			case stream.ch <- msg:
+			case <-ctx.Done():
+			}
+		}
+		err := repo.StreamDiff(ctx, filePath, opts, func(chunk string) {
+			send(diffLoadedMsg{content: chunk, stream: stream, more: true})
+		})
+		send(diffLoadedMsg{stream: stream, err: err})
+	}()
+	return waitForDiffChunk(stream.ch)
+}
+
+// waitForDiffChunk delivers the next piece of a streamed diff, joined with
+// any that arrived while the previous one was being drawn.
+func waitForDiffChunk(ch <-chan diffLoadedMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		for msg.more {
+			select {
+			case next, ok := <-ch:
+				if !ok {
+					return msg
+				}
+				next.content = msg.content + next.content
+				msg = next
+			default:
+				return msg
+			}
+		}
+		return msg
+	}
+}
+
+// appendChunk adds a piece of a streamed diff to the content. Pieces from
+// a stream the viewer has moved on from are dropped and the stream stopped.
+func (m DiffViewerModel) appendChunk(msg diffLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.streams == nil || msg.stream != m.streams.current {
+		msg.stream.cancel()
+		return m, nil
+	}
+	if !msg.stream.started {
+		msg.stream.started = true
+		m.lines, m.rows, m.fullFileNote = []string{""}, nil, ""
+		m.viewport.SetContent("")
+	}
+	m.appendLines(msg.content)
+	if !msg.more && len(m.lines) == 1 && m.lines[0] == "" {
+		m.lines = []string{m.formatDiff("")}
+	}
+	m.err = msg.err
+	m.loading = msg.more
+	if m.ready && m.err == nil {
+		m.setViewportContent()
+	}
+	if msg.more {
+		return m, waitForDiffChunk(msg.stream.ch)
+	}
+	m.Close()
+	return m, nil
+}
+
+// appendLines adds a piece of streamed content to lines. The last line is
+// unfinished until a newline ends it, so the piece continues it; only the
+// new lines are split, keeping a huge diff linear to load.
+func (m *DiffViewerModel) appendLines(chunk string) {
+	parts := strings.Split(chunk, "\n")
+	last := len(m.lines) - 1
+	m.lines[last] += parts[0]
+	m.lines = append(m.lines, parts[1:]...)
+	// Drop the carriage returns of CRLF endings, including one split
+	// between two pieces.
+	for i := last; i < len(m.lines)-1; i++ {
+		m.lines[i] = strings.TrimSuffix(m.lines[i], "\r")
+	}
+}
+
+// Close stops a diff that is still streaming in. Views embedding the
+// viewer call it when they leave or replace it.
+func (m DiffViewerModel) Close() {
+	if m.streams == nil || m.streams.current == nil {
+		return
+	}
+	m.streams.current.cancel()
+	m.streams.current = nil
+}
+
+// loadFullFile fetches the file with every line as context and renders it
+// with change markers. Files longer than the configured limit fall back to
+// the unified diff.
+func (m DiffViewerModel) loadFullFile() tea.Cmd {
+	maxLines := config.Load().FullFileMaxLines
 	return func() tea.Msg {
-		content, err := m.repo.FileDiff(m.filePath, m.staged)
-		return diffLoadedMsg{
-			content: content,
-			err:     err,
+		content, err := m.repo.FileDiffWithOptions(m.filePath, git.DiffOptions{
+			Staged:   m.staged,
+			Context:  git.WholeFileContext,
+			Plain:    true,
+			OrigPath: m.origPath,
+		})
+		if err != nil {
+			return diffLoadedMsg{err: err}
+		}
+		lines := strings.Split(strings.TrimRight(normalizeNewlines(content), "\n"), "\n")
+		if maxLines > 0 && len(lines) > maxLines {
+			content, err := m.repo.FileDiffWithOptions(m.filePath, git.DiffOptions{Staged: m.staged, Context: m.context, OrigPath: m.origPath})
+			return diffLoadedMsg{
+				content: content,
+				err:     err,
+				note:    fmt.Sprintf(" (over %d lines, showing diff)", maxLines),
+			}
+		}
+		rows, ok := fullFileRows(lines)
+		if !ok {
+			return diffLoadedMsg{content: content}
 		}
+		return diffLoadedMsg{rows: rows, note: " (full file)"}
+	}
+}
+
+// fullFileRows turns a whole-file unified diff into the file's lines with
+// new-file line numbers and +/- markers. It reports false when the input
+// has no hunk to render (e.g. binary or deleted files).
+func fullFileRows(lines []string) ([]fullFileRow, bool) {
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "@@") {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+
+	rows := make([]fullFileRow, 0, len(lines)-start)
+	lineNo := 1
+	for _, line := range lines[start:] {
+		if line == "" {
+			line = " "
+		}
+		switch line[0] {
+		case '+':
+			rows = append(rows, fullFileRow{lineNo: lineNo, kind: '+', text: line[1:]})
+			lineNo++
+		case '-':
+			rows = append(rows, fullFileRow{kind: '-', text: line[1:]})
+		case '\\':
+			// "\ No newline at end of file"
+		default:
+			rows = append(rows, fullFileRow{lineNo: lineNo, kind: ' ', text: line[1:]})
+			lineNo++
+		}
+	}
+	return rows, true
+}
+
+// renderRow styles one full-file row behind a line number gutter.
+func (m DiffViewerModel) renderRow(row fullFileRow) string {
+	switch row.kind {
+	case '+':
+		return fmt.Sprintf("%s %s", m.contextStyle.Render(fmt.Sprintf("%5d", row.lineNo)), m.addedStyle.Render("+ "+row.text))
+	case '-':
+		return fmt.Sprintf("%s %s", strings.Repeat(" ", 5), m.removedStyle.Render("- "+row.text))
+	default:
+		return fmt.Sprintf("%s   %s", m.contextStyle.Render(fmt.Sprintf("%5d", row.lineNo)), row.text)
 	}
 }
 
+// defaultDiffContext mirrors git's built-in number of context lines.
+const defaultDiffContext = 3
+
+// sideLabel says which side of a partly staged file is shown, when the
+// view can switch.
+func (m DiffViewerModel) sideLabel() string {
+	switch {
+	case !m.labelSide:
+		return ""
+	case m.staged:
+		return " (staged; tab: unstaged)"
+	default:
+		return " (unstaged; tab: staged)"
+	}
+}
+
+// contextLabel describes a non-default context setting for the title.
+func (m DiffViewerModel) contextLabel() string {
+	switch {
+	case m.load != nil:
+		return ""
+	case m.fullFile:
+		return m.fullFileNote
+	case m.context < 0:
+		return ""
+	case m.context == git.WholeFileContext:
+		return " (whole file)"
+	default:
+		return fmt.Sprintf(" (-U%d)", m.context)
+	}
+}
+
+// normalizeNewlines turns CRLF line endings into LF. A stray carriage
+// return sends the terminal cursor back to the start of the line, so
+// content from CRLF files would otherwise overwrite itself on screen.
+func normalizeNewlines(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
 func (m DiffViewerModel) formatDiff(content string) string {
 	if content == "" {
+		if m.emptyMessage != "" {
+			return m.contextStyle.Render(m.emptyMessage)
+		}
 		return m.contextStyle.Render("No differences found for this file.")
 	}
 
@@ -150,8 +534,38 @@ func (m DiffViewerModel) formatDiff(content string) string {
 	return content
 }
 
-func ShowDiff(repo *git.GitRepo, filePath string) error {
+func ShowDiff(repo *git.GitRepo, filePath string, staged bool) error {
 	m := NewDiffViewerModel(repo, filePath)
+	m.staged = staged
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
+
+// upstreamDiffTitle labels an incoming/outgoing upstream diff.
+func upstreamDiffTitle(direction string) string {
+	if direction == "outgoing" {
+		return "Outgoing changes (HEAD vs upstream)"
+	}
+	return "Incoming changes (upstream vs HEAD)"
+}
+
+// ShowUpstreamDiff opens the diff between the current branch and its
+// upstream in the viewer. direction is "incoming" or "outgoing".
+func ShowUpstreamDiff(repo *git.GitRepo, direction string) error {
+	m := NewContentViewerModel(repo, upstreamDiffTitle(direction), func() (string, error) {
+		return repo.DiffUpstream(direction)
+	})
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
+
+// ShowCommitDiff opens `git show` for ref in the viewer.
+func ShowCommitDiff(repo *git.GitRepo, ref string) error {
+	m := NewContentViewerModel(repo, "Commit "+ref, func() (string, error) {
+		return repo.ShowCommit(ref)
+	})
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bigDiff returns a colored unified diff of n changed lines, as git prints
+// it for a file diff.
+func bigDiff(n int) string {
+	var sb strings.Builder
+	sb.WriteString("\x1b[1mdiff --git a/big.txt b/big.txt\x1b[m\n")
+	fmt.Fprintf(&sb, "\x1b[36m@@ -1,%d +1,%d @@\x1b[m\n", n/2, n/2)
+	for i := 0; i < n; i += 2 {
+		fmt.Fprintf(&sb, "\x1b[31m-old line %d with some text to make it look like code\x1b[m\n", i)
+		fmt.Fprintf(&sb, "\x1b[32m+new line %d with some text to make it look like code\x1b[m\n", i)
+	}
+	return sb.String()
+}
+
+// openDiff returns a diff viewer of the given size showing content.
+func openDiff(content string, width, height int) DiffViewerModel {
+	var model tea.Model = NewDiffViewerModel(nil, "big.txt")
+	model, _ = model.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	model, _ = model.Update(diffLoadedMsg{content: content})
+	return model.(DiffViewerModel)
+}
+
+func BenchmarkDiffViewerOpen100kLines(b *testing.B) {
+	content := bigDiff(100_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := openDiff(content, 120, 40)
+		_ = m.View()
+	}
+}
+
+func BenchmarkDiffViewerScroll100kLines(b *testing.B) {
+	m := openDiff(bigDiff(100_000), 120, 40)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var model tea.Model
+		model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+		m = model.(DiffViewerModel)
+		_ = m.View()
+	}
+}
+
+func TestDiffViewerShowsWindowOfLargeDiff(t *testing.T) {
+	m := openDiff(bigDiff(100_000), 120, 10)
+	if view := m.View(); !strings.Contains(view, "old line 0 ") || strings.Contains(view, "line 20 ") {
+		t.Fatalf("first screen shows the wrong lines:\n%s", view)
+	}
+
+	var model tea.Model = m
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}})
+	view := model.View()
+	if !strings.Contains(view, "new line 99998 ") {
+		t.Errorf("G does not reach the last line:\n%s", view)
+	}
+	if lines := strings.Count(view, "\n") + 1; lines > 10 {
+		t.Errorf("view is %d lines tall, want at most 10", lines)
+	}
+}
+
+// streamDiff feeds content to a diff viewer of the given size the way a
+// streamed diff arrives, in pieces of chunkSize bytes.
+func streamDiff(content string, chunkSize, width, height int) DiffViewerModel {
+	var model tea.Model = NewDiffViewerModel(nil, "big.txt")
+	model, _ = model.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	stream := &diffStream{cancel: func() {}}
+	model.(DiffViewerModel).streams.current = stream
+	for more := true; more; {
+		n := min(chunkSize, len(content))
+		more = n < len(content)
+		model, _ = model.Update(diffLoadedMsg{content: content[:n], stream: stream, more: more})
+		content = content[n:]
+	}
+	return model.(DiffViewerModel)
+}
+
+func BenchmarkDiffViewerStream100kLines(b *testing.B) {
+	content := bigDiff(100_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := streamDiff(content, 32*1024, 120, 40)
+		_ = m.View()
+	}
+}
+
+func TestDiffViewerStreamMatchesWholeDiff(t *testing.T) {
+	content := strings.ReplaceAll(bigDiff(1_000), "\n", "\r\n")
+	whole := openDiff(content, 120, 10)
+	// Pieces of 7 bytes split lines, escape codes and CRLF pairs.
+	streamed := streamDiff(content, 7, 120, 10)
+	if streamed.loading {
+		t.Fatal("viewer still loading after the last piece")
+	}
+	if len(streamed.lines) != len(whole.lines) {
+		t.Fatalf("streamed %d lines, want %d", len(streamed.lines), len(whole.lines))
+	}
+	for i := range whole.lines {
+		if streamed.lines[i] != whole.lines[i] {
+			t.Fatalf("line %d = %q, want %q", i, streamed.lines[i], whole.lines[i])
+		}
+	}
+	if got, want := streamed.viewport.TotalLineCount(), whole.viewport.TotalLineCount(); got != want {
+		t.Errorf("viewport has %d lines, want %d", got, want)
+	}
+}
+
+func TestDiffViewerEmptyStream(t *testing.T) {
+	m := streamDiff("", 1, 120, 10)
+	if len(m.lines) != 1 || !strings.Contains(m.lines[0], "No differences") {
+		t.Errorf("lines = %q, want the empty message", m.lines)
+	}
+}
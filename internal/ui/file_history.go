@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+type FileHistoryModel struct {
+	repo         *git.GitRepo
+	path         string
+	mode         Mode
+	commits      []git.CommitInfo
+	currentIndex int
+	scrollOffset int
+	visibleLines int
+	width        int
+	height       int
+
+	diffViewer DiffViewerModel
+
+	titleStyle      lipgloss.Style
+	selectedStyle   lipgloss.Style
+	unselectedStyle lipgloss.Style
+	helpStyle       lipgloss.Style
+	dimStyle        lipgloss.Style
+}
+
+func NewFileHistoryModel(repo *git.GitRepo, path string, commits []git.CommitInfo) FileHistoryModel {
+	return FileHistoryModel{
+		repo:    repo,
+		path:    path,
+		mode:    NormalMode,
+		commits: commits,
+
+		titleStyle:      TitlePinkStyle,
+		selectedStyle:   SelectedPeachStyle,
+		unselectedStyle: UnselectedStyle,
+		helpStyle:       HelpStyle,
+		dimStyle:        DimStyle,
+	}
+}
+
+func (m FileHistoryModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m FileHistoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.mode == DetailMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "q", "esc":
+				m.mode = NormalMode
+				return m, nil
+			}
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.visibleLines = msg.Height - 6
+		}
+		updatedViewer, viewCmd := m.diffViewer.Update(msg)
+		if dv, ok := updatedViewer.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+		return m, viewCmd
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.visibleLines = msg.Height - 6
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc":
+			return m, tea.Quit
+
+		case "j", "down":
+			if len(m.commits) > 0 {
+				m.currentIndex = (m.currentIndex + 1) % len(m.commits)
+				m.adjustScrolling()
+			}
+
+		case "k", "up":
+			if len(m.commits) > 0 {
+				m.currentIndex = (m.currentIndex - 1 + len(m.commits)) % len(m.commits)
+				m.adjustScrolling()
+			}
+
+		case "g", "home":
+			m.currentIndex = 0
+			m.scrollOffset = 0
+
+		case "G", "end":
+			if len(m.commits) > 0 {
+				m.currentIndex = len(m.commits) - 1
+				m.adjustScrolling()
+			}
+
+		case "enter":
+			if len(m.commits) > 0 {
+				return m, m.openCommit(m.commits[m.currentIndex])
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// openCommit shows the selected commit's diff for the file, using the path
+// the file had at that commit so renames still show their changes.
+func (m *FileHistoryModel) openCommit(c git.CommitInfo) tea.Cmd {
+	path := m.path
+	if len(c.Files) > 0 {
+		path = c.Files[0]
+	}
+	repo := m.repo
+	m.diffViewer = NewContentViewerModel(repo, fmt.Sprintf("%s — %s", c.ShortHash, path), func() (string, error) {
+		return repo.ShowCommitFile(c.Hash, path)
+	})
+	m.mode = DetailMode
+
+	cmds := []tea.Cmd{m.diffViewer.Init()}
+	if m.width > 0 && m.height > 0 {
+		updatedViewer, sizeCmd := m.diffViewer.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		if dv, ok := updatedViewer.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+		cmds = append(cmds, sizeCmd)
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m FileHistoryModel) View() string {
+	if m.mode == DetailMode {
+		return m.diffViewer.View()
+	}
+
+	var sections []string
+	sections = append(sections, m.titleStyle.Render(fmt.Sprintf("History of %s (%d commits)", m.path, len(m.commits))))
+	sections = append(sections, "")
+
+	startIdx := m.scrollOffset
+	endIdx := min(startIdx+m.visibleLines, len(m.commits))
+
+	for i := startIdx; i < endIdx; i++ {
+		c := m.commits[i]
+		prefix := "  "
+		style := m.unselectedStyle
+		if i == m.currentIndex {
+			prefix = "> "
+			style = m.selectedStyle
+		}
+		meta := m.dimStyle.Render(fmt.Sprintf("  %s, %s", c.Author, c.Date))
+		sections = append(sections, fmt.Sprintf("%s%s %s%s", prefix, m.helpStyle.Render(c.ShortHash), style.Render(c.Subject), meta))
+	}
+
+	if len(m.commits) > m.visibleLines {
+		sections = append(sections, "")
+		sections = append(sections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(m.commits))))
+	}
+
+	sections = append(sections, "")
+	sections = append(sections, m.helpStyle.Render("j/k: navigate  enter: view change  g/G: top/bottom  q: back"))
+
+	return strings.Join(sections, "\n")
+}
+
+func (m *FileHistoryModel) adjustScrolling() {
+	if m.visibleLines <= 0 {
+		return
+	}
+	if m.currentIndex >= m.scrollOffset+m.visibleLines {
+		m.scrollOffset = m.currentIndex - m.visibleLines + 1
+	}
+	if m.currentIndex < m.scrollOffset {
+		m.scrollOffset = m.currentIndex
+	}
+	maxOffset := len(m.commits) - m.visibleLines
+	if maxOffset < 0 {
+		maxOffset = 0
+	}
+	if m.scrollOffset > maxOffset {
+		m.scrollOffset = maxOffset
+	}
+	if m.scrollOffset < 0 {
+		m.scrollOffset = 0
+	}
+}
+
+// StartFileHistory lists the commits that touched path.
+func StartFileHistory(repo *git.GitRepo, path string, limit int) error {
+	commits, err := repo.FileHistory(path, limit)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		fmt.Printf("No history for %s.\n", path)
+		return nil
+	}
+	m := NewFileHistoryModel(repo, path, commits)
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err = p.Run()
+	return err
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+// fileTreeRow is one line of a file list grouped by directory: either a
+// directory node or a file.
+type fileTreeRow struct {
+	dir   string // full directory path for directory rows, "" for files
+	index int    // index into the file list for file rows, -1 for directories
+	depth int
+	name  string // last path segment, with a trailing "/" for directories
+}
+
+func (r fileTreeRow) isDir() bool {
+	return r.index < 0
+}
+
+// flatFileRows lists paths as-is, one row per file.
+func flatFileRows(paths []string) []fileTreeRow {
+	rows := make([]fileTreeRow, len(paths))
+	for i, p := range paths {
+		rows[i] = fileTreeRow{index: i, name: p}
+	}
+	return rows
+}
+
+// buildFileTree nests paths under their directories. Children of
+// directories in collapsed are left out; the directory row stays.
+func buildFileTree(paths []string, collapsed map[string]bool) []fileTreeRow {
+	order := make([]int, len(paths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return paths[order[a]] < paths[order[b]]
+	})
+
+	var rows []fileTreeRow
+	emitted := make(map[string]bool)
+	for _, i := range order {
+		segments := strings.Split(paths[i], "/")
+		hidden := false
+		for depth := 0; depth < len(segments)-1; depth++ {
+			dir := strings.Join(segments[:depth+1], "/")
+			if !emitted[dir] {
+				emitted[dir] = true
+				rows = append(rows, fileTreeRow{dir: dir, index: -1, depth: depth, name: segments[depth] + "/"})
+			}
+			if collapsed[dir] {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			rows = append(rows, fileTreeRow{index: i, depth: len(segments) - 1, name: segments[len(segments)-1]})
+		}
+	}
+	return rows
+}
+
+// filesUnder returns the paths inside dir, at any depth.
+func filesUnder(paths []string, dir string) []string {
+	var under []string
+	for _, p := range paths {
+		if strings.HasPrefix(p, dir+"/") {
+			under = append(under, p)
+		}
+	}
+	return under
+}
+
+// renameLabel shows a renamed file as "old → new".
+func renameLabel(f git.FileStatus, name string) string {
+	if f.OrigPath == "" {
+		return name
+	}
+	return f.OrigPath + " → " + name
+}
+
+// treeRowLabel renders a row's indentation, expander and name.
+func treeRowLabel(row fileTreeRow, collapsed map[string]bool) string {
+	indent := strings.Repeat("  ", row.depth)
+	if !row.isDir() {
+		return indent + row.name
+	}
+	if collapsed[row.dir] {
+		return indent + "▸ " + row.name
+	}
+	return indent + "▾ " + row.name
+}
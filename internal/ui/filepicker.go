@@ -1,14 +1,18 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
@@ -25,10 +29,13 @@ type FilePickerModel struct {
 	unstagedSelections map[string]bool
 
 	operationInProgress bool
+	progress            *git.Progress
 	lastOperationStatus string
 	showStatusMessage   bool
+	summary             FileOperationSummary
 
 	currentIndex    int
+	count           countPrefix
 	mode            Mode
 	searchInput     textinput.Model
 	searchQuery     string
@@ -52,6 +59,36 @@ type FilePickerModel struct {
 	diffViewer DiffViewerModel
 	splitPane  bool
 
+	// Tree view groups files by directory; treeCursor is the row under the
+	// cursor in NormalMode, and currentIndex follows it onto file rows.
+	treeView   bool
+	collapsed  map[string]bool
+	treeCursor int
+
+	// Review queue: the selected files stepped through in the full-screen
+	// diff with n/p. Non-nil while reviewing.
+	reviewQueue []string
+	reviewIndex int
+
+	// Files waiting for y/n before being reverted to HEAD with 'R'.
+	confirmRevert []string
+
+	// Commits waiting for y/n before being pushed after 'P'.
+	confirmPush []git.CommitInfo
+
+	// Files waiting for y/n before being deleted with 'D'.
+	confirmDelete []string
+
+	// Type-to-jump opened with 'f': the text typed so far and the cursor
+	// it started from, to return to on esc.
+	jumping    bool
+	jumpQuery  string
+	jumpOrigin int
+
+	// Rename prompt opened with 'm': the file being renamed and its new path.
+	renameFrom  string
+	renameInput textinput.Model
+
 	// Commit modal (entered from NormalMode via 'C' / 'P')
 	commitInput     CommitInputModel
 	pushAfterCommit bool
@@ -96,6 +133,7 @@ func NewFilePicker(repo *git.GitRepo, stagedFileStatuses []git.FileStatus, unsta
 		selectedFiles:        make(map[string]bool),
 		stagedSelections:     make(map[string]bool),
 		unstagedSelections:   make(map[string]bool),
+		collapsed:            make(map[string]bool),
 		searchInput:          si,
 		showStatusChars:      true,
 		staged:               startInStaged,
@@ -114,6 +152,7 @@ func NewFilePicker(repo *git.GitRepo, stagedFileStatuses []git.FileStatus, unsta
 	if len(files) > 0 {
 		m.diffViewer = NewDiffViewerModel(repo, files[0])
 		m.diffViewer.staged = startInStaged
+		m.diffViewer.origPath = activeFileStatuses[0].OrigPath
 	}
 
 	return m
@@ -136,12 +175,9 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.visibleLines = msg.Height - 8
 
-		var diffMsg tea.WindowSizeMsg
+		diffMsg := m.paneDiffSize()
 		if m.mode == DiffMode {
-			diffMsg = tea.WindowSizeMsg{Width: msg.Width, Height: msg.Height}
-		} else {
-			leftWidth := msg.Width / 2
-			diffMsg = tea.WindowSizeMsg{Width: msg.Width - leftWidth - 1, Height: msg.Height}
+			diffMsg = m.fullDiffSize()
 		}
 		updatedDiff, diffCmd := m.diffViewer.Update(diffMsg)
 		if dv, ok := updatedDiff.(DiffViewerModel); ok {
@@ -154,20 +190,40 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if dv, ok := updatedDiff.(DiffViewerModel); ok {
 			m.diffViewer = dv
 		}
+		if errors.Is(msg.err, git.ErrFileGone) {
+			return m, tea.Batch(diffCmd, m.fileGone())
+		}
 		return m, diffCmd
 
 	case StatusBarMsg:
 		m.statusBar = msg.Bar
 		return m, nil
 
+	case networkProgressMsg:
+		m.progress = &msg.progress
+		return m, waitForProgress(msg.ch)
+
 	case GitOperationCompleteMsg:
 		m.operationInProgress = false
+		m.progress = nil
+		if errors.Is(msg.error, git.ErrFileGone) {
+			return m, m.fileGone()
+		}
 		if msg.success {
+			m.summary.record(msg.operation, m.staged, len(msg.filesAffected))
 			if msg.operation == "push" {
 				m.lastOperationStatus = "✓ Committed and pushed"
 			} else {
 				action := "staged"
-				if msg.operation == "restore" {
+				if msg.operation == "move" {
+					action = "renamed"
+				} else if msg.operation == "delete" {
+					action = "deleted"
+				} else if msg.operation == "intent" {
+					action = "intent-to-add"
+				} else if msg.operation == "revert" {
+					action = "reverted to HEAD"
+				} else if msg.operation == "restore" {
 					if m.staged {
 						action = "restored from staging"
 					} else {
@@ -177,9 +233,11 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.lastOperationStatus = fmt.Sprintf("✓ %s %d file(s)", action, len(msg.filesAffected))
 			}
 			m.showStatusMessage = true
-			return m, tea.Batch(m.refreshRepositoryStatus(), m.clearStatusAfterDelay(), FetchStatusBar(m.repo))
+			return m, tea.Batch(m.refreshRepositoryStatus(), m.clearStatusAfterDelay())
 		}
-		if msg.operation == "push" {
+		if msg.operation == "push" && errors.Is(msg.error, git.ErrNotFastForward) {
+			m.lastOperationStatus = "✗ Push rejected: the remote has new commits, pull first"
+		} else if msg.operation == "push" {
 			m.lastOperationStatus = fmt.Sprintf("✗ Push failed: %v", msg.error)
 		} else {
 			m.lastOperationStatus = fmt.Sprintf("✗ Error: %v", msg.error)
@@ -195,6 +253,7 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.stagedFileStatuses = msg.stagedFiles
 		m.unstagedFileStatuses = msg.unstagedFiles
+		m.statusBar = msg.bar
 		if m.staged {
 			m.fileStatuses = m.stagedFileStatuses
 			m.selectedFiles = m.stagedSelections
@@ -213,7 +272,15 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.currentIndex = 0
 			}
 		}
+		if m.treeView {
+			m.treeCursor = max(min(m.treeCursor, len(m.treeRows())-1), 0)
+			m.syncTreeCursor()
+		}
 		m.adjustScrolling()
+		if m.reviewQueue != nil {
+			// The review keeps its own diff on screen.
+			return m, nil
+		}
 		return m, m.loadCurrentDiff()
 
 	case ClearStatusMsg:
@@ -234,16 +301,30 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pushAfterCommit = false
 			return m, m.clearStatusAfterDelay()
 		}
+		m.summary.Committed = true
 		if m.pushAfterCommit {
 			m.pushAfterCommit = false
-			m.operationInProgress = true
-			m.lastOperationStatus = "✓ Committed — pushing..."
+			m.lastOperationStatus = "✓ Committed"
 			m.showStatusMessage = true
-			return m, tea.Batch(m.performPush(), m.refreshRepositoryStatus(), FetchStatusBar(m.repo))
+			return m, tea.Batch(m.loadOutgoing(), m.refreshRepositoryStatus())
 		}
 		m.lastOperationStatus = "✓ Committed"
 		m.showStatusMessage = true
-		return m, tea.Batch(m.refreshRepositoryStatus(), FetchStatusBar(m.repo), m.clearStatusAfterDelay())
+		return m, tea.Batch(m.refreshRepositoryStatus(), m.clearStatusAfterDelay())
+
+	case outgoingLoadedMsg:
+		if msg.err != nil {
+			m.lastOperationStatus = fmt.Sprintf("✗ Committed, but listing commits to push failed: %v", msg.err)
+			m.showStatusMessage = true
+			return m, nil
+		}
+		if len(msg.commits) == 0 {
+			m.operationInProgress = true
+			m.lastOperationStatus = "✓ Committed — pushing..."
+			return m, m.performPush()
+		}
+		m.confirmPush = msg.commits
+		return m, nil
 
 	case tea.KeyMsg:
 		// In CommitMode, route everything to the embedded commit input
@@ -261,6 +342,79 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, ciCmd
 		}
 
+		// The rename prompt takes every key until it is confirmed or canceled.
+		if m.renameFrom != "" {
+			switch msg.String() {
+			case "esc":
+				m.renameFrom = ""
+				return m, nil
+			case "enter":
+				src, dst := m.renameFrom, strings.TrimSpace(m.renameInput.Value())
+				m.renameFrom = ""
+				if dst == "" || dst == src {
+					return m, nil
+				}
+				m.operationInProgress = true
+				return m, m.performMove(src, dst)
+			}
+			var cmd tea.Cmd
+			m.renameInput, cmd = m.renameInput.Update(msg)
+			return m, cmd
+		}
+
+		// A pending push takes the next key as its answer.
+		if m.confirmPush != nil {
+			m.confirmPush = nil
+			if msg.String() != "y" {
+				m.lastOperationStatus = "Push canceled; the commit stays local"
+				m.showStatusMessage = true
+				return m, m.clearStatusAfterDelay()
+			}
+			m.operationInProgress = true
+			m.lastOperationStatus = "Pushing..."
+			m.showStatusMessage = true
+			return m, m.performPush()
+		}
+
+		// A pending delete takes the next key as its answer.
+		if m.confirmDelete != nil {
+			files := m.confirmDelete
+			m.confirmDelete = nil
+			if msg.String() != "y" {
+				m.lastOperationStatus = "Delete canceled"
+				m.showStatusMessage = true
+				return m, m.clearStatusAfterDelay()
+			}
+			m.operationInProgress = true
+			m.selectedFiles = make(map[string]bool)
+			return m, m.performDelete(files)
+		}
+
+		// A pending revert takes the next key as its answer.
+		if m.confirmRevert != nil {
+			files := m.confirmRevert
+			m.confirmRevert = nil
+			if msg.String() != "y" {
+				m.lastOperationStatus = "Revert canceled"
+				m.showStatusMessage = true
+				return m, m.clearStatusAfterDelay()
+			}
+			m.operationInProgress = true
+			m.selectedFiles = make(map[string]bool)
+			return m, m.performRevert(files)
+		}
+
+		// Type-to-jump takes every key until enter or esc.
+		if m.jumping {
+			return m.updateJump(msg)
+		}
+
+		// A count typed in NormalMode, as in 5j, goes to the next motion.
+		if m.mode == NormalMode && m.count.add(msg.String()) {
+			return m, nil
+		}
+		count := m.count.take()
+
 		// Split-pane diff scroll keys (active in Normal and locked Search mode)
 		if m.mode != DiffMode && m.mode != SearchMode || (m.mode == SearchMode && m.searchLocked) {
 			switch msg.String() {
@@ -283,17 +437,7 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			switch m.mode {
 			case DiffMode:
-				m.mode = NormalMode
-				// Resize diff viewer back to right-pane width
-				if m.width > 0 {
-					leftWidth := m.width / 2
-					rightWidth := m.width - leftWidth - 1
-					sizeMsg := tea.WindowSizeMsg{Width: rightWidth, Height: m.height}
-					updatedDiff, _ := m.diffViewer.Update(sizeMsg)
-					if dv, ok := updatedDiff.(DiffViewerModel); ok {
-						m.diffViewer = dv
-					}
-				}
+				return m, m.leaveDiffMode()
 			case SearchMode:
 				m.mode = NormalMode
 				m.searchInput.Blur()
@@ -311,23 +455,29 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "q":
 			switch m.mode {
 			case DiffMode:
-				m.mode = NormalMode
-				if m.width > 0 {
-					leftWidth := m.width / 2
-					rightWidth := m.width - leftWidth - 1
-					sizeMsg := tea.WindowSizeMsg{Width: rightWidth, Height: m.height}
-					updatedDiff, _ := m.diffViewer.Update(sizeMsg)
-					if dv, ok := updatedDiff.(DiffViewerModel); ok {
-						m.diffViewer = dv
-					}
-				}
-				return m, nil
+				return m, m.leaveDiffMode()
 			case NormalMode:
 				m.quitting = true
 				return m, tea.Quit
 			}
 			// SearchMode: fall through to text input
 
+		case "h", "left":
+			// In the compact layout the diff stands in for the right pane.
+			if m.mode == DiffMode && m.reviewQueue == nil && m.compactLayout() {
+				return m, m.leaveDiffMode()
+			}
+
+		case "l", "right":
+			if m.mode == NormalMode && m.splitPane && m.compactLayout() && len(m.files) > 0 && !m.onDirRow() {
+				m.mode = DiffMode
+				updatedDiff, _ := m.diffViewer.Update(m.fullDiffSize())
+				if dv, ok := updatedDiff.(DiffViewerModel); ok {
+					m.diffViewer = dv
+				}
+				return m, nil
+			}
+
 		case "enter":
 			switch m.mode {
 			case SearchMode:
@@ -340,6 +490,10 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			case NormalMode:
+				if row, ok := m.currentTreeRow(); ok && row.isDir() {
+					m.toggleDirSelection(row.dir)
+					return m, nil
+				}
 				if len(m.files) > 0 {
 					file := m.files[m.currentIndex]
 					m.selectedFiles[file] = !m.selectedFiles[file]
@@ -356,8 +510,12 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				// Unlocked: fall through to text input
 			case NormalMode:
+				if m.treeView {
+					m.treeCursor = countedStep(m.treeCursor, len(m.treeRows()), 1, count)
+					return m, m.moveTreeCursor(0)
+				}
 				if len(m.files) > 0 {
-					m.currentIndex = (m.currentIndex + 1) % len(m.files)
+					m.currentIndex = countedStep(m.currentIndex, len(m.files), 1, count)
 					m.adjustScrolling()
 					return m, m.loadCurrentDiff()
 				}
@@ -373,8 +531,12 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				// Unlocked: fall through to text input
 			case NormalMode:
+				if m.treeView {
+					m.treeCursor = countedStep(m.treeCursor, len(m.treeRows()), -1, count)
+					return m, m.moveTreeCursor(0)
+				}
 				if len(m.files) > 0 {
-					m.currentIndex = (m.currentIndex - 1 + len(m.files)) % len(m.files)
+					m.currentIndex = countedStep(m.currentIndex, len(m.files), -1, count)
 					m.adjustScrolling()
 					return m, m.loadCurrentDiff()
 				}
@@ -382,6 +544,25 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		if m.mode == DiffMode && m.reviewQueue != nil {
+			switch msg.String() {
+			case "n":
+				if m.reviewIndex < len(m.reviewQueue)-1 {
+					m.reviewIndex++
+					return m, m.loadReviewDiff()
+				}
+				return m, nil
+			case "p":
+				if m.reviewIndex > 0 {
+					m.reviewIndex--
+					return m, m.loadReviewDiff()
+				}
+				return m, nil
+			case "s":
+				return m, m.stageReviewed()
+			}
+		}
+
 		// DiffMode: forward remaining keys to the diff viewer
 		if m.mode == DiffMode {
 			updatedDiff, diffCmd := m.diffViewer.Update(msg)
@@ -438,6 +619,66 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedFiles = make(map[string]bool)
 				return m, m.performGitOperation(selectedFiles, true)
 
+			case "R":
+				if m.operationInProgress || len(m.getSelectedFiles()) == 0 {
+					return m, nil
+				}
+				m.confirmRevert = m.getSelectedFiles()
+				return m, nil
+
+			case "N":
+				if m.operationInProgress || m.staged || len(m.files) == 0 {
+					return m, nil
+				}
+				targets := make(map[string]bool)
+				for _, file := range m.getSelectedFiles() {
+					targets[file] = true
+				}
+				if len(targets) == 0 && !m.onDirRow() {
+					targets[m.files[m.currentFileIdx()]] = true
+				}
+				var untracked []string
+				for i, file := range m.files {
+					if targets[file] && m.fileStatuses[i].Status == "?" {
+						untracked = append(untracked, file)
+					}
+				}
+				if len(untracked) == 0 {
+					m.lastOperationStatus = "N only applies to untracked files"
+					m.showStatusMessage = true
+					return m, m.clearStatusAfterDelay()
+				}
+				m.operationInProgress = true
+				m.selectedFiles = make(map[string]bool)
+				return m, m.performIntentToAdd(untracked)
+
+			case "D":
+				if m.operationInProgress || len(m.files) == 0 {
+					return m, nil
+				}
+				files := m.getSelectedFiles()
+				if len(files) == 0 && !m.onDirRow() {
+					files = []string{m.files[m.currentFileIdx()]}
+				}
+				if len(files) > 0 {
+					m.confirmDelete = files
+				}
+				return m, nil
+
+			case "m":
+				if m.operationInProgress || len(m.files) == 0 || m.onDirRow() {
+					return m, nil
+				}
+				m.renameFrom = m.files[m.currentFileIdx()]
+				m.renameInput = textinput.New()
+				m.renameInput.Prompt = "> "
+				m.renameInput.CharLimit = 500
+				m.renameInput.Width = 50
+				m.renameInput.SetValue(m.renameFrom)
+				m.renameInput.CursorEnd()
+				m.renameInput.Focus()
+				return m, textinput.Blink
+
 			case "C", "P":
 				if m.operationInProgress {
 					return m, nil
@@ -447,17 +688,34 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.showStatusMessage = true
 					return m, m.clearStatusAfterDelay()
 				}
+				m.pushAfterCommit = msg.String() == "P"
+				if config.Load().CommitUseEditor {
+					return m, tea.ExecProcess(editorCommitCommand(m.repo, false), func(err error) tea.Msg {
+						if err != nil {
+							err = fmt.Errorf("git commit: %w", err)
+						}
+						return CommitCompleteMsg{Success: err == nil, Err: err}
+					})
+				}
 				m.commitInput = NewCommitInputModel(m.repo)
 				m.commitInput.embedded = true
-				m.pushAfterCommit = msg.String() == "P"
 				m.mode = CommitMode
 				return m, m.commitInput.Init()
 
 			case "p":
-				if m.operationInProgress || m.staged || len(m.files) == 0 {
+				if m.operationInProgress || m.staged || len(m.files) == 0 || m.onDirRow() {
 					return m, nil
 				}
 				filePath := m.files[m.currentFileIdx()]
+				// add -p has nothing to offer for a file git doesn't track
+				// yet; intent-to-add makes its whole content a hunk.
+				if m.fileStatuses[m.currentFileIdx()].Status == "?" {
+					if err := m.repo.IntentToAdd([]string{filePath}); err != nil {
+						m.lastOperationStatus = fmt.Sprintf("✗ Intent to add failed: %v", err)
+						m.showStatusMessage = true
+						return m, m.clearStatusAfterDelay()
+					}
+				}
 				patchCmd := exec.Command("git", "add", "-p", filePath)
 				return m, tea.ExecProcess(patchCmd, func(err error) tea.Msg {
 					return GitOperationCompleteMsg{
@@ -469,7 +727,7 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				})
 
 			case " ":
-				if len(m.files) > 0 {
+				if len(m.files) > 0 && !m.onDirRow() {
 					m.mode = DiffMode
 					// Expand diff viewer to full screen
 					if m.width > 0 {
@@ -493,7 +751,19 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 
+			case "f":
+				if m.mode == NormalMode {
+					m.jumping = true
+					m.jumpQuery = ""
+					m.jumpOrigin = m.jumpCursor()
+				}
+				return m, nil
+
 			case "g":
+				if m.mode == NormalMode && m.treeView {
+					m.treeCursor = 0
+					return m, m.moveTreeCursor(0)
+				}
 				if m.mode == NormalMode {
 					m.currentIndex = 0
 					m.scrollOffset = 0
@@ -501,12 +771,36 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 			case "G":
+				if m.mode == NormalMode && m.treeView {
+					m.treeCursor = countedRow(len(m.treeRows()), count)
+					return m, m.moveTreeCursor(0)
+				}
 				if m.mode == NormalMode && len(m.files) > 0 {
-					m.currentIndex = len(m.files) - 1
+					m.currentIndex = countedRow(len(m.files), count)
 					m.adjustScrolling()
 					return m, m.loadCurrentDiff()
 				}
 
+			case "t":
+				if m.mode == NormalMode {
+					m.treeView = !m.treeView
+					m.treeCursor = 0
+					if m.treeView {
+						for i, row := range m.treeRows() {
+							if row.index == m.currentIndex {
+								m.treeCursor = i
+							}
+						}
+					}
+					m.adjustScrolling()
+				}
+
+			case "o":
+				if row, ok := m.currentTreeRow(); ok && row.isDir() {
+					m.collapsed[row.dir] = !m.collapsed[row.dir]
+					m.adjustScrolling()
+				}
+
 			case "a":
 				if inLockedSearch {
 					for _, idx := range m.filteredIndices {
@@ -521,6 +815,68 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "A":
 				m.selectedFiles = make(map[string]bool)
 
+			case "x", "X":
+				// Confirm the current selection and hand it back to the
+				// caller: x for staging, X for restoring.
+				if m.operationInProgress || len(m.getSelectedFiles()) == 0 {
+					return m, nil
+				}
+				m.confirmed = true
+				m.removing = msg.String() == "X"
+				m.quitting = true
+				return m, tea.Quit
+
+			case "S":
+				if m.operationInProgress || m.staged || len(m.files) == 0 {
+					return m, nil
+				}
+				dir := path.Dir(m.files[m.currentFileIdx()])
+				if row, ok := m.currentTreeRow(); ok && row.isDir() {
+					dir = row.dir
+				}
+				if dir == "." {
+					m.lastOperationStatus = "✗ File is at the repository root; select it and press c"
+					m.showStatusMessage = true
+					return m, m.clearStatusAfterDelay()
+				}
+				m.operationInProgress = true
+				return m, m.performStageDirectory(dir)
+
+			case "d":
+				if len(m.files) == 0 || m.onDirRow() {
+					return m, nil
+				}
+				if !m.repo.DifftoolConfigured() {
+					// No difftool: fall back to the full-screen viewer.
+					m.mode = DiffMode
+					if m.width > 0 {
+						updatedDiff, _ := m.diffViewer.Update(m.fullDiffSize())
+						if dv, ok := updatedDiff.(DiffViewerModel); ok {
+							m.diffViewer = dv
+						}
+					}
+					return m, nil
+				}
+				filePath := m.files[m.currentFileIdx()]
+				return m, tea.ExecProcess(m.repo.DifftoolCommand(filePath, m.staged), func(err error) tea.Msg {
+					if err != nil {
+						return GitOperationCompleteMsg{error: err, operation: "difftool"}
+					}
+					return ClearStatusMsg{}
+				})
+
+			case "v":
+				selected := m.getSelectedFiles()
+				if len(selected) == 0 {
+					m.lastOperationStatus = "Select files to review first"
+					m.showStatusMessage = true
+					return m, m.clearStatusAfterDelay()
+				}
+				m.reviewQueue = selected
+				m.reviewIndex = 0
+				m.mode = DiffMode
+				return m, m.loadReviewDiff()
+
 			case "s":
 				m.splitPane = !m.splitPane
 
@@ -545,6 +901,7 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.files = append(m.files, status.Path)
 					}
 					m.currentIndex = 0
+					m.treeCursor = 0
 					m.scrollOffset = 0
 					return m, m.loadCurrentDiff()
 				}
@@ -555,6 +912,69 @@ func (m FilePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m FilePickerModel) treeRows() []fileTreeRow {
+	return buildFileTree(m.files, m.collapsed)
+}
+
+// currentTreeRow returns the tree row under the cursor, if the tree view
+// is showing.
+func (m FilePickerModel) currentTreeRow() (fileTreeRow, bool) {
+	if !m.treeView || m.mode != NormalMode {
+		return fileTreeRow{}, false
+	}
+	rows := m.treeRows()
+	if m.treeCursor < 0 || m.treeCursor >= len(rows) {
+		return fileTreeRow{}, false
+	}
+	return rows[m.treeCursor], true
+}
+
+func (m FilePickerModel) onDirRow() bool {
+	row, ok := m.currentTreeRow()
+	return ok && row.isDir()
+}
+
+// moveTreeCursor moves the tree cursor by delta rows, wrapping around, and
+// shows the diff when it lands on a file.
+func (m *FilePickerModel) moveTreeCursor(delta int) tea.Cmd {
+	rows := m.treeRows()
+	if len(rows) == 0 {
+		return nil
+	}
+	m.treeCursor = (m.treeCursor + delta + len(rows)) % len(rows)
+	m.adjustScrolling()
+	if m.syncTreeCursor() {
+		return m.loadCurrentDiff()
+	}
+	return nil
+}
+
+// syncTreeCursor points currentIndex at the file under the tree cursor,
+// reporting whether it is on a file.
+func (m *FilePickerModel) syncTreeCursor() bool {
+	row, ok := m.currentTreeRow()
+	if !ok || row.isDir() {
+		return false
+	}
+	m.currentIndex = row.index
+	return true
+}
+
+// toggleDirSelection selects every file under dir, or clears them all if
+// they were already selected.
+func (m *FilePickerModel) toggleDirSelection(dir string) {
+	under := filesUnder(m.files, dir)
+	all := true
+	for _, f := range under {
+		if !m.selectedFiles[f] {
+			all = false
+		}
+	}
+	for _, f := range under {
+		m.selectedFiles[f] = !all
+	}
+}
+
 // currentFileIdx returns the index into m.files for the currently highlighted item.
 func (m FilePickerModel) currentFileIdx() int {
 	if m.mode == SearchMode && m.searchLocked && len(m.filteredIndices) > 0 {
@@ -563,19 +983,111 @@ func (m FilePickerModel) currentFileIdx() int {
 	return m.currentIndex
 }
 
+// minPaneWidth is the narrowest a pane of the split layout gets before the
+// picker falls back to showing one pane at a time.
+const minPaneWidth = 40
+
+// compactLayout reports whether the window is too narrow to show the list
+// and the diff side by side. The list then takes the whole width and l
+// opens the diff full screen, h coming back. Height doesn't matter: the
+// panes sit next to each other, so a short window squeezes both alike.
+func (m FilePickerModel) compactLayout() bool {
+	return m.width > 0 && m.width < 2*minPaneWidth+1
+}
+
+// paneDiffSize is the size of the diff pane beside the list.
+func (m FilePickerModel) paneDiffSize() tea.WindowSizeMsg {
+	if m.compactLayout() {
+		return m.fullDiffSize()
+	}
+	return tea.WindowSizeMsg{Width: m.width - m.width/2 - 1, Height: m.height}
+}
+
+// leaveDiffMode returns from the full-screen diff to the list, putting the
+// diff back in its pane.
+func (m *FilePickerModel) leaveDiffMode() tea.Cmd {
+	m.mode = NormalMode
+	if m.reviewQueue != nil {
+		m.reviewQueue = nil
+		return m.loadCurrentDiff()
+	}
+	if m.width > 0 {
+		updatedDiff, _ := m.diffViewer.Update(m.paneDiffSize())
+		if dv, ok := updatedDiff.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+	}
+	return nil
+}
+
+// fullDiffSize is the size of the full-screen diff, leaving a line for the
+// review header while reviewing.
+func (m FilePickerModel) fullDiffSize() tea.WindowSizeMsg {
+	if m.reviewQueue != nil {
+		return tea.WindowSizeMsg{Width: m.width, Height: m.height - 1}
+	}
+	return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+}
+
+// loadReviewDiff shows the review queue's current file full screen.
+func (m *FilePickerModel) loadReviewDiff() tea.Cmd {
+	m.diffViewer.Close()
+	m.diffViewer = NewDiffViewerModel(m.repo, m.reviewQueue[m.reviewIndex])
+	m.diffViewer.staged = m.staged
+	m.diffViewer.origPath = m.origPath(m.reviewQueue[m.reviewIndex])
+	if m.width > 0 && m.height > 0 {
+		updatedDiff, _ := m.diffViewer.Update(m.fullDiffSize())
+		if dv, ok := updatedDiff.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+	}
+	return m.diffViewer.Init()
+}
+
+// stageReviewed stages the file under review, drops it from the queue and
+// moves on to the next one, ending the review after the last.
+func (m *FilePickerModel) stageReviewed() tea.Cmd {
+	if m.staged || m.operationInProgress {
+		return nil
+	}
+	file := m.reviewQueue[m.reviewIndex]
+	delete(m.selectedFiles, file)
+	m.reviewQueue = append(m.reviewQueue[:m.reviewIndex], m.reviewQueue[m.reviewIndex+1:]...)
+	m.operationInProgress = true
+	stage := m.performGitOperation([]string{file}, false)
+
+	if len(m.reviewQueue) == 0 {
+		m.reviewQueue = nil
+		m.mode = NormalMode
+		return stage
+	}
+	m.reviewIndex = min(m.reviewIndex, len(m.reviewQueue)-1)
+	return tea.Batch(stage, m.loadReviewDiff())
+}
+
+// origPath returns where file was renamed from, or "".
+func (m FilePickerModel) origPath(file string) string {
+	for _, f := range m.fileStatuses {
+		if f.Path == file {
+			return f.OrigPath
+		}
+	}
+	return ""
+}
+
 // loadCurrentDiff creates a new diff viewer for the currently highlighted file.
 func (m *FilePickerModel) loadCurrentDiff() tea.Cmd {
 	if len(m.files) == 0 {
 		return nil
 	}
 	filePath := m.files[m.currentFileIdx()]
+	m.diffViewer.Close()
 	m.diffViewer = NewDiffViewerModel(m.repo, filePath)
 	m.diffViewer.staged = m.staged
+	m.diffViewer.origPath = m.origPath(filePath)
 	// Re-apply the current pane size
 	if m.width > 0 && m.height > 0 {
-		rightWidth := m.width - m.width/2 - 1
-		sizeMsg := tea.WindowSizeMsg{Width: rightWidth, Height: m.height}
-		updatedDiff, _ := m.diffViewer.Update(sizeMsg)
+		updatedDiff, _ := m.diffViewer.Update(m.paneDiffSize())
 		if dv, ok := updatedDiff.(DiffViewerModel); ok {
 			m.diffViewer = dv
 		}
@@ -589,6 +1101,14 @@ func (m FilePickerModel) View() string {
 	}
 
 	// Full-screen diff mode
+	if m.mode == DiffMode && m.reviewQueue != nil {
+		keys := "n/p: next/previous  s: stage and advance  esc: back"
+		if m.staged {
+			keys = "n/p: next/previous  esc: back"
+		}
+		header := m.searchStyle.Render(fmt.Sprintf("Review %d/%d", m.reviewIndex+1, len(m.reviewQueue))) + "  " + m.helpStyle.Render(keys)
+		return lipgloss.JoinVertical(lipgloss.Left, header, m.diffViewer.View())
+	}
 	if m.mode == DiffMode {
 		return m.diffViewer.View()
 	}
@@ -599,9 +1119,6 @@ func (m FilePickerModel) View() string {
 	}
 
 	leftWidth := m.width / 2
-	if leftWidth < 10 {
-		leftWidth = m.width // fallback for very narrow terminals
-	}
 
 	// ── Left panel: file list ──────────────────────────────────────────────
 	var leftSections []string
@@ -616,7 +1133,11 @@ func (m FilePickerModel) View() string {
 	} else {
 		managing = "Unstaged changes"
 	}
-	leftSections = append(leftSections, m.titleStyle.Render("Files — "+managing))
+	title := m.titleStyle.Render("Files — " + managing)
+	if m.splitPane && m.compactLayout() {
+		title += m.helpStyle.Render("  l: diff")
+	}
+	leftSections = append(leftSections, title)
 
 	if m.showStatusMessage && m.lastOperationStatus != "" {
 		statusStyle := m.checkedStyle
@@ -626,7 +1147,31 @@ func (m FilePickerModel) View() string {
 		leftSections = append(leftSections, statusStyle.Render(m.lastOperationStatus))
 	}
 
-	if m.operationInProgress {
+	if m.confirmRevert != nil {
+		leftSections = append(leftSections, ErrorStyle.Render(fmt.Sprintf(
+			"Revert %d file(s) to HEAD? Staged and unstaged changes will be lost. y/N", len(m.confirmRevert))))
+	}
+
+	if m.confirmDelete != nil {
+		leftSections = append(leftSections, ErrorStyle.Render(fmt.Sprintf(
+			"Delete %d file(s) and stage the deletion? Uncommitted changes will be lost. y/N", len(m.confirmDelete))))
+	}
+
+	if m.renameFrom != "" {
+		leftSections = append(leftSections, m.searchStyle.Render("Rename "+m.renameFrom+" to:"))
+		leftSections = append(leftSections, m.renameInput.View())
+		leftSections = append(leftSections, m.helpStyle.Render("enter: rename (staged as a rename)  esc: cancel"))
+	}
+
+	if m.confirmPush != nil {
+		leftSections = append(leftSections, m.searchStyle.Render(fmt.Sprintf(
+			"Push %d commit(s)? y/N", len(m.confirmPush))))
+		leftSections = append(leftSections, outgoingLines(m.confirmPush, 8, m.helpStyle)...)
+	}
+
+	if m.operationInProgress && m.progress != nil {
+		leftSections = append(leftSections, m.searchStyle.Render("⏳ "+renderProgress(*m.progress)))
+	} else if m.operationInProgress {
 		leftSections = append(leftSections, m.searchStyle.Render("⏳ Operation in progress..."))
 	}
 
@@ -659,10 +1204,14 @@ func (m FilePickerModel) View() string {
 						checkbox = m.checkedStyle.Render("[x]")
 					}
 					statusChar := ""
-					if m.showStatusChars && idx < len(m.fileStatuses) {
-						statusChar = fmt.Sprintf("[%s] ", m.fileStatuses[idx].Status)
+					label := file
+					if idx < len(m.fileStatuses) {
+						label = renameLabel(m.fileStatuses[idx], file)
+						if m.showStatusChars {
+							statusChar = fmt.Sprintf("[%s] ", m.fileStatuses[idx].Status)
+						}
 					}
-					line := fmt.Sprintf("%s%s %s%s", prefix, checkbox, statusChar, file)
+					line := fmt.Sprintf("%s%s %s%s", prefix, checkbox, statusChar, label)
 					leftSections = append(leftSections, style.Render(line))
 				}
 			}
@@ -672,56 +1221,130 @@ func (m FilePickerModel) View() string {
 	} else {
 		selectedCount := len(m.getSelectedFiles())
 		leftSections = append(leftSections, m.unselectedStyle.Render(fmt.Sprintf("(%d selected)", selectedCount)))
-		leftSections = append(leftSections, "")
-
-		startIdx := m.scrollOffset
-		endIdx := min(startIdx+m.visibleLines, len(m.files))
-		for i := startIdx; i < endIdx; i++ {
-			file := m.files[i]
-			prefix := "  "
-			style := m.unselectedStyle
-			if i == m.currentIndex {
-				prefix = "> "
-				style = m.selectedStyle
-			}
-			checkbox := "[ ]"
-			if m.selectedFiles[file] {
-				checkbox = m.checkedStyle.Render("[x]")
-			}
-			statusChar := ""
-			if m.showStatusChars && i < len(m.fileStatuses) {
-				statusChar = fmt.Sprintf("[%s] ", m.fileStatuses[i].Status)
-			}
-			line := fmt.Sprintf("%s%s %s%s", prefix, checkbox, statusChar, file)
-			leftSections = append(leftSections, style.Render(line))
-		}
+		leftSections = append(leftSections, m.jumpLine())
 
-		if len(m.files) > m.visibleLines {
-			leftSections = append(leftSections, "")
-			leftSections = append(leftSections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(m.files))))
+		if m.treeView {
+			leftSections = append(leftSections, m.renderTree()...)
+		} else {
+			leftSections = append(leftSections, m.renderFlat()...)
 		}
 	}
 
-	if m.splitPane {
+	if m.splitPane && !m.compactLayout() {
 		leftPanel := lipgloss.NewStyle().Width(leftWidth).Render(strings.Join(leftSections, "\n"))
 		separator := m.separatorStyle.Render(strings.Repeat("│\n", m.height))
-		return lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, separator, m.diffViewer.View())
+		return lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, separator, m.diffViewer.paneView())
 	}
 
 	return lipgloss.NewStyle().Width(m.width).Render(strings.Join(leftSections, "\n"))
 }
 
+// renderFlat draws the NormalMode list one file per line.
+func (m FilePickerModel) renderFlat() []string {
+	var lines []string
+	startIdx := m.scrollOffset
+	endIdx := min(startIdx+m.visibleLines, len(m.files))
+	for i := startIdx; i < endIdx; i++ {
+		file := m.files[i]
+		prefix := "  "
+		style := m.unselectedStyle
+		if i == m.currentIndex {
+			prefix = "> "
+			style = m.selectedStyle
+		} else if m.jumpMatch(file) {
+			style = m.searchStyle
+		}
+		checkbox := "[ ]"
+		if m.selectedFiles[file] {
+			checkbox = m.checkedStyle.Render("[x]")
+		}
+		statusChar := ""
+		label := file
+		if i < len(m.fileStatuses) {
+			label = renameLabel(m.fileStatuses[i], file)
+			if m.showStatusChars {
+				statusChar = fmt.Sprintf("[%s] ", m.fileStatuses[i].Status)
+			}
+		}
+		line := fmt.Sprintf("%s%s %s%s", prefix, checkbox, statusChar, label)
+		lines = append(lines, style.Render(line))
+	}
+
+	if len(m.files) > m.visibleLines {
+		lines = append(lines, "")
+		lines = append(lines, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(m.files))))
+	}
+	return lines
+}
+
+// renderTree draws the NormalMode list grouped by directory. Directory
+// checkboxes show [x] when every file inside is selected and [-] for some.
+func (m FilePickerModel) renderTree() []string {
+	var lines []string
+	rows := m.treeRows()
+	startIdx := m.scrollOffset
+	endIdx := min(startIdx+m.visibleLines, len(rows))
+	for i := startIdx; i < endIdx; i++ {
+		row := rows[i]
+		prefix := "  "
+		style := m.unselectedStyle
+		if i == m.treeCursor {
+			prefix = "> "
+			style = m.selectedStyle
+		} else if !row.isDir() && m.jumpMatch(m.files[row.index]) {
+			style = m.searchStyle
+		}
+		checkbox := "[ ]"
+		statusChar := ""
+		if row.isDir() {
+			selected := 0
+			under := filesUnder(m.files, row.dir)
+			for _, f := range under {
+				if m.selectedFiles[f] {
+					selected++
+				}
+			}
+			if selected == len(under) {
+				checkbox = m.checkedStyle.Render("[x]")
+			} else if selected > 0 {
+				checkbox = m.checkedStyle.Render("[-]")
+			}
+		} else {
+			if m.selectedFiles[m.files[row.index]] {
+				checkbox = m.checkedStyle.Render("[x]")
+			}
+			if row.index < len(m.fileStatuses) {
+				row.name = renameLabel(m.fileStatuses[row.index], row.name)
+				if m.showStatusChars {
+					statusChar = fmt.Sprintf("[%s] ", m.fileStatuses[row.index].Status)
+				}
+			}
+		}
+		line := fmt.Sprintf("%s%s %s%s", prefix, checkbox, statusChar, treeRowLabel(row, m.collapsed))
+		lines = append(lines, style.Render(line))
+	}
+	if len(rows) > m.visibleLines {
+		lines = append(lines, "")
+		lines = append(lines, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(rows))))
+	}
+	return lines
+}
+
 func (m *FilePickerModel) adjustScrolling() {
 	if m.visibleLines <= 0 {
 		return
 	}
-	if m.currentIndex >= m.scrollOffset+m.visibleLines {
-		m.scrollOffset = m.currentIndex - m.visibleLines + 1
+	cursor, total := m.currentIndex, len(m.files)
+	if m.treeView {
+		cursor, total = m.treeCursor, len(m.treeRows())
 	}
-	if m.currentIndex < m.scrollOffset {
-		m.scrollOffset = m.currentIndex
+	if cursor >= m.scrollOffset+m.visibleLines {
+		m.scrollOffset = cursor - m.visibleLines + 1
 	}
-	maxOffset := len(m.files) - m.visibleLines
+	if cursor < m.scrollOffset {
+		m.scrollOffset = cursor
+	}
+	maxOffset := total - m.visibleLines
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
@@ -749,6 +1372,126 @@ func (m *FilePickerModel) performSearch() {
 	m.searchSelected = 0
 }
 
+// updateJump handles a key while type-to-jump is open: typing narrows to
+// the files matching the text, fuzzily, and moves to the first match from
+// where the jump started; up/down step between matches, enter stays on the
+// match and esc goes back.
+func (m FilePickerModel) updateJump(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.jumping, m.jumpQuery = false, ""
+		return m, m.jumpTo(m.jumpOrigin)
+	case "enter":
+		m.jumping, m.jumpQuery = false, ""
+		return m, nil
+	case "down", "ctrl+n":
+		return m, m.jumpFrom(m.jumpCursor()+1, 1)
+	case "up", "ctrl+p":
+		return m, m.jumpFrom(m.jumpCursor()-1, -1)
+	case "backspace":
+		if q := []rune(m.jumpQuery); len(q) > 0 {
+			m.jumpQuery = string(q[:len(q)-1])
+		}
+	default:
+		if msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace {
+			return m, nil
+		}
+		m.jumpQuery += string(msg.Runes)
+	}
+	if m.jumpQuery == "" {
+		return m, m.jumpTo(m.jumpOrigin)
+	}
+	return m, m.jumpFrom(m.jumpOrigin, 1)
+}
+
+// jumpMatch reports whether file matches the type-to-jump text.
+func (m FilePickerModel) jumpMatch(file string) bool {
+	return m.jumping && m.jumpQuery != "" && m.fuzzyMatch(strings.ToLower(file), strings.ToLower(m.jumpQuery))
+}
+
+// jumpMatches lists the cursor positions of the files matching the
+// type-to-jump text: indices into files, or into treeRows in the tree.
+func (m FilePickerModel) jumpMatches() []int {
+	var matches []int
+	if m.treeView {
+		for i, row := range m.treeRows() {
+			if !row.isDir() && m.jumpMatch(m.files[row.index]) {
+				matches = append(matches, i)
+			}
+		}
+		return matches
+	}
+	for i, file := range m.files {
+		if m.jumpMatch(file) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// jumpFrom moves to the nearest match at or after from (dir 1) or at or
+// before it (dir -1), wrapping around the ends of the list.
+func (m *FilePickerModel) jumpFrom(from, dir int) tea.Cmd {
+	matches := m.jumpMatches()
+	if len(matches) == 0 {
+		return nil
+	}
+	if dir < 0 {
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i] <= from {
+				return m.jumpTo(matches[i])
+			}
+		}
+		return m.jumpTo(matches[len(matches)-1])
+	}
+	for _, pos := range matches {
+		if pos >= from {
+			return m.jumpTo(pos)
+		}
+	}
+	return m.jumpTo(matches[0])
+}
+
+// jumpCursor is the cursor position jumpTo takes.
+func (m FilePickerModel) jumpCursor() int {
+	if m.treeView {
+		return m.treeCursor
+	}
+	return m.currentIndex
+}
+
+// jumpTo puts the cursor on pos, a tree row or a file index.
+func (m *FilePickerModel) jumpTo(pos int) tea.Cmd {
+	if m.treeView {
+		m.treeCursor = pos
+		return m.moveTreeCursor(0)
+	}
+	if pos >= len(m.files) {
+		return nil
+	}
+	m.currentIndex = pos
+	m.adjustScrolling()
+	return m.loadCurrentDiff()
+}
+
+// jumpLine shows the type-to-jump text and how many files match it, or
+// nothing when it is closed.
+func (m FilePickerModel) jumpLine() string {
+	if !m.jumping {
+		return ""
+	}
+	line := "Jump to: " + m.jumpQuery + "▏"
+	switch n := len(m.jumpMatches()); {
+	case m.jumpQuery == "":
+		line += "  (type part of a file name; enter: stay  esc: back)"
+	case n == 0:
+		line += "  (no match)"
+	default:
+		line += fmt.Sprintf("  (%d match(es); ↑/↓: previous/next)", n)
+	}
+	return m.searchStyle.Render(line)
+}
+
 func (m FilePickerModel) fuzzyMatch(text, query string) bool {
 	if query == "" {
 		return true
@@ -778,17 +1521,62 @@ func (m FilePickerModel) getSelectedFiles() []string {
 			selected = append(selected, file)
 		}
 	}
+	sort.Strings(selected)
 	return selected
 }
 
-func (m FilePickerModel) performPush() tea.Cmd {
+type outgoingLoadedMsg struct {
+	commits []git.CommitInfo
+	err     error
+}
+
+// loadOutgoing lists the commits a push would send, for confirmation.
+func (m FilePickerModel) loadOutgoing() tea.Cmd {
+	repo := m.repo
 	return func() tea.Msg {
-		err := m.repo.Push()
+		commits, err := repo.OutgoingCommits()
+		return outgoingLoadedMsg{commits: commits, err: err}
+	}
+}
+
+// outgoingLines renders up to limit commits, one per line.
+func outgoingLines(commits []git.CommitInfo, limit int, style lipgloss.Style) []string {
+	var lines []string
+	for i, c := range commits {
+		if i == limit {
+			lines = append(lines, style.Render(fmt.Sprintf("  … and %d more", len(commits)-limit)))
+			break
+		}
+		lines = append(lines, style.Render(fmt.Sprintf("  %s %s", c.ShortHash, c.Subject)))
+	}
+	return lines
+}
+
+func (m FilePickerModel) performPush() tea.Cmd {
+	repo := m.repo
+	push := func(onProgress git.ProgressFunc) error {
+		return repo.PushWithOptions(git.PushOptions{Progress: onProgress})
+	}
+	return runWithProgress(push, func(err error) tea.Msg {
 		return GitOperationCompleteMsg{
 			success:   err == nil,
 			error:     err,
 			operation: "push",
 		}
+	})
+}
+
+// performStageDirectory stages everything under dir in one go.
+func (m FilePickerModel) performStageDirectory(dir string) tea.Cmd {
+	files := filesUnder(m.files, dir)
+	return func() tea.Msg {
+		err := m.repo.StageDirectory(dir)
+		return GitOperationCompleteMsg{
+			success:       err == nil,
+			error:         err,
+			operation:     "stage",
+			filesAffected: files,
+		}
 	}
 }
 
@@ -798,7 +1586,11 @@ func (m FilePickerModel) performGitOperation(files []string, restore bool) tea.C
 		var operation string
 		if restore {
 			operation = "restore"
-			err = m.repo.RemoveFiles(files, m.staged)
+			paths := files
+			if m.staged {
+				paths = withOrigPaths(files, m.fileStatuses)
+			}
+			err = m.repo.RemoveFiles(paths, m.staged)
 		} else {
 			operation = "stage"
 			err = m.repo.AddFiles(files)
@@ -812,42 +1604,202 @@ func (m FilePickerModel) performGitOperation(files []string, restore bool) tea.C
 	}
 }
 
+// performRevert checks files out from HEAD, dropping all their changes.
+func (m FilePickerModel) performRevert(files []string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.repo.RevertToHead(files)
+		return GitOperationCompleteMsg{
+			success:       err == nil,
+			error:         err,
+			operation:     "revert",
+			filesAffected: files,
+		}
+	}
+}
+
+// performIntentToAdd marks untracked files with git add -N.
+func (m FilePickerModel) performIntentToAdd(files []string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.repo.IntentToAdd(files)
+		return GitOperationCompleteMsg{
+			success:       err == nil,
+			error:         err,
+			operation:     "intent",
+			filesAffected: files,
+		}
+	}
+}
+
+// performDelete removes files with git rm, stopping at the first failure.
+// The user has confirmed, so uncommitted changes don't block it.
+func (m FilePickerModel) performDelete(files []string) tea.Cmd {
+	return func() tea.Msg {
+		for i, file := range files {
+			if err := m.repo.DeleteTrackedFile(file, git.RemoveOptions{Force: true}); err != nil {
+				return GitOperationCompleteMsg{error: err, operation: "delete", filesAffected: files[:i]}
+			}
+		}
+		return GitOperationCompleteMsg{success: true, operation: "delete", filesAffected: files}
+	}
+}
+
+// performMove renames src to dst with git mv.
+func (m FilePickerModel) performMove(src, dst string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.repo.MoveFile(src, dst)
+		return GitOperationCompleteMsg{
+			success:       err == nil,
+			error:         err,
+			operation:     "move",
+			filesAffected: []string{dst},
+		}
+	}
+}
+
 func (m FilePickerModel) refreshRepositoryStatus() tea.Cmd {
 	return func() tea.Msg {
-		stagedFiles, unstagedFiles, err := m.repo.GetFileStatuses()
+		status, err := m.repo.GetRepositoryStatus()
+		if err != nil {
+			return StatusRefreshMsg{error: err}
+		}
 		return StatusRefreshMsg{
-			stagedFiles:   stagedFiles,
-			unstagedFiles: unstagedFiles,
-			error:         err,
+			stagedFiles:   status.StagedFiles,
+			unstagedFiles: append(status.UnstagedFiles, status.UntrackedFiles...),
+			bar:           statusBarOf(status),
 		}
 	}
 }
 
+// fileGone reports that a file vanished from under us and refreshes the
+// lists so the stale entry disappears.
+func (m *FilePickerModel) fileGone() tea.Cmd {
+	m.lastOperationStatus = "File no longer exists, refreshing..."
+	m.showStatusMessage = true
+	return tea.Batch(m.refreshRepositoryStatus(), m.clearStatusAfterDelay())
+}
+
 func (m FilePickerModel) clearStatusAfterDelay() tea.Cmd {
 	return tea.Tick(3*time.Second, func(t time.Time) tea.Msg {
 		return ClearStatusMsg{}
 	})
 }
 
+// FileOperationSummary tallies the operations performed during one file
+// picker session, so callers can report them after the TUI closes.
+type FileOperationSummary struct {
+	Staged    int
+	Unstaged  int
+	Discarded int
+	Reverted  int
+	Renamed   int
+	Deleted   int
+	Patched   int
+	Committed bool
+	Pushed    bool
+}
+
+func (s *FileOperationSummary) record(operation string, fromStaged bool, count int) {
+	switch operation {
+	case "stage":
+		s.Staged += count
+	case "restore":
+		if fromStaged {
+			s.Unstaged += count
+		} else {
+			s.Discarded += count
+		}
+	case "revert":
+		s.Reverted += count
+	case "move":
+		s.Renamed += count
+	case "delete":
+		s.Deleted += count
+	case "patch":
+		s.Patched += count
+	case "push":
+		s.Pushed = true
+	}
+}
+
+// String renders the summary as e.g. "staged 4 files, discarded 2 files".
+// It returns "" when nothing happened.
+func (s FileOperationSummary) String() string {
+	var parts []string
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 file"
+		}
+		return fmt.Sprintf("%d files", n)
+	}
+	if s.Staged > 0 {
+		parts = append(parts, "staged "+plural(s.Staged))
+	}
+	if s.Patched > 0 {
+		parts = append(parts, "patched "+plural(s.Patched))
+	}
+	if s.Unstaged > 0 {
+		parts = append(parts, "unstaged "+plural(s.Unstaged))
+	}
+	if s.Discarded > 0 {
+		parts = append(parts, "discarded "+plural(s.Discarded))
+	}
+	if s.Reverted > 0 {
+		parts = append(parts, "reverted "+plural(s.Reverted)+" to HEAD")
+	}
+	if s.Renamed > 0 {
+		parts = append(parts, "renamed "+plural(s.Renamed))
+	}
+	if s.Deleted > 0 {
+		parts = append(parts, "deleted "+plural(s.Deleted))
+	}
+	if s.Committed {
+		parts = append(parts, "committed")
+	}
+	if s.Pushed {
+		parts = append(parts, "pushed")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FilePickerResult is what SelectFiles hands back when the picker exits.
+// Files and Removing are only set when the user confirmed a selection with
+// x/X; Summary always covers the operations applied in place.
+type FilePickerResult struct {
+	Files    []string
+	Removing bool
+	Summary  FileOperationSummary
+}
+
 // SelectFiles provides an enhanced file picker with split-pane diff preview.
-func SelectFiles(repo *git.GitRepo, stagedFileStatuses []git.FileStatus, unstagedFileStatuses []git.FileStatus, staged bool) ([]string, bool, error) {
+// Operations (stage, restore, commit) are applied in place and the picker
+// stays open until the user quits or confirms a selection.
+func SelectFiles(repo *git.GitRepo, stagedFileStatuses []git.FileStatus, unstagedFileStatuses []git.FileStatus, staged bool) (FilePickerResult, error) {
 	if len(stagedFileStatuses) == 0 && len(unstagedFileStatuses) == 0 {
-		return []string{}, false, nil
+		return FilePickerResult{}, nil
 	}
 
+	// A credential prompt would be drawn over the alt screen and never seen,
+	// so pushes from the picker fail fast instead.
+	noPrompt := repo.NoPrompt
+	repo.NoPrompt = true
+	defer func() { repo.NoPrompt = noPrompt }()
+
 	m := NewFilePicker(repo, stagedFileStatuses, unstagedFileStatuses, staged)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
 	if err != nil {
-		return nil, false, err
+		return FilePickerResult{}, err
 	}
 
+	var result FilePickerResult
 	if model, ok := finalModel.(FilePickerModel); ok {
+		result.Summary = model.summary
 		if model.confirmed {
-			return model.getSelectedFiles(), model.removing, nil
+			result.Files = model.getSelectedFiles()
+			result.Removing = model.removing
 		}
 	}
 
-	return []string{}, false, nil
+	return result, nil
 }
@@ -1,27 +1,58 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
+	"os"
+	"os/exec"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
-var hashRegex = regexp.MustCompile(`\b([0-9a-f]{7,12})\b`)
-
 type cherryPickMsg struct {
 	hash string
 	err  error
 }
 
+type reorderMsg struct {
+	hash      string
+	direction int
+	err       error
+}
+
+type dropMsg struct {
+	hash string
+	err  error
+}
+
+type squashMsg struct {
+	hash        string
+	keepMessage bool
+	err         error
+}
+
+// squashEditorMsg carries a squash that is ready to run with the terminal,
+// for its messages to be combined in the editor.
+type squashEditorMsg struct {
+	hash   string
+	cmd    *exec.Cmd
+	finish func(error) error
+}
+
+type logReloadedMsg struct {
+	lines []git.LogLine
+	err   error
+}
+
 type LogViewerModel struct {
 	repo         *git.GitRepo
+	load         func() ([]git.LogLine, error)
 	mode         Mode
-	logLines     []string
-	commitHashes []string // parallel to logLines; empty string for graph-only lines
+	lines        []git.LogLine
 	currentIndex int
 	scrollOffset int
 	visibleLines int
@@ -32,6 +63,16 @@ type LogViewerModel struct {
 	showStatus bool
 	statusBar  StatusBar
 
+	// Commit waiting for y/n before being rewritten: dropped with 'd', or
+	// squashed into its parent with 'f' ("fixup") or 's' ("squash").
+	// dropPushed records whether a commit to drop is already on a remote.
+	confirmHash   string
+	confirmAction string
+	dropPushed    bool
+	// Set when a drop stopped on conflicts; the viewer quits so the caller
+	// can open the resolver.
+	dropConflict error
+
 	diffViewer DiffViewerModel
 
 	titleStyle      lipgloss.Style
@@ -40,23 +81,14 @@ type LogViewerModel struct {
 	helpStyle       lipgloss.Style
 	successStyle    lipgloss.Style
 	errorStyle      lipgloss.Style
+	refStyle        lipgloss.Style
+	graphStyles     []lipgloss.Style // cycled through by graph column
 }
 
-func NewLogViewerModel(repo *git.GitRepo, content string) LogViewerModel {
-	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
-
-	hashes := make([]string, len(lines))
-	for i, line := range lines {
-		if m := hashRegex.FindString(line); m != "" {
-			hashes[i] = m
-		}
-	}
-
-	return LogViewerModel{
-		repo:         repo,
-		mode:         NormalMode,
-		logLines:     lines,
-		commitHashes: hashes,
+func NewLogViewerModel(repo *git.GitRepo, lines []git.LogLine) LogViewerModel {
+	m := LogViewerModel{
+		repo: repo,
+		mode: NormalMode,
 
 		titleStyle:      TitlePinkStyle,
 		selectedStyle:   SelectedPeachStyle,
@@ -64,7 +96,46 @@ func NewLogViewerModel(repo *git.GitRepo, content string) LogViewerModel {
 		helpStyle:       HelpStyle,
 		successStyle:    SuccessStyle,
 		errorStyle:      ErrorStyle,
+		refStyle:        lipgloss.NewStyle().Foreground(colorCyan),
+	}
+	for _, c := range []lipgloss.TerminalColor{colorPink, colorGreen, colorOrange, colorCyan, colorPeach, colorRed} {
+		m.graphStyles = append(m.graphStyles, lipgloss.NewStyle().Foreground(c))
+	}
+	m.setLines(lines)
+	return m
+}
+
+// setLines replaces the log, keeping the selection on a commit row near
+// where it was.
+func (m *LogViewerModel) setLines(lines []git.LogLine) {
+	m.lines = lines
+	m.currentIndex = max(min(m.currentIndex, len(m.lines)-1), 0)
+	if m.selectedHash() == "" {
+		m.step(1)
+	}
+	m.adjustScrolling()
+}
+
+// selectedHash returns the selected commit, or "" when there is none.
+func (m LogViewerModel) selectedHash() string {
+	if m.currentIndex < len(m.lines) {
+		return m.lines[m.currentIndex].Hash
 	}
+	return ""
+}
+
+// step moves the selection delta commits down, wrapping around and
+// skipping rows that only carry the graph.
+func (m *LogViewerModel) step(delta int) {
+	n := len(m.lines)
+	for i := 1; i <= n; i++ {
+		idx := ((m.currentIndex+i*delta)%n + n) % n
+		if m.lines[idx].Hash != "" {
+			m.currentIndex = idx
+			break
+		}
+	}
+	m.adjustScrolling()
 }
 
 func (m LogViewerModel) Init() tea.Cmd {
@@ -79,6 +150,7 @@ func (m LogViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "q", "esc":
+				m.diffViewer.Close()
 				m.mode = NormalMode
 				return m, nil
 			}
@@ -123,21 +195,94 @@ func (m LogViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.showStatus = true
 		return m, FetchStatusBar(m.repo)
 
+	case reorderMsg:
+		m.showStatus = true
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("✗ move %s: %v", msg.hash, msg.err)
+			return m, nil
+		}
+		where := "later"
+		if msg.direction < 0 {
+			where = "earlier"
+		}
+		m.statusMsg = fmt.Sprintf("✓ Moved %s %s", msg.hash, where)
+		// The moved commit swapped places with its neighbour; follow it.
+		m.currentIndex = max(m.currentIndex-msg.direction, 0)
+		return m, tea.Batch(m.reload(), FetchStatusBar(m.repo))
+
+	case dropMsg:
+		if errors.Is(msg.err, git.ErrMergeConflict) {
+			m.dropConflict = msg.err
+			return m, tea.Quit
+		}
+		m.showStatus = true
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("✗ drop %s: %v", msg.hash, msg.err)
+			return m, nil
+		}
+		m.statusMsg = fmt.Sprintf("✓ Dropped %s", msg.hash)
+		return m, tea.Batch(m.reload(), FetchStatusBar(m.repo))
+
+	case squashMsg:
+		m.showStatus = true
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("✗ squash %s: %v", msg.hash, msg.err)
+			return m, nil
+		}
+		m.statusMsg = fmt.Sprintf("✓ Squashed %s into its parent, combining their messages", msg.hash)
+		if msg.keepMessage {
+			m.statusMsg = fmt.Sprintf("✓ Squashed %s into its parent, keeping the parent's message", msg.hash)
+		}
+		return m, tea.Batch(m.reload(), FetchStatusBar(m.repo))
+
+	case squashEditorMsg:
+		if editor := config.Load().Editor; editor != "" {
+			msg.cmd.Env = append(os.Environ(), "GIT_EDITOR="+editor)
+		}
+		return m, tea.ExecProcess(msg.cmd, func(err error) tea.Msg {
+			return squashMsg{hash: msg.hash, err: msg.finish(err)}
+		})
+
+	case logReloadedMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("✗ reload log: %v", msg.err)
+			m.showStatus = true
+			return m, nil
+		}
+		m.setLines(msg.lines)
+		return m, nil
+
 	case tea.KeyMsg:
+		// A pending drop or squash takes the next key as its answer.
+		if m.confirmHash != "" {
+			hash, action := m.confirmHash, m.confirmAction
+			m.confirmHash, m.confirmAction = "", ""
+			if msg.String() != "y" {
+				m.statusMsg = "Squash canceled"
+				if action == "drop" {
+					m.statusMsg = "Drop canceled"
+				}
+				m.showStatus = true
+				return m, nil
+			}
+			if action == "drop" {
+				return m, m.dropCmd(hash)
+			}
+			return m, m.squashCmd(hash, action == "fixup")
+		}
+
 		switch msg.String() {
 		case "q", "esc":
 			return m, tea.Quit
 
 		case "j", "down":
-			if len(m.logLines) > 0 {
-				m.currentIndex = (m.currentIndex + 1) % len(m.logLines)
-				m.adjustScrolling()
+			if len(m.lines) > 0 {
+				m.step(1)
 			}
 
 		case "k", "up":
-			if len(m.logLines) > 0 {
-				m.currentIndex = (m.currentIndex - 1 + len(m.logLines)) % len(m.logLines)
-				m.adjustScrolling()
+			if len(m.lines) > 0 {
+				m.step(-1)
 			}
 
 		case "g", "home":
@@ -145,20 +290,42 @@ func (m LogViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.scrollOffset = 0
 
 		case "G", "end":
-			if len(m.logLines) > 0 {
-				m.currentIndex = len(m.logLines) - 1
-				m.adjustScrolling()
+			if len(m.lines) > 0 {
+				m.currentIndex = 0
+				m.step(-1)
 			}
 
 		case "p":
-			if m.currentIndex < len(m.commitHashes) && m.commitHashes[m.currentIndex] != "" {
-				hash := m.commitHashes[m.currentIndex]
+			if hash := m.selectedHash(); hash != "" {
 				return m, m.cherryPickCmd(hash)
 			}
 
+		case "J", "shift+down":
+			if hash := m.selectedHash(); hash != "" {
+				return m, m.reorderCmd(hash, -1)
+			}
+
+		case "K", "shift+up":
+			if hash := m.selectedHash(); hash != "" {
+				return m, m.reorderCmd(hash, 1)
+			}
+
+		case "d":
+			if hash := m.selectedHash(); hash != "" && m.load != nil {
+				m.confirmHash, m.confirmAction = hash, "drop"
+				m.dropPushed = m.repo.IsPushed(hash)
+			}
+
+		case "f", "s":
+			if hash := m.selectedHash(); hash != "" && m.load != nil {
+				m.confirmHash, m.confirmAction = hash, "squash"
+				if msg.String() == "f" {
+					m.confirmAction = "fixup"
+				}
+			}
+
 		case "enter":
-			if m.currentIndex < len(m.commitHashes) && m.commitHashes[m.currentIndex] != "" {
-				hash := m.commitHashes[m.currentIndex]
+			if hash := m.selectedHash(); hash != "" {
 				m.diffViewer = NewDiffViewerModel(m.repo, hash)
 				m.mode = DetailMode
 				var cmds []tea.Cmd
@@ -188,6 +355,53 @@ func (m LogViewerModel) cherryPickCmd(hash string) tea.Cmd {
 	}
 }
 
+// reorderCmd moves hash one commit earlier (direction -1, down the log) or
+// later (direction 1, up the log) in history.
+func (m LogViewerModel) reorderCmd(hash string, direction int) tea.Cmd {
+	if m.load == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		err := m.repo.ReorderCommit(hash, direction)
+		return reorderMsg{hash: hash, direction: direction, err: err}
+	}
+}
+
+func (m LogViewerModel) dropCmd(hash string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.repo.DropCommit(hash)
+		return dropMsg{hash: hash, err: err}
+	}
+}
+
+// squashCmd folds hash into its parent: a fixup that keeps the parent's
+// message when keepMessage is set, a squash that combines both otherwise.
+// A squash opens the editor on the combined message, so it only gets the
+// rebase ready here and runs it from Update with the terminal attached.
+func (m LogViewerModel) squashCmd(hash string, keepMessage bool) tea.Cmd {
+	if m.load == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if keepMessage {
+			err := m.repo.SquashIntoParent(hash, true)
+			return squashMsg{hash: hash, keepMessage: true, err: err}
+		}
+		cmd, finish, err := m.repo.SquashEditorCommand(hash)
+		if err != nil {
+			return squashMsg{hash: hash, err: err}
+		}
+		return squashEditorMsg{hash: hash, cmd: cmd, finish: finish}
+	}
+}
+
+func (m LogViewerModel) reload() tea.Cmd {
+	return func() tea.Msg {
+		lines, err := m.load()
+		return logReloadedMsg{lines: lines, err: err}
+	}
+}
+
 func (m LogViewerModel) loadCommitDetail(hash string) tea.Cmd {
 	return func() tea.Msg {
 		content, err := m.repo.ShowCommit(hash)
@@ -206,7 +420,14 @@ func (m LogViewerModel) View() string {
 		sections = append(sections, bar)
 	}
 
-	sections = append(sections, m.titleStyle.Render("Git Log"))
+	title := m.titleStyle.Render("Git Log")
+	if len(m.lines) > m.visibleLines && m.visibleLines > 0 {
+		// The help line sits right under the list, so the position goes
+		// in the title rather than a line of its own.
+		endIdx := min(m.scrollOffset+m.visibleLines, len(m.lines))
+		title += m.helpStyle.Render(fmt.Sprintf("  (%d-%d of %d)", m.scrollOffset+1, endIdx, len(m.lines)))
+	}
+	sections = append(sections, title)
 
 	if m.showStatus {
 		style := m.successStyle
@@ -219,23 +440,58 @@ func (m LogViewerModel) View() string {
 	sections = append(sections, "")
 
 	startIdx := m.scrollOffset
-	endIdx := min(startIdx+m.visibleLines, len(m.logLines))
+	endIdx := min(startIdx+m.visibleLines, len(m.lines))
 
 	for i := startIdx; i < endIdx; i++ {
-		line := m.logLines[i]
+		l := m.lines[i]
+		prefix := "  "
+		style := m.unselectedStyle
 		if i == m.currentIndex {
-			sections = append(sections, m.selectedStyle.Render("> "+line))
-		} else {
-			sections = append(sections, m.unselectedStyle.Render("  "+line))
+			prefix = "> "
+			style = m.selectedStyle
+		}
+		line := prefix + m.renderGraph(l.Graph)
+		if l.Hash != "" {
+			line += m.helpStyle.Render(l.Hash) + " "
+			if l.Refs != "" {
+				line += m.refStyle.Render("("+l.Refs+")") + " "
+			}
+			line += style.Render(l.Subject)
 		}
+		sections = append(sections, line)
 	}
 
 	sections = append(sections, "")
-	sections = append(sections, m.helpStyle.Render("j/k: navigate  enter: view commit  p: cherry-pick  g/G: top/bottom  q: quit"))
+	switch m.confirmAction {
+	case "drop":
+		if m.dropPushed {
+			sections = append(sections, m.errorStyle.Render(fmt.Sprintf("%s is already pushed: dropping it rewrites shared history and needs a force push.", m.confirmHash)))
+		}
+		sections = append(sections, m.errorStyle.Render(fmt.Sprintf("Drop %s? y/N", m.confirmHash)))
+	case "fixup":
+		sections = append(sections, m.errorStyle.Render(fmt.Sprintf("Squash %s into its parent, keeping the parent's message? y/N", m.confirmHash)))
+	case "squash":
+		sections = append(sections, m.errorStyle.Render(fmt.Sprintf("Squash %s into its parent and edit the combined message? y/N", m.confirmHash)))
+	}
+	sections = append(sections, m.helpStyle.Render("j/k: navigate  enter: view commit  p: cherry-pick  J/K: move commit down/up  f/s: fixup/squash into parent  d: drop commit  g/G: top/bottom  q: quit"))
 
 	return strings.Join(sections, "\n")
 }
 
+// renderGraph colours each column of the graph drawing in turn, so the
+// lines of different branches can be told apart.
+func (m LogViewerModel) renderGraph(graph string) string {
+	var b strings.Builder
+	for i, r := range graph {
+		if r == ' ' {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString(m.graphStyles[(i/2)%len(m.graphStyles)].Render(string(r)))
+	}
+	return b.String()
+}
+
 func (m *LogViewerModel) adjustScrolling() {
 	if m.visibleLines <= 0 {
 		return
@@ -246,7 +502,7 @@ func (m *LogViewerModel) adjustScrolling() {
 	if m.currentIndex < m.scrollOffset {
 		m.scrollOffset = m.currentIndex
 	}
-	maxOffset := len(m.logLines) - m.visibleLines
+	maxOffset := len(m.lines) - m.visibleLines
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
@@ -258,9 +514,24 @@ func (m *LogViewerModel) adjustScrolling() {
 	}
 }
 
-func StartLogViewer(repo *git.GitRepo, content string) error {
-	m := NewLogViewerModel(repo, content)
+// StartLogViewer shows the log that load produces. load is called again
+// after a commit has been moved or dropped, to pick up the rewritten
+// history. If a drop stops on conflicts the viewer closes and returns that
+// error, which wraps git.ErrMergeConflict, with the rebase in progress.
+func StartLogViewer(repo *git.GitRepo, load func() ([]git.LogLine, error)) error {
+	lines, err := load()
+	if err != nil {
+		return err
+	}
+	m := NewLogViewerModel(repo, lines)
+	m.load = load
 	p := tea.NewProgram(m, tea.WithAltScreen())
-	_, err := p.Run()
-	return err
+	model, err := p.Run()
+	if err != nil {
+		return err
+	}
+	if finalModel, ok := model.(LogViewerModel); ok {
+		return finalModel.dropConflict
+	}
+	return nil
 }
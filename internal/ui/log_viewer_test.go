@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+func TestLogViewerAsksBeforeSquashing(t *testing.T) {
+	m := NewLogViewerModel(git.New(t.TempDir()), []git.LogLine{{Hash: "abc1234", Subject: "child"}, {Hash: "def5678", Subject: "parent"}})
+	m.load = func() ([]git.LogLine, error) { return m.lines, nil }
+	press := func(key string) tea.Cmd {
+		t.Helper()
+		model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = model.(LogViewerModel)
+		return cmd
+	}
+
+	for _, key := range []string{"f", "s"} {
+		if cmd := press(key); cmd != nil {
+			t.Fatalf("%s started rewriting before being confirmed", key)
+		}
+		if view := m.View(); !strings.Contains(view, "Squash abc1234 into its parent") {
+			t.Fatalf("%s shows no confirmation:\n%s", key, view)
+		}
+		if cmd := press("n"); cmd != nil || !strings.Contains(m.View(), "Squash canceled") {
+			t.Fatalf("n after %s did not cancel:\n%s", key, m.View())
+		}
+	}
+
+	press("s")
+	if cmd := press("y"); cmd == nil {
+		t.Fatal("y did not start the squash")
+	}
+}
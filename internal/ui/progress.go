@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+// networkProgressMsg carries one progress update from a running network
+// operation. ch is where the next update will arrive.
+type networkProgressMsg struct {
+	progress git.Progress
+	ch       <-chan tea.Msg
+}
+
+// runWithProgress runs op in the background, streaming its progress as
+// networkProgressMsg values. The message returned by done ends the stream.
+func runWithProgress(op func(git.ProgressFunc) error, done func(error) tea.Msg) tea.Cmd {
+	ch := make(chan tea.Msg)
+	go func() {
+		err := op(func(p git.Progress) {
+			ch <- networkProgressMsg{progress: p, ch: ch}
+		})
+		ch <- done(err)
+	}()
+	return waitForProgress(ch)
+}
+
+func waitForProgress(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+// renderProgress draws a one-line progress bar, e.g.
+// "Receiving objects  45% [█████░░░░░░░]".
+func renderProgress(p git.Progress) string {
+	const width = 20
+	filled := min(max(p.Percent*width/100, 0), width)
+	return fmt.Sprintf("%s %3d%% [%s%s]", p.Phase, p.Percent,
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled))
+}
+
+type spinnerDoneMsg struct {
+	err error
+}
+
+// spinnerModel shows a spinner next to title until work finishes.
+type spinnerModel struct {
+	spinner spinner.Model
+	title   string
+	work    func() error
+	done    bool
+	err     error
+}
+
+func (m spinnerModel) Init() tea.Cmd {
+	work := m.work
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return spinnerDoneMsg{err: work()}
+	})
+}
+
+func (m spinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case spinnerDoneMsg:
+		m.done = true
+		m.err = msg.err
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m spinnerModel) View() string {
+	if m.done {
+		return ""
+	}
+	return m.spinner.View() + " " + m.title + "\n"
+}
+
+// RunWithSpinner runs work while showing a spinner and title, for slow
+// operations that report no progress of their own. Input isn't read, so
+// the operation is never abandoned halfway.
+func RunWithSpinner(title string, work func() error) error {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = SelectedPeachStyle
+
+	final, err := tea.NewProgram(spinnerModel{spinner: s, title: title, work: work}, tea.WithInput(nil)).Run()
+	if err != nil {
+		return err
+	}
+	return final.(spinnerModel).err
+}
@@ -1,19 +1,26 @@
 package ui
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
-type rebaseCompleteMsg struct {
-	err error
-}
+// ErrRebaseCancelled is returned by EditRebaseTodo when the user quits the
+// picker without starting the rebase.
+var ErrRebaseCancelled = errors.New("rebase cancelled")
+
+// RebaseCancelEnv names the variable through which StartRebasePicker learns
+// that the picker, running as git's sequence editor, was cancelled: it
+// holds a path the picker creates before exiting.
+const RebaseCancelEnv = "CGIT_REBASE_CANCELLED"
 
 type RebasePickerModel struct {
 	repo         *git.GitRepo
@@ -24,24 +31,21 @@ type RebasePickerModel struct {
 	width        int
 	height       int
 
-	statusMsg  string
-	showStatus bool
+	statusMsg string
+	confirmed bool
 
 	titleStyle      lipgloss.Style
 	selectedStyle   lipgloss.Style
 	unselectedStyle lipgloss.Style
 	helpStyle       lipgloss.Style
-	successStyle    lipgloss.Style
 	errorStyle      lipgloss.Style
 	actionStyles    map[string]lipgloss.Style
 }
 
-var actionOrder = []string{"pick", "reword", "edit", "squash", "fixup", "drop"}
-
 func nextAction(current string) string {
-	for i, a := range actionOrder {
+	for i, a := range git.RebaseActions {
 		if a == current {
-			return actionOrder[(i+1)%len(actionOrder)]
+			return git.RebaseActions[(i+1)%len(git.RebaseActions)]
 		}
 	}
 	return "pick"
@@ -56,7 +60,6 @@ func NewRebasePickerModel(repo *git.GitRepo, entries []git.RebaseEntry) RebasePi
 		selectedStyle:   SelectedPeachStyle,
 		unselectedStyle: UnselectedStyle,
 		helpStyle:       HelpStyle,
-		successStyle:    SuccessStyle,
 		errorStyle:      ErrorStyle,
 		actionStyles: map[string]lipgloss.Style{
 			"pick":   lipgloss.NewStyle().Foreground(colorGreen),
@@ -80,18 +83,10 @@ func (m RebasePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.visibleLines = msg.Height - 7
 
-	case rebaseCompleteMsg:
-		if msg.err != nil {
-			m.statusMsg = fmt.Sprintf("✗ Rebase failed: %v", msg.err)
-		} else {
-			m.statusMsg = "✓ Rebase complete"
-		}
-		m.showStatus = true
-		return m, tea.Quit
-
 	case tea.KeyMsg:
+		m.statusMsg = ""
 		switch msg.String() {
-		case "q", "esc":
+		case "q", "esc", "ctrl+c":
 			return m, tea.Quit
 
 		case "j", "down":
@@ -106,63 +101,91 @@ func (m RebasePickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.adjustScrolling()
 			}
 
+		case "J", "shift+down":
+			m.move(1)
+
+		case "K", "shift+up":
+			m.move(-1)
+
 		case "p":
-			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = "pick"
-			}
+			m.setAction("pick")
 
 		case "r":
-			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = "reword"
-			}
+			m.setAction("reword")
 
 		case "e":
-			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = "edit"
-			}
+			m.setAction("edit")
 
 		case "s":
-			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = "squash"
-			}
+			m.setAction("squash")
 
 		case "f":
-			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = "fixup"
-			}
+			m.setAction("fixup")
 
 		case "d":
-			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = "drop"
-			}
+			m.setAction("drop")
 
 		case " ":
 			// Cycle through actions
 			if len(m.entries) > 0 {
-				m.entries[m.currentIndex].Action = nextAction(m.entries[m.currentIndex].Action)
+				m.setAction(nextAction(m.entries[m.currentIndex].Action))
 			}
 
 		case "enter", "x":
-			if len(m.entries) > 0 {
-				return m, m.executeRebase()
+			if err := m.validate(); err != nil {
+				m.statusMsg = err.Error()
+				return m, nil
 			}
+			m.confirmed = true
+			return m, tea.Quit
 		}
 	}
 
 	return m, nil
 }
 
+// setAction changes what the rebase does with the selected commit. Other
+// todo lines, such as exec, are left as they are.
+func (m *RebasePickerModel) setAction(action string) {
+	if len(m.entries) == 0 || !m.entries[m.currentIndex].IsCommit() {
+		return
+	}
+	m.entries[m.currentIndex].Action = action
+}
+
+// move shifts the selected entry by delta places, keeping it selected.
+func (m *RebasePickerModel) move(delta int) {
+	target := m.currentIndex + delta
+	if len(m.entries) == 0 || target < 0 || target >= len(m.entries) {
+		return
+	}
+	m.entries[m.currentIndex], m.entries[target] = m.entries[target], m.entries[m.currentIndex]
+	m.currentIndex = target
+	m.adjustScrolling()
+}
+
+// validate catches the todo mistakes git would otherwise only report after
+// the picker has closed.
+func (m RebasePickerModel) validate() error {
+	for _, e := range m.entries {
+		if !e.IsCommit() || e.Action == "drop" {
+			continue
+		}
+		if e.Action == "squash" || e.Action == "fixup" {
+			return fmt.Errorf("✗ the first commit kept can't be a %s: there is nothing before it to fold into", e.Action)
+		}
+		return nil
+	}
+	return nil
+}
+
 func (m RebasePickerModel) View() string {
 	var sections []string
 	sections = append(sections, m.titleStyle.Render(fmt.Sprintf("Interactive Rebase (%d commits)", len(m.entries))))
 	sections = append(sections, m.helpStyle.Render("oldest → newest (top = applied first)"))
 
-	if m.showStatus {
-		style := m.successStyle
-		if strings.HasPrefix(m.statusMsg, "✗") {
-			style = m.errorStyle
-		}
-		sections = append(sections, style.Render(m.statusMsg))
+	if m.statusMsg != "" {
+		sections = append(sections, m.errorStyle.Render(m.statusMsg))
 	}
 
 	sections = append(sections, "")
@@ -184,6 +207,9 @@ func (m RebasePickerModel) View() string {
 		}
 		action := actionStyle.Render(fmt.Sprintf("%-6s", e.Action))
 		line := fmt.Sprintf("%s%s  %s  %s", prefix, action, m.helpStyle.Render(e.Hash), subjectStyle.Render(e.Subject))
+		if !e.IsCommit() {
+			line = fmt.Sprintf("%s%s  %s", prefix, action, subjectStyle.Render(e.Subject))
+		}
 		sections = append(sections, line)
 	}
 
@@ -194,61 +220,11 @@ func (m RebasePickerModel) View() string {
 
 	sections = append(sections, "")
 	sections = append(sections, m.helpStyle.Render("p: pick  r: reword  e: edit  s: squash  f: fixup  d: drop  space: cycle"))
-	sections = append(sections, m.helpStyle.Render("enter/x: execute  q: quit"))
+	sections = append(sections, m.helpStyle.Render("J/K: move down/up  enter/x: start rebase  q: cancel"))
 
 	return strings.Join(sections, "\n")
 }
 
-func (m RebasePickerModel) executeRebase() tea.Cmd {
-	return func() tea.Msg {
-		// Build todo content (newest first from git log, but rebase needs oldest first)
-		reversed := make([]git.RebaseEntry, len(m.entries))
-		for i, e := range m.entries {
-			reversed[len(m.entries)-1-i] = e
-		}
-
-		var lines []string
-		for _, e := range reversed {
-			lines = append(lines, fmt.Sprintf("%s %s %s", e.Action, e.Hash, e.Subject))
-		}
-		content := strings.Join(lines, "\n") + "\n"
-
-		tmpFile, err := os.CreateTemp("", "cgit-rebase-*.txt")
-		if err != nil {
-			return rebaseCompleteMsg{err: err}
-		}
-		tmpPath := tmpFile.Name()
-		if _, err := tmpFile.WriteString(content); err != nil {
-			tmpFile.Close()
-			os.Remove(tmpPath)
-			return rebaseCompleteMsg{err: err}
-		}
-		tmpFile.Close()
-
-		// Normalise path separators for Git for Windows bash cp command
-		tmpPathNorm := strings.ReplaceAll(tmpPath, `\`, `/`)
-		seqEditor := fmt.Sprintf("cp '%s'", tmpPathNorm)
-
-		count := len(m.entries)
-		rebaseCmd := exec.Command("git",
-			"-c", "sequence.editor="+seqEditor,
-			"rebase", "-i", fmt.Sprintf("HEAD~%d", count),
-		)
-		rebaseCmd.Dir = m.repo.WorkDir
-		rebaseCmd.Env = os.Environ()
-
-		var errBuf strings.Builder
-		rebaseCmd.Stderr = &errBuf
-
-		runErr := rebaseCmd.Run()
-		os.Remove(tmpPath)
-		if runErr != nil {
-			return rebaseCompleteMsg{err: fmt.Errorf("%w\n%s", runErr, errBuf.String())}
-		}
-		return rebaseCompleteMsg{}
-	}
-}
-
 func (m *RebasePickerModel) adjustScrolling() {
 	if m.visibleLines <= 0 {
 		return
@@ -271,17 +247,76 @@ func (m *RebasePickerModel) adjustScrolling() {
 	}
 }
 
-func StartRebasePicker(repo *git.GitRepo, limit int) error {
-	entries, err := repo.GetRebaseCommits(limit)
+// StartRebasePicker rebases the last limit commits interactively. git
+// writes the todo and hands it to sequenceEditor, which should run
+// EditRebaseTodo; the terminal stays attached so reword and squash can open
+// the message editor. A rebase that stops on conflicts or at an edit is
+// left in progress.
+func StartRebasePicker(repo *git.GitRepo, limit int, sequenceEditor string) error {
+	marker, err := os.CreateTemp("", "cgit-rebase-*")
+	if err != nil {
+		return err
+	}
+	marker.Close()
+	// The picker recreates the file to report a cancel.
+	os.Remove(marker.Name())
+	defer os.Remove(marker.Name())
+
+	cmd := repo.InteractiveRebaseCommand(limit, sequenceEditor)
+	cmd.Env = append(os.Environ(), RebaseCancelEnv+"="+marker.Name())
+	if editor := config.Load().Editor; editor != "" {
+		cmd.Env = append(cmd.Env, "GIT_EDITOR="+editor)
+	}
+	var stderr bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if _, err := os.Stat(marker.Name()); err == nil {
+		fmt.Println("Rebase cancelled; nothing was changed.")
+		return nil
+	}
+	if repo.RebaseInProgress() {
+		if runErr != nil {
+			return fmt.Errorf("%s\nThe rebase stopped part way. Fix the conflicts and run 'cgit continue', or undo it with 'cgit abort'",
+				strings.TrimSpace(stderr.String()))
+		}
+		fmt.Println("Rebase stopped for editing. Amend the commit, then run 'cgit continue' (or 'cgit abort' to undo).")
+		return nil
+	}
+	if runErr != nil {
+		return fmt.Errorf("%w\n%s", runErr, strings.TrimSpace(stderr.String()))
+	}
+	fmt.Println("✓ Rebase complete")
+	return nil
+}
+
+// EditRebaseTodo is the sequence editor side of StartRebasePicker: it shows
+// the todo at path in the picker and writes the edited list back. Quitting
+// returns ErrRebaseCancelled; the caller should then exit with an error so
+// git abandons the rebase before touching anything.
+func EditRebaseTodo(path string) error {
+	content, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
+	entries := git.ParseRebaseTodo(string(content))
 	if len(entries) == 0 {
-		fmt.Println("No commits to rebase.")
 		return nil
 	}
-	m := NewRebasePickerModel(repo, entries)
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	_, err = p.Run()
-	return err
+
+	p := tea.NewProgram(NewRebasePickerModel(git.New("."), entries), tea.WithAltScreen())
+	model, err := p.Run()
+	if err != nil {
+		return err
+	}
+	m, ok := model.(RebasePickerModel)
+	if !ok || !m.confirmed {
+		if marker := os.Getenv(RebaseCancelEnv); marker != "" {
+			os.WriteFile(marker, nil, 0o600)
+		}
+		return ErrRebaseCancelled
+	}
+	return os.WriteFile(path, []byte(git.FormatRebaseTodo(m.entries)), 0o644)
 }
@@ -0,0 +1,193 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
+)
+
+const (
+	wizardStepTheme = iota
+	wizardStepBaseBranch
+	wizardStepConfirm
+	wizardStepDone
+)
+
+type SetupWizardModel struct {
+	cfg        config.Config
+	step       int
+	themeIndex int
+	confirm    bool
+	branch     textinput.Model
+	skipped    bool
+	aborted    bool
+
+	titleStyle      lipgloss.Style
+	selectedStyle   lipgloss.Style
+	unselectedStyle lipgloss.Style
+	helpStyle       lipgloss.Style
+}
+
+func NewSetupWizardModel() SetupWizardModel {
+	cfg := config.Default()
+
+	bi := textinput.New()
+	bi.Placeholder = "auto-detect"
+	bi.CharLimit = 100
+	bi.Width = 40
+
+	return SetupWizardModel{
+		cfg:     cfg,
+		confirm: cfg.ConfirmDestructive,
+		branch:  bi,
+
+		titleStyle:      TitlePinkStyle,
+		selectedStyle:   SelectedPinkStyle,
+		unselectedStyle: UnselectedStyle,
+		helpStyle:       HelpStyle,
+	}
+}
+
+func (m SetupWizardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SetupWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if m.step == wizardStepBaseBranch {
+			var cmd tea.Cmd
+			m.branch, cmd = m.branch.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "ctrl+c":
+		m.aborted = true
+		return m, tea.Quit
+	case "esc":
+		// Skip the rest of the wizard and keep defaults for everything.
+		m.cfg = config.Default()
+		m.skipped = true
+		m.step = wizardStepDone
+		return m, tea.Quit
+	}
+
+	switch m.step {
+	case wizardStepTheme:
+		switch keyMsg.String() {
+		case "j", "down":
+			m.themeIndex = (m.themeIndex + 1) % len(Themes)
+		case "k", "up":
+			m.themeIndex = (m.themeIndex - 1 + len(Themes)) % len(Themes)
+		case "enter":
+			m.cfg.Theme = Themes[m.themeIndex]
+			m.step = wizardStepBaseBranch
+			m.branch.Focus()
+			return m, textinput.Blink
+		}
+
+	case wizardStepBaseBranch:
+		if keyMsg.String() == "enter" {
+			m.cfg.BaseBranch = strings.TrimSpace(m.branch.Value())
+			m.branch.Blur()
+			m.step = wizardStepConfirm
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.branch, cmd = m.branch.Update(msg)
+		return m, cmd
+
+	case wizardStepConfirm:
+		switch keyMsg.String() {
+		case "y":
+			m.confirm = true
+		case "n":
+			m.confirm = false
+		case "j", "k", "down", "up", "tab":
+			m.confirm = !m.confirm
+		case "enter":
+			m.cfg.ConfirmDestructive = m.confirm
+			m.step = wizardStepDone
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+func (m SetupWizardModel) View() string {
+	if m.step == wizardStepDone || m.aborted {
+		return ""
+	}
+
+	var sections []string
+	sections = append(sections, m.titleStyle.Render("Welcome to cgit — first-run setup"))
+	sections = append(sections, m.helpStyle.Render(fmt.Sprintf("Step %d of 3", m.step+1)))
+	sections = append(sections, "")
+
+	switch m.step {
+	case wizardStepTheme:
+		sections = append(sections, "Color theme:")
+		for i, name := range Themes {
+			sections = append(sections, m.renderOption(name, i == m.themeIndex))
+		}
+		sections = append(sections, "")
+		sections = append(sections, m.helpStyle.Render("j/k: choose  enter: next  esc: skip (use defaults)"))
+
+	case wizardStepBaseBranch:
+		sections = append(sections, "Default base branch for feature work (leave empty to auto-detect):")
+		sections = append(sections, m.branch.View())
+		sections = append(sections, "")
+		sections = append(sections, m.helpStyle.Render("enter: next  esc: skip (use defaults)"))
+
+	case wizardStepConfirm:
+		sections = append(sections, "Ask for confirmation before destructive actions (e.g. full-clean)?")
+		sections = append(sections, m.renderOption("yes", m.confirm))
+		sections = append(sections, m.renderOption("no", !m.confirm))
+		sections = append(sections, "")
+		sections = append(sections, m.helpStyle.Render("y/n or j/k: choose  enter: save  esc: skip (use defaults)"))
+	}
+
+	return strings.Join(sections, "\n")
+}
+
+func (m SetupWizardModel) renderOption(label string, selected bool) string {
+	if selected {
+		return m.selectedStyle.Render("> " + label)
+	}
+	return m.unselectedStyle.Render("  " + label)
+}
+
+// RunSetupWizard walks the user through the initial settings and writes the
+// config file. Skipping with esc still writes the defaults so the wizard is
+// not shown again; ctrl+c leaves nothing on disk.
+func RunSetupWizard() (config.Config, error) {
+	m := NewSetupWizardModel()
+	p := tea.NewProgram(m)
+	finalModel, err := p.Run()
+	if err != nil {
+		return config.Default(), err
+	}
+
+	wm, ok := finalModel.(SetupWizardModel)
+	if !ok || wm.aborted {
+		return config.Default(), nil
+	}
+
+	if err := config.Save(wm.cfg); err != nil {
+		return wm.cfg, err
+	}
+	if wm.skipped {
+		fmt.Printf("Using default settings. Saved to %s\n", config.Path())
+	} else {
+		fmt.Printf("Settings saved to %s\n", config.Path())
+	}
+	return wm.cfg, nil
+}
@@ -159,7 +159,7 @@ func (m StashPickerModel) View() string {
 	if m.splitPane && m.width > 20 {
 		leftPanel := lipgloss.NewStyle().Width(leftWidth).Render(strings.Join(sections, "\n"))
 		separator := m.separatorStyle.Render(strings.Repeat("│\n", m.height))
-		return lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, separator, m.diffViewer.View())
+		return lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, separator, m.diffViewer.paneView())
 	}
 
 	return strings.Join(sections, "\n")
@@ -173,6 +173,8 @@ func (m StashPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.visibleLines = msg.Height - 8
+		// A smaller window can leave the cursor below the list.
+		m.adjustScrolling()
 		leftWidth := msg.Width / 2
 		rightWidth := msg.Width - leftWidth - 1
 		rightMsg := tea.WindowSizeMsg{Width: rightWidth, Height: msg.Height}
@@ -25,21 +25,24 @@ type StatusBarMsg struct {
 // FetchStatusBar fetches branch, ahead/behind, and clean state asynchronously.
 func FetchStatusBar(repo *git.GitRepo) tea.Cmd {
 	return func() tea.Msg {
-		var bar StatusBar
-
-		if branch, err := repo.GetCurrentBranch(); err == nil {
-			bar.Branch = branch
-		}
-		if clean, err := repo.IsClean(); err == nil {
-			bar.Clean = clean
-		}
-		if ahead, behind, err := repo.GetAheadBehind(); err == nil {
-			bar.HasUpstream = true
-			bar.Ahead = ahead
-			bar.Behind = behind
+		// One `git status` answers all of it.
+		status, err := repo.GetRepositoryStatus()
+		if err != nil {
+			return StatusBarMsg{}
 		}
+		return StatusBarMsg{Bar: statusBarOf(status)}
+	}
+}
 
-		return StatusBarMsg{Bar: bar}
+// statusBarOf builds the status bar from an already read status, for
+// views that load the file lists anyway.
+func statusBarOf(status *git.RepoStatus) StatusBar {
+	return StatusBar{
+		Branch:      status.CurrentBranch,
+		Ahead:       status.Ahead,
+		Behind:      status.Behind,
+		Clean:       len(status.StagedFiles) == 0 && len(status.UnstagedFiles) == 0 && len(status.UntrackedFiles) == 0,
+		HasUpstream: status.Upstream != "",
 	}
 }
 
@@ -1,33 +1,123 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"path"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/corpeningc/cgit/internal/config"
 	"github.com/corpeningc/cgit/internal/git"
 )
 
+// statusFilesLoadedMsg carries the file lists, and the status bar read
+// from the same `git status`.
 type statusFilesLoadedMsg struct {
-	staged   []git.FileStatus
-	unstaged []git.FileStatus
+	staged    []git.FileStatus
+	unstaged  []git.FileStatus
+	untracked []git.FileStatus
+	bar       StatusBar
+	stashes   int // -1 when not counted, as after staging
+	err       error
+	// keepPosition leaves the cursor on the same file (or where it was,
+	// clamped to the list, if that file has gone) instead of returning to
+	// the top.
+	keepPosition bool
+}
+
+// statusMarkersMsg carries the number of stray conflict markers in tracked
+// files.
+type statusMarkersMsg struct {
+	count int
+}
+
+type statusIgnoredLoadedMsg struct {
+	ignored []git.FileStatus
+	err     error
+}
+
+type statusFetchedMsg struct {
 	err      error
+	incoming int // commits to pull after the fetch; -1 when unknown
+}
+
+type difftoolClosedMsg struct {
+	err error
 }
 
+// statusStagedMsg reports that a staging change finished.
+type statusStagedMsg struct {
+	err error
+}
+
+// statusHelpSavedMsg reports whether toggling the help line was saved to
+// the config.
+type statusHelpSavedMsg struct {
+	err error
+}
+
+// statusRefreshTickMsg fires refreshDebounce after a staging change; only
+// the one from the latest change reloads the lists.
+type statusRefreshTickMsg struct {
+	seq int
+}
+
+// The status viewer's tabs, in order. The ignored tab is only present
+// while toggled on with i.
+const (
+	stagedTab = iota
+	unstagedTab
+	untrackedTab
+	ignoredTab
+)
+
 type StatusViewerModel struct {
-	repo          *git.GitRepo
-	stagedFiles   []git.FileStatus
-	unstagedFiles []git.FileStatus
-	statusBar     StatusBar
-	currentTab    int // 0=staged, 1=unstaged
-	currentIndex  int
-	scrollOffset  int
-	visibleLines  int
-	width         int
-	height        int
-	launchManage  bool
-	manageStaged  bool
+	repo           *git.GitRepo
+	stagedFiles    []git.FileStatus
+	unstagedFiles  []git.FileStatus
+	untrackedFiles []git.FileStatus
+	ignoredFiles   []git.FileStatus
+	stashCount     int
+	markerCount    int // stray conflict markers in tracked files
+	showIgnored    bool
+	statusBar      StatusBar
+	currentTab     int // stagedTab, unstagedTab, untrackedTab or ignoredTab (when shown)
+	currentIndex   int // row in currentRows()
+	treeView       bool
+	statusFilter   string // only show files with this status; "" shows all
+	collapsed      map[string]bool
+	scrollOffset   int
+	visibleLines   int
+	width          int
+	height         int
+	launchManage   bool
+	manageStaged   bool
+	historyPath    string
+	openShell      bool
+	mode           Mode
+	message        string
+	messageFailed  bool
+	fetching       bool
+	progress       *git.Progress
+	refreshSeq     int // bumped by scheduleRefresh; see statusRefreshTickMsg
+	count          countPrefix
+	showHelp       bool // the key help line; H toggles it
+	fullHelp       bool // the full key list opened with ?
+	// marks maps a mark's name to the file it was set on. It is shared
+	// with the models StartStatusViewer opens after manage and history.
+	marks map[string]string
+	// prefixKey is "M" or "'" while waiting for the name of a mark to set
+	// or jump to, and "F" while waiting for the filter digit.
+	prefixKey string
+
+	diffViewer DiffViewerModel
+	// diffStaged is which side of a file diff the viewer shows; tab flips
+	// it for files with both staged and unstaged changes.
+	diffStaged bool
+	palette    commandPalette
 
 	titleStyle       lipgloss.Style
 	selectedStyle    lipgloss.Style
@@ -41,7 +131,11 @@ type StatusViewerModel struct {
 
 func NewStatusViewerModel(repo *git.GitRepo) StatusViewerModel {
 	return StatusViewerModel{
-		repo: repo,
+		repo:      repo,
+		palette:   newCommandPalette(),
+		collapsed: make(map[string]bool),
+		marks:     make(map[string]string),
+		showHelp:  config.Load().ShowHelp,
 
 		titleStyle:       TitlePinkStyle,
 		selectedStyle:    SelectedPeachStyle,
@@ -55,145 +149,995 @@ func NewStatusViewerModel(repo *git.GitRepo) StatusViewerModel {
 }
 
 func (m StatusViewerModel) Init() tea.Cmd {
-	return tea.Batch(FetchStatusBar(m.repo), m.fetchFiles())
+	return tea.Batch(m.fetchFiles(), m.scanMarkers())
 }
 
 func (m StatusViewerModel) fetchFiles() tea.Cmd {
 	return func() tea.Msg {
-		staged, unstaged, err := m.repo.GetFileStatuses()
-		return statusFilesLoadedMsg{staged: staged, unstaged: unstaged, err: err}
+		msg := loadStatusFiles(m.repo)
+		stashes, _ := m.repo.StashList()
+		msg.stashes = len(stashes)
+		return msg
 	}
 }
 
+// loadStatusFiles reads the file lists and the status bar with one
+// `git status`.
+func loadStatusFiles(repo *git.GitRepo) statusFilesLoadedMsg {
+	status, err := repo.GetRepositoryStatus()
+	if err != nil {
+		return statusFilesLoadedMsg{stashes: -1, err: err}
+	}
+	return statusFilesLoadedMsg{
+		staged:    status.StagedFiles,
+		unstaged:  status.UnstagedFiles,
+		untracked: status.UntrackedFiles,
+		bar:       statusBarOf(status),
+		stashes:   -1,
+	}
+}
+
+// scanMarkers counts stray conflict markers. It greps the whole tree, so
+// it only runs when the viewer opens and on an explicit r, not on every
+// refresh.
+func (m StatusViewerModel) scanMarkers() tea.Cmd {
+	repo := m.repo
+	return func() tea.Msg {
+		hits, err := repo.ScanForConflictMarkers()
+		if err != nil {
+			return nil
+		}
+		return statusMarkersMsg{count: len(hits)}
+	}
+}
+
+// toggleStaged stages paths from the unstaged or untracked tab, or
+// unstages them from the staged tab. They leave the list, so the cursor
+// stays put and lands on the next file.
+func (m *StatusViewerModel) toggleStaged(paths []string) tea.Cmd {
+	repo := m.repo
+	if m.currentTab == stagedTab {
+		unstage := withOrigPaths(paths, m.stagedFiles)
+		m.dropPaths(paths)
+		return m.applyStaging(func() error { return repo.RemoveFiles(unstage, true) })
+	}
+	m.dropPaths(paths)
+	return m.applyStaging(func() error { return repo.AddFiles(paths) })
+}
+
+// withOrigPaths adds the paths that renames among paths came from. A staged
+// rename is a staged deletion too, so unstaging only the new path would
+// leave the old one deleted in the index.
+func withOrigPaths(paths []string, files []git.FileStatus) []string {
+	renamed := make(map[string]string)
+	for _, f := range files {
+		if f.OrigPath != "" {
+			renamed[f.Path] = f.OrigPath
+		}
+	}
+	all := append([]string(nil), paths...)
+	for _, p := range paths {
+		if orig, ok := renamed[p]; ok {
+			all = append(all, orig)
+		}
+	}
+	return all
+}
+
+// toggleStagedDir stages everything under dir from the unstaged or
+// untracked tab (new and modified files alike), or
+// unstages the staged files under it from the staged tab.
+func (m *StatusViewerModel) toggleStagedDir(dir string) tea.Cmd {
+	repo := m.repo
+	if m.currentTab == stagedTab {
+		var paths []string
+		for _, f := range m.stagedFiles {
+			paths = append(paths, f.Path)
+		}
+		return m.toggleStaged(filesUnder(paths, dir))
+	}
+	var paths []string
+	for _, f := range m.currentFiles() {
+		paths = append(paths, f.Path)
+	}
+	m.dropPaths(filesUnder(paths, dir))
+	return m.applyStaging(func() error { return repo.StageDirectory(dir) })
+}
+
+// dropPaths takes paths off the current tab straight away, so the cursor
+// moves on while the refresh that moves them to the other tab is pending.
+func (m *StatusViewerModel) dropPaths(paths []string) {
+	drop := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		drop[p] = true
+	}
+	keep := func(files []git.FileStatus) []git.FileStatus {
+		var kept []git.FileStatus
+		for _, f := range files {
+			if !drop[f.Path] {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+	switch m.currentTab {
+	case stagedTab:
+		m.stagedFiles = keep(m.stagedFiles)
+	case unstagedTab:
+		m.unstagedFiles = keep(m.unstagedFiles)
+	case untrackedTab:
+		m.untrackedFiles = keep(m.untrackedFiles)
+	}
+	m.currentIndex = max(min(m.currentIndex, len(m.currentRows())-1), 0)
+	m.adjustScrolling()
+}
+
+// refreshDebounce is how long the file lists must go without another
+// staging change before they are reloaded, so a burst of staging costs one
+// git status rather than one per key press.
+const refreshDebounce = 150 * time.Millisecond
+
+// applyStaging runs op, then schedules a reload of the file lists.
+func (m StatusViewerModel) applyStaging(op func() error) tea.Cmd {
+	return func() tea.Msg {
+		return statusStagedMsg{err: op()}
+	}
+}
+
+// scheduleRefresh reloads the file lists once refreshDebounce passes
+// without another call; each call supersedes the pending one, so the last
+// change is always followed by a reload.
+func (m *StatusViewerModel) scheduleRefresh() tea.Cmd {
+	m.refreshSeq++
+	seq := m.refreshSeq
+	return tea.Tick(refreshDebounce, func(time.Time) tea.Msg {
+		return statusRefreshTickMsg{seq: seq}
+	})
+}
+
+// refreshFiles reloads the file lists, keeping the cursor in place.
+func (m StatusViewerModel) refreshFiles() tea.Cmd {
+	repo := m.repo
+	return func() tea.Msg {
+		msg := loadStatusFiles(repo)
+		msg.keepPosition = true
+		return msg
+	}
+}
+
+func (m StatusViewerModel) fetchIgnored() tea.Cmd {
+	return func() tea.Msg {
+		ignored, err := m.repo.GetIgnoredFiles()
+		return statusIgnoredLoadedMsg{ignored: ignored, err: err}
+	}
+}
+
+// setFilter applies the filter typed after F: 1–3 show one status, or
+// drop it again when it is already shown, and 0 clears it. Other keys
+// leave the filter alone.
+func (m *StatusViewerModel) setFilter(key string) {
+	status, ok := statusFilters[key]
+	switch {
+	case key == "0" || ok && m.statusFilter == status:
+		m.statusFilter = ""
+	case ok:
+		m.statusFilter = status
+	default:
+		return
+	}
+	m.currentIndex = 0
+	m.scrollOffset = 0
+}
+
+// statusFilters maps the filter keys to the status they show. Untracked
+// files have a tab of their own, so there is no filter for them.
+var statusFilters = map[string]string{"1": "M", "2": "A", "3": "D"}
+
+var statusFilterNames = map[string]string{"M": "modified", "A": "added", "D": "deleted"}
+
 func (m StatusViewerModel) currentFiles() []git.FileStatus {
-	if m.currentTab == 0 {
-		return m.stagedFiles
+	switch m.currentTab {
+	case stagedTab:
+		return m.filtered(m.stagedFiles)
+	case untrackedTab:
+		return m.filtered(m.untrackedFiles)
+	case ignoredTab:
+		return m.filtered(m.ignoredFiles)
+	}
+	return m.filtered(m.unstagedFiles)
+}
+
+// filtered returns the files matching the active status filter.
+func (m StatusViewerModel) filtered(files []git.FileStatus) []git.FileStatus {
+	if m.statusFilter == "" {
+		return files
+	}
+	var matching []git.FileStatus
+	for _, f := range files {
+		if f.Status == m.statusFilter {
+			matching = append(matching, f)
+		}
+	}
+	return matching
+}
+
+// tabLabel renders a tab's name and count, showing how many files pass
+// the filter when one is active.
+func (m StatusViewerModel) tabLabel(name string, files []git.FileStatus) string {
+	if m.statusFilter == "" {
+		return fmt.Sprintf("  %s (%d)  ", name, len(files))
+	}
+	return fmt.Sprintf("  %s (%d/%d)  ", name, len(m.filtered(files)), len(files))
+}
+
+// currentRows lists the current tab's files, flat or grouped by directory.
+func (m StatusViewerModel) currentRows() []fileTreeRow {
+	files := m.currentFiles()
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	if m.treeView {
+		return buildFileTree(paths, m.collapsed)
+	}
+	return flatFileRows(paths)
+}
+
+// selectedRow returns the row under the cursor.
+func (m StatusViewerModel) selectedRow() (fileTreeRow, bool) {
+	rows := m.currentRows()
+	if m.currentIndex < 0 || m.currentIndex >= len(rows) {
+		return fileTreeRow{}, false
+	}
+	return rows[m.currentIndex], true
+}
+
+// selectedPath returns the path of the file or directory under the
+// cursor, or "" when the list is empty.
+func (m StatusViewerModel) selectedPath() string {
+	row, ok := m.selectedRow()
+	switch {
+	case !ok:
+		return ""
+	case row.isDir():
+		return row.dir
+	}
+	return m.currentFiles()[row.index].Path
+}
+
+// reselect puts the cursor back on path after the lists were reloaded.
+// When path has gone, the cursor stays at the same position, clamped to
+// the list, which lands it on the neighbour that took its place.
+func (m *StatusViewerModel) reselect(path string) {
+	if i, ok := m.rowOf(path); ok {
+		m.currentIndex = i
+		return
+	}
+	m.currentIndex = max(min(m.currentIndex, len(m.currentRows())-1), 0)
+}
+
+// rowOf finds the row of the current tab showing path, a file or a
+// directory.
+func (m StatusViewerModel) rowOf(path string) (int, bool) {
+	if path == "" {
+		return 0, false
+	}
+	files := m.currentFiles()
+	for i, row := range m.currentRows() {
+		if row.dir == path || !row.isDir() && files[row.index].Path == path {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// setMark records the file under the cursor as mark name.
+func (m *StatusViewerModel) setMark(name string) {
+	row, ok := m.selectedRow()
+	if !ok || row.isDir() {
+		return
+	}
+	path := m.currentFiles()[row.index].Path
+	m.marks[name] = path
+	m.message = fmt.Sprintf("Mark '%s set on %s", name, path)
+	m.messageFailed = false
+}
+
+// jumpToMark moves the cursor to the file marked name, switching tabs when
+// the file has moved since, e.g. from unstaged to staged.
+func (m *StatusViewerModel) jumpToMark(name string) {
+	path, ok := m.marks[name]
+	if !ok {
+		m.message = fmt.Sprintf("✗ Mark '%s is not set", name)
+		m.messageFailed = true
+		return
+	}
+	// Look in the current tab first, then in each tab in turn.
+	tab := m.currentTab
+	for i := -1; i < m.tabCount(); i++ {
+		if i >= 0 {
+			m.currentTab = i
+		}
+		if row, ok := m.rowOf(path); ok {
+			if m.currentTab != tab {
+				m.scrollOffset = 0
+			}
+			m.currentIndex = row
+			m.adjustScrolling()
+			m.message = ""
+			return
+		}
+	}
+	m.currentTab = tab
+	m.message = fmt.Sprintf("✗ %s (mark '%s) is not in the lists", path, name)
+	m.messageFailed = true
+}
+
+// selectedPaths returns the file under the cursor, or every file inside
+// the directory under the cursor.
+func (m StatusViewerModel) selectedPaths() []string {
+	row, ok := m.selectedRow()
+	if !ok {
+		return nil
+	}
+	files := m.currentFiles()
+	if !row.isDir() {
+		return []string{files[row.index].Path}
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
 	}
-	return m.unstagedFiles
+	return filesUnder(paths, row.dir)
+}
+
+func (m StatusViewerModel) tabCount() int {
+	if m.showIgnored {
+		return 4
+	}
+	return 3
 }
 
 func (m StatusViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.mode == DetailMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			if msg.String() == "q" || msg.String() == "esc" {
+				m.diffViewer.Close()
+				m.mode = NormalMode
+				return m, nil
+			}
+			if msg.String() == "tab" && m.diffViewer.load == nil && m.partiallyStaged(m.diffViewer.filePath) {
+				prev := m.diffViewer
+				prev.Close()
+				m.showFileDiff(prev.filePath, !m.diffStaged)
+				m.diffViewer.context, m.diffViewer.fullFile = prev.context, prev.fullFile
+				return m, m.showDetail()
+			}
+			return m.updateDiffViewer(msg)
+		case tea.WindowSizeMsg:
+			m.width = msg.Width
+			m.height = msg.Height
+			m.visibleLines = m.listHeight()
+			return m.updateDiffViewer(msg)
+		case diffLoadedMsg:
+			return m.updateDiffViewer(msg)
+		}
+	}
+
+	if m.mode == PaletteMode {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "esc":
+				m.palette.close()
+				m.mode = NormalMode
+				return m, nil
+			case "tab":
+				m.palette.complete()
+				return m, nil
+			case "enter":
+				m.palette.close()
+				m.mode = NormalMode
+				return m, m.palette.run()
+			}
+			return m, m.palette.update(msg)
+		case tea.WindowSizeMsg, StatusBarMsg, statusFilesLoadedMsg, statusIgnoredLoadedMsg,
+			networkProgressMsg, statusFetchedMsg:
+			// handled below
+		default:
+			// cursor blinks and other input internals
+			return m, m.palette.update(msg)
+		}
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.visibleLines = msg.Height - 8
+		m.visibleLines = m.listHeight()
+
+	case networkProgressMsg:
+		m.progress = &msg.progress
+		return m, waitForProgress(msg.ch)
+
+	case difftoolClosedMsg:
+		if msg.err != nil {
+			m.message = fmt.Sprintf("✗ Difftool failed: %v", msg.err)
+			m.messageFailed = true
+		}
+		return m, nil
+
+	case statusFetchedMsg:
+		m.fetching = false
+		m.progress = nil
+		m.messageFailed = msg.err != nil
+		if msg.err != nil {
+			m.message = fmt.Sprintf("✗ Fetch failed: %v", msg.err)
+		} else if msg.incoming > 0 {
+			m.message = fmt.Sprintf("✓ Fetched from origin — %d incoming commit(s), c to list", msg.incoming)
+		} else {
+			m.message = "✓ Fetched from origin"
+		}
+		return m, FetchStatusBar(m.repo)
+
+	case statusHelpSavedMsg:
+		if msg.err != nil {
+			m.message = fmt.Sprintf("✗ Couldn't save show_help: %v", msg.err)
+			m.messageFailed = true
+		}
+		return m, nil
+
+	case paletteResultMsg:
+		m.message = paletteMessage(msg)
+		m.messageFailed = msg.err != nil
+		return m, m.fetchFiles()
 
 	case StatusBarMsg:
 		m.statusBar = msg.Bar
 
+	case statusStagedMsg:
+		if msg.err != nil {
+			m.message = fmt.Sprintf("✗ %v", msg.err)
+			m.messageFailed = true
+		}
+		// Files were taken off the list up front; a failure needs the
+		// reload just as much to put them back.
+		return m, m.scheduleRefresh()
+
+	case statusRefreshTickMsg:
+		if msg.seq != m.refreshSeq {
+			return m, nil
+		}
+		return m, m.refreshFiles()
+
 	case statusFilesLoadedMsg:
+		if msg.err != nil && msg.keepPosition {
+			m.message = fmt.Sprintf("✗ %v", msg.err)
+			m.messageFailed = true
+			return m, nil
+		}
+		selected := m.selectedPath()
 		if msg.err == nil {
 			m.stagedFiles = msg.staged
 			m.unstagedFiles = msg.unstaged
+			m.untrackedFiles = msg.untracked
+			m.statusBar = msg.bar
+		}
+		if msg.stashes >= 0 {
+			m.stashCount = msg.stashes
+		}
+		if msg.keepPosition {
+			m.reselect(selected)
+			m.adjustScrolling()
+			return m, nil
 		}
 		m.currentIndex = 0
 		m.scrollOffset = 0
 
+	case statusMarkersMsg:
+		m.markerCount = msg.count
+
+	case statusIgnoredLoadedMsg:
+		if msg.err == nil {
+			m.ignoredFiles = msg.ignored
+		}
+		if m.currentTab == ignoredTab {
+			m.currentIndex = 0
+			m.scrollOffset = 0
+		}
+
 	case tea.KeyMsg:
+		// Any key closes the full help.
+		if m.fullHelp {
+			m.fullHelp = false
+			return m, nil
+		}
+
+		// M and ' take the next key as the mark's name, F as the filter.
+		if m.prefixKey != "" {
+			key := m.prefixKey
+			m.prefixKey = ""
+			name := msg.String()
+			switch {
+			case key == "F":
+				m.setFilter(name)
+			case len([]rune(name)) != 1:
+			case key == "M":
+				m.setMark(name)
+			default:
+				m.jumpToMark(name)
+			}
+			return m, nil
+		}
+
+		// Digits are all free for counts; the filters sit behind F.
+		if m.count.add(msg.String()) {
+			return m, nil
+		}
+		count := m.count.take()
+
 		switch msg.String() {
 		case "q", "esc":
 			return m, tea.Quit
 
 		case "tab":
-			m.currentTab = 1 - m.currentTab
+			m.currentTab = (m.currentTab + 1) % m.tabCount()
 			m.currentIndex = 0
 			m.scrollOffset = 0
 
-		case "j", "down":
-			files := m.currentFiles()
-			if len(files) > 0 {
-				m.currentIndex = (m.currentIndex + 1) % len(files)
-				m.adjustScrolling()
+		case "i":
+			m.showIgnored = !m.showIgnored
+			if m.showIgnored {
+				return m, m.fetchIgnored()
+			}
+			if m.currentTab == ignoredTab {
+				m.currentTab = stagedTab
+				m.currentIndex = 0
+				m.scrollOffset = 0
 			}
 
+		case "j", "down":
+			m.currentIndex = countedStep(m.currentIndex, len(m.currentRows()), 1, count)
+			m.adjustScrolling()
+
 		case "k", "up":
-			files := m.currentFiles()
-			if len(files) > 0 {
-				m.currentIndex = (m.currentIndex - 1 + len(files)) % len(files)
+			m.currentIndex = countedStep(m.currentIndex, len(m.currentRows()), -1, count)
+			m.adjustScrolling()
+
+		case "M", "'", "F":
+			m.prefixKey = msg.String()
+
+		case "?":
+			m.fullHelp = true
+
+		case "H":
+			m.showHelp = !m.showHelp
+			m.visibleLines = m.listHeight()
+			m.adjustScrolling()
+			show := m.showHelp
+			return m, func() tea.Msg {
+				return statusHelpSavedMsg{err: config.Update(func(cfg *config.Config) { cfg.ShowHelp = show })}
+			}
+
+		case "g", "home":
+			m.currentIndex = 0
+			m.adjustScrolling()
+
+		case "G", "end":
+			m.currentIndex = countedRow(len(m.currentRows()), count)
+			m.adjustScrolling()
+
+		case "t":
+			m.treeView = !m.treeView
+			m.currentIndex = 0
+			m.scrollOffset = 0
+
+		case "o":
+			if row, ok := m.selectedRow(); ok && row.isDir() {
+				m.collapsed[row.dir] = !m.collapsed[row.dir]
 				m.adjustScrolling()
 			}
 
+		case "s":
+			row, ok := m.selectedRow()
+			if m.currentTab == ignoredTab || !ok {
+				return m, nil
+			}
+			var cmd tea.Cmd
+			if row.isDir() {
+				cmd = m.toggleStagedDir(row.dir)
+			} else {
+				cmd = m.toggleStaged(m.selectedPaths())
+			}
+			return m, cmd
+
+		case "S":
+			row, ok := m.selectedRow()
+			if m.currentTab == ignoredTab || !ok {
+				return m, nil
+			}
+			dir := row.dir
+			if !row.isDir() {
+				dir = path.Dir(m.currentFiles()[row.index].Path)
+			}
+			if dir == "." {
+				m.message = "✗ File is at the repository root; use s to stage it alone"
+				m.messageFailed = true
+				return m, nil
+			}
+			cmd := m.toggleStagedDir(dir)
+			return m, cmd
+
 		case "m":
+			if m.currentTab == ignoredTab {
+				return m, nil
+			}
 			m.launchManage = true
-			m.manageStaged = m.currentTab == 0
+			m.manageStaged = m.currentTab == stagedTab
 			return m, tea.Quit
 
+		case "d":
+			row, ok := m.selectedRow()
+			if m.currentTab == ignoredTab || !ok || row.isDir() {
+				return m, nil
+			}
+			return m, m.openDifftool(m.currentFiles()[row.index].Path, m.currentTab == stagedTab)
+
+		case "h":
+			row, ok := m.selectedRow()
+			if m.currentTab == ignoredTab || !ok || row.isDir() || m.currentFiles()[row.index].Status == "?" {
+				return m, nil
+			}
+			m.historyPath = m.currentFiles()[row.index].Path
+			return m, tea.Quit
+
+		case ":":
+			m.mode = PaletteMode
+			m.message = ""
+			return m, m.palette.open()
+
+		case "!":
+			m.openShell = true
+			return m, tea.Quit
+
+		case "f":
+			if m.fetching {
+				return m, nil
+			}
+			m.fetching = true
+			m.message = ""
+			repo := m.repo
+			return m, runWithProgress(repo.FetchWithProgress, func(err error) tea.Msg {
+				incoming := -1
+				if err == nil {
+					if commits, inErr := repo.IncomingCommits(); inErr == nil {
+						incoming = len(commits)
+					}
+				}
+				return statusFetchedMsg{err: err, incoming: incoming}
+			})
+
+		case "u", "U":
+			direction := "incoming"
+			if msg.String() == "U" {
+				direction = "outgoing"
+			}
+			return m, m.openUpstreamDiff(direction)
+
+		case "c", "C":
+			direction := "incoming"
+			if msg.String() == "C" {
+				direction = "outgoing"
+			}
+			return m, m.openUpstreamCommits(direction)
+
+		case "y", "Y":
+			name, err := CopyBranchName(m.repo, msg.String() == "Y")
+			if err != nil {
+				m.message = fmt.Sprintf("✗ %v", err)
+				m.messageFailed = true
+				return m, nil
+			}
+			m.message = "✓ Copied " + name
+			m.messageFailed = false
+			return m, nil
+
+		case "b":
+			url, err := RemoteWebURL(m.repo, true)
+			if err == nil {
+				err = OpenURL(url)
+			}
+			switch {
+			case errors.Is(err, ErrNoBrowser):
+				m.message = "No browser available; the branch page is " + url
+			case err != nil:
+				m.message = fmt.Sprintf("✗ %v", err)
+			default:
+				m.message = "✓ Opened " + url
+			}
+			m.messageFailed = err != nil && !errors.Is(err, ErrNoBrowser)
+			return m, nil
+
 		case "r":
-			return m, m.fetchFiles()
+			if m.showIgnored {
+				return m, tea.Batch(m.fetchFiles(), m.scanMarkers(), m.fetchIgnored())
+			}
+			return m, tea.Batch(m.fetchFiles(), m.scanMarkers())
 		}
 	}
 
 	return m, nil
 }
 
+func (m StatusViewerModel) updateDiffViewer(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updatedViewer, viewCmd := m.diffViewer.Update(msg)
+	if dv, ok := updatedViewer.(DiffViewerModel); ok {
+		m.diffViewer = dv
+	}
+	return m, viewCmd
+}
+
+// openUpstreamDiff switches to a full-screen diff against the upstream.
+func (m *StatusViewerModel) openUpstreamDiff(direction string) tea.Cmd {
+	repo := m.repo
+	m.diffViewer = NewContentViewerModel(repo, upstreamDiffTitle(direction), func() (string, error) {
+		return repo.DiffUpstream(direction)
+	})
+	return m.showDetail()
+}
+
+// openUpstreamCommits lists the commits a pull ("incoming") or push
+// ("outgoing") would transfer, as of the last fetch.
+func (m *StatusViewerModel) openUpstreamCommits(direction string) tea.Cmd {
+	repo := m.repo
+	list := repo.IncomingCommits
+	title := "Incoming commits (on upstream, not in HEAD)"
+	if direction == "outgoing" {
+		list = repo.OutgoingCommits
+		title = "Outgoing commits (in HEAD, not on upstream)"
+	}
+	m.diffViewer = NewContentViewerModel(repo, title, func() (string, error) {
+		commits, err := list()
+		if err != nil {
+			return "", err
+		}
+		var lines []string
+		for _, c := range commits {
+			lines = append(lines, fmt.Sprintf("%s %s (%s, %s)", c.ShortHash, c.Subject, c.Author, c.Date))
+		}
+		return strings.Join(lines, "\n"), nil
+	})
+	m.diffViewer.emptyMessage = "Up to date with the upstream."
+	return m.showDetail()
+}
+
+// openDifftool opens path in the user's git difftool, or in the built-in
+// viewer when none is configured.
+func (m *StatusViewerModel) openDifftool(path string, staged bool) tea.Cmd {
+	if m.repo.DifftoolConfigured() {
+		return tea.ExecProcess(m.repo.DifftoolCommand(path, staged), func(err error) tea.Msg {
+			return difftoolClosedMsg{err: err}
+		})
+	}
+	m.showFileDiff(path, staged)
+	return m.showDetail()
+}
+
+// showFileDiff points the built-in viewer at path's staged or unstaged
+// diff. Call showDetail to load it.
+func (m *StatusViewerModel) showFileDiff(path string, staged bool) {
+	m.diffViewer = NewDiffViewerModel(m.repo, path)
+	m.diffViewer.staged = staged
+	m.diffStaged = staged
+	if staged {
+		for _, f := range m.stagedFiles {
+			if f.Path == path {
+				m.diffViewer.origPath = f.OrigPath
+			}
+		}
+	}
+	m.diffViewer.labelSide = m.partiallyStaged(path)
+}
+
+// partiallyStaged reports whether path has both staged and unstaged
+// changes, so its diff viewer can switch between the two.
+func (m StatusViewerModel) partiallyStaged(path string) bool {
+	staged, unstaged := false, false
+	for _, f := range m.stagedFiles {
+		staged = staged || f.Path == path
+	}
+	for _, f := range m.unstagedFiles {
+		unstaged = unstaged || f.Path == path
+	}
+	return staged && unstaged
+}
+
+// showDetail switches to the full-screen diff viewer.
+func (m *StatusViewerModel) showDetail() tea.Cmd {
+	m.mode = DetailMode
+	cmds := []tea.Cmd{m.diffViewer.Init()}
+	if m.width > 0 && m.height > 0 {
+		updatedViewer, sizeCmd := m.diffViewer.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		if dv, ok := updatedViewer.(DiffViewerModel); ok {
+			m.diffViewer = dv
+		}
+		cmds = append(cmds, sizeCmd)
+	}
+	return tea.Batch(cmds...)
+}
+
 func (m StatusViewerModel) View() string {
+	if m.mode == DetailMode {
+		return m.diffViewer.View()
+	}
+	if m.fullHelp {
+		return m.fullHelpView()
+	}
+
 	var sections []string
 
 	if bar := m.statusBar.Render(m.helpStyle); bar != "" {
 		sections = append(sections, bar)
 	}
 
+	tally := m.helpStyle.Render(m.tally())
+	if m.markerCount > 0 {
+		tally += ErrorStyle.Render(fmt.Sprintf("  ⚠ %d conflict marker(s) left in tracked files, see cgit check", m.markerCount))
+	}
+	sections = append(sections, tally)
 	sections = append(sections, "")
 
-	stagedLabel := fmt.Sprintf("  Staged (%d)  ", len(m.stagedFiles))
-	unstagedLabel := fmt.Sprintf("  Unstaged (%d)  ", len(m.unstagedFiles))
-	if m.currentTab == 0 {
-		sections = append(sections, lipgloss.JoinHorizontal(lipgloss.Top,
-			m.activeTabStyle.Render(stagedLabel),
-			m.inactiveTabStyle.Render(unstagedLabel)))
-	} else {
-		sections = append(sections, lipgloss.JoinHorizontal(lipgloss.Top,
-			m.inactiveTabStyle.Render(stagedLabel),
-			m.activeTabStyle.Render(unstagedLabel)))
+	labels := []string{
+		m.tabLabel("Staged", m.stagedFiles),
+		m.tabLabel("Unstaged", m.unstagedFiles),
+		m.tabLabel("Untracked", m.untrackedFiles),
+	}
+	if m.showIgnored {
+		labels = append(labels, m.tabLabel("Ignored", m.ignoredFiles))
+	}
+	var tabs []string
+	for i, label := range labels {
+		if i == m.currentTab {
+			tabs = append(tabs, m.activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, m.inactiveTabStyle.Render(label))
+		}
+	}
+	sections = append(sections, lipgloss.JoinHorizontal(lipgloss.Top, tabs...))
+	if m.statusFilter != "" {
+		sections = append(sections, m.helpStyle.Render(fmt.Sprintf("  Showing %s files only (F0: clear)", statusFilterNames[m.statusFilter])))
 	}
 	sections = append(sections, "")
 
 	files := m.currentFiles()
+	rows := m.currentRows()
 	if len(files) == 0 {
 		sections = append(sections, m.unselectedStyle.Render("  No files"))
 	} else {
 		startIdx := m.scrollOffset
-		endIdx := min(startIdx+m.visibleLines, len(files))
+		endIdx := min(startIdx+m.visibleLines, len(rows))
 		for i := startIdx; i < endIdx; i++ {
-			f := files[i]
+			row := rows[i]
 			prefix := "  "
 			style := m.unselectedStyle
 			if i == m.currentIndex {
 				prefix = "> "
 				style = m.selectedStyle
 			}
+			if row.isDir() {
+				sections = append(sections, style.Render(prefix+treeRowLabel(row, m.collapsed)))
+				continue
+			}
 			statusStyle := m.stagedStyle
-			if m.currentTab == 1 {
+			switch m.currentTab {
+			case unstagedTab, untrackedTab:
 				statusStyle = m.unstagedStyle
+			case ignoredTab:
+				statusStyle = DimStyle
 			}
-			line := fmt.Sprintf("%s%s  %s", prefix, statusStyle.Render(f.Status), f.Path)
+			row.name = renameLabel(files[row.index], row.name)
+			line := fmt.Sprintf("%s%s  %s", prefix, statusStyle.Render(files[row.index].Status), treeRowLabel(row, m.collapsed))
 			sections = append(sections, style.Render(line))
 		}
-		if len(files) > m.visibleLines {
+		if len(rows) > m.visibleLines {
 			sections = append(sections, "")
-			sections = append(sections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(files))))
+			sections = append(sections, m.helpStyle.Render(fmt.Sprintf("(%d-%d of %d)", startIdx+1, endIdx, len(rows))))
+		}
+	}
+
+	if m.fetching {
+		line := "⏳ Fetching..."
+		if m.progress != nil {
+			line = "⏳ " + renderProgress(*m.progress)
 		}
+		sections = append(sections, "")
+		sections = append(sections, m.helpStyle.Render(line))
+	}
+
+	if m.message != "" {
+		style := SuccessStyle
+		if m.messageFailed {
+			style = ErrorStyle
+		}
+		sections = append(sections, "")
+		sections = append(sections, style.Render(m.message))
 	}
 
 	sections = append(sections, "")
-	sections = append(sections, m.helpStyle.Render("Tab: switch  j/k: navigate  m: manage  r: refresh  q: quit"))
+	if m.mode == PaletteMode {
+		sections = append(sections, m.palette.view(m.helpStyle))
+	} else {
+		sections = append(sections, m.helpStyle.Render(m.helpLine()))
+	}
 
 	return strings.Join(sections, "\n")
 }
 
+// statusKeys lists the status viewer's keys for the help line and the full
+// help.
+var statusKeys = []string{
+	"Tab: switch", "j/k: navigate (5j: five)", "g/G: top/bottom", "M/': set/jump to mark",
+	"s/S: stage/unstage file/dir", "t: tree", "o: fold", "F1-3: filter M/A/D (F0: all)", "m: manage",
+	"d: difftool", "h: history", "i: ignored", "u/U: incoming/outgoing diff",
+	"c/C: incoming/outgoing commits", "y/Y: copy branch/upstream", "b: open branch page",
+	"f: fetch", ":: command", "!: shell", "r: refresh", "H: hide/show this line", "?: all keys", "q: quit",
+}
+
+// helpLine is the line of keys under the list, or just a pointer to the
+// full help when the user has hidden it.
+func (m StatusViewerModel) helpLine() string {
+	if !m.showHelp {
+		return "?: keys"
+	}
+	return strings.Join(statusKeys, "  ")
+}
+
+// listHeight is how many file rows fit around the header, the messages
+// and the help line, which takes several rows when it wraps.
+func (m StatusViewerModel) listHeight() int {
+	helpRows := 1
+	if m.width > 0 {
+		helpRows = max((lipgloss.Width(m.helpLine())+m.width-1)/m.width, 1)
+	}
+	return m.height - 8 - helpRows
+}
+
+// fullHelpView lists every key, in as many columns as the height needs.
+func (m StatusViewerModel) fullHelpView() string {
+	perColumn := max(m.height-4, 1)
+	var columns []string
+	for start := 0; start < len(statusKeys); start += perColumn {
+		end := min(start+perColumn, len(statusKeys))
+		columns = append(columns, lipgloss.NewStyle().PaddingRight(4).Render(strings.Join(statusKeys[start:end], "\n")))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.titleStyle.Render("Status viewer keys"),
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Top, columns...),
+		"",
+		m.helpStyle.Render("Press any key to go back"),
+	)
+}
+
+// tally summarises the working tree regardless of the tab or filter shown.
+func (m StatusViewerModel) tally() string {
+	return fmt.Sprintf("staged %d | unstaged %d | untracked %d | stashes %d",
+		len(m.stagedFiles), len(m.unstagedFiles), len(m.untrackedFiles), m.stashCount)
+}
+
 func (m *StatusViewerModel) adjustScrolling() {
 	if m.visibleLines <= 0 {
 		return
 	}
-	files := m.currentFiles()
+	rows := m.currentRows()
 	if m.currentIndex >= m.scrollOffset+m.visibleLines {
 		m.scrollOffset = m.currentIndex - m.visibleLines + 1
 	}
 	if m.currentIndex < m.scrollOffset {
 		m.scrollOffset = m.currentIndex
 	}
-	maxOffset := len(files) - m.visibleLines
+	maxOffset := len(rows) - m.visibleLines
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
@@ -205,16 +1149,39 @@ func (m *StatusViewerModel) adjustScrolling() {
 	}
 }
 
-// StartStatusViewer runs the status TUI, looping back after manage sessions.
+// ErrOpenShell is returned by StartStatusViewer when the user asks to drop
+// into the interactive shell; the caller is expected to start it.
+var ErrOpenShell = errors.New("open shell requested")
+
+// StartStatusViewer runs the status TUI, looping back after manage and
+// file history sessions.
 func StartStatusViewer(repo *git.GitRepo) error {
+	// As in SelectFiles, a credential prompt would fight the TUI for the
+	// terminal, so fetches and pushes from here fail fast instead.
+	noPrompt := repo.NoPrompt
+	repo.NoPrompt = true
+	defer func() { repo.NoPrompt = noPrompt }()
+
+	// Marks last until the user quits, across manage and history sessions.
+	marks := make(map[string]string)
 	for {
 		m := NewStatusViewerModel(repo)
+		m.marks = marks
 		p := tea.NewProgram(m, tea.WithAltScreen())
 		finalModel, err := p.Run()
 		if err != nil {
 			return err
 		}
 		sv, ok := finalModel.(StatusViewerModel)
+		if ok && sv.openShell {
+			return ErrOpenShell
+		}
+		if ok && sv.historyPath != "" {
+			if err := StartFileHistory(repo, sv.historyPath, config.Load().LogLimit); err != nil {
+				return err
+			}
+			continue
+		}
 		if !ok || !sv.launchManage {
 			return nil
 		}
@@ -222,7 +1189,8 @@ func StartStatusViewer(repo *git.GitRepo) error {
 		if err != nil {
 			return err
 		}
-		_, _, err = SelectFiles(repo, repoStatus.StagedFiles, repoStatus.UnstagedFiles, sv.manageStaged)
+		unstaged := append(repoStatus.UnstagedFiles, repoStatus.UntrackedFiles...)
+		_, err = SelectFiles(repo, repoStatus.StagedFiles, unstaged, sv.manageStaged)
 		if err != nil {
 			return err
 		}
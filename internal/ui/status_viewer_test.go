@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/corpeningc/cgit/internal/git"
+)
+
+// newStatusRepo returns a repository with one commit of files, which are
+// then overwritten with changes.
+func newStatusRepo(t *testing.T, files, changes map[string]string) *git.GitRepo {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(files map[string]string) {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.name", "Test")
+	run("config", "user.email", "test@example.com")
+	run("config", "commit.gpgsign", "false")
+	write(files)
+	run("add", "-A")
+	run("commit", "-q", "--allow-empty", "-m", "initial")
+	write(changes)
+	return git.New(dir)
+}
+
+// countGitRuns puts a git on PATH that logs each run before handing over
+// to the real one, and returns a function reporting the runs so far.
+func countGitRuns(t *testing.T) func() int {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the counting git is a shell script")
+	}
+	real, err := exec.LookPath("git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "runs")
+	script := "#!/bin/sh\necho \"$*\" >> '" + log + "'\nexec '" + real + "' \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "git"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return func() int {
+		data, _ := os.ReadFile(log)
+		return strings.Count(string(data), "\n")
+	}
+}
+
+func TestStatusViewerRefreshRunsOneGit(t *testing.T) {
+	repo := newStatusRepo(t, map[string]string{"a.txt": "a\n"}, map[string]string{"a.txt": "changed\n", "b.txt": "b\n"})
+	runs := countGitRuns(t)
+
+	m := NewStatusViewerModel(repo)
+	msg := m.refreshFiles()().(statusFilesLoadedMsg)
+	if msg.err != nil {
+		t.Fatal(msg.err)
+	}
+	// Whatever the viewer asks for once the files are in is part of the
+	// refresh too.
+	if _, cmd := m.Update(msg); cmd != nil {
+		cmd()
+	}
+	if n := runs(); n != 1 {
+		t.Errorf("refresh ran git %d times, want 1", n)
+	}
+	if len(msg.unstaged) != 1 || len(msg.untracked) != 1 {
+		t.Errorf("refresh found %d unstaged and %d untracked files, want 1 and 1", len(msg.unstaged), len(msg.untracked))
+	}
+	if msg.bar.Branch != "main" || msg.bar.Clean {
+		t.Errorf("refresh status bar = %+v, want main with changes", msg.bar)
+	}
+}
+
+func TestStatusViewerRefreshKeepsSelection(t *testing.T) {
+	files := func(paths ...string) []git.FileStatus {
+		var fs []git.FileStatus
+		for _, p := range paths {
+			fs = append(fs, git.FileStatus{Path: p, Status: "M", WorkTree: true})
+		}
+		return fs
+	}
+	tests := []struct {
+		name     string
+		before   []string
+		selected string
+		after    []string
+		want     string
+	}{
+		{"same list", []string{"a", "b", "c"}, "b", []string{"a", "b", "c"}, "b"},
+		{"file added above", []string{"a", "b", "c"}, "b", []string{"0", "a", "b", "c"}, "b"},
+		{"file removed above", []string{"a", "b", "c"}, "c", []string{"b", "c"}, "c"},
+		{"selected file gone", []string{"a", "b", "c"}, "b", []string{"a", "c"}, "c"},
+		{"last file gone", []string{"a", "b", "c"}, "c", []string{"a", "b"}, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var model tea.Model = NewStatusViewerModel(git.New(t.TempDir()))
+			model, _ = model.Update(statusFilesLoadedMsg{unstaged: files(tt.before...), stashes: -1})
+			m := model.(StatusViewerModel)
+			m.currentTab = unstagedTab
+			i, ok := m.rowOf(tt.selected)
+			if !ok {
+				t.Fatalf("%s is not listed", tt.selected)
+			}
+			m.currentIndex = i
+
+			model, _ = m.Update(statusFilesLoadedMsg{unstaged: files(tt.after...), stashes: -1, keepPosition: true})
+			if got := model.(StatusViewerModel).selectedPath(); got != tt.want {
+				t.Errorf("after the refresh %q is selected, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusViewerCounts(t *testing.T) {
+	var paths []string
+	for i := 0; i < 20; i++ {
+		paths = append(paths, fmt.Sprintf("f%02d.txt", i))
+	}
+	tests := []struct {
+		keys string
+		want int
+	}{
+		{"j", 1},
+		{"2j", 2},
+		{"3j", 3},
+		{"10j", 10},
+		{"12G", 11},
+		{"G25k", 0},
+		{"05j", 5},
+		{"G", 19},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			var model tea.Model = NewStatusViewerModel(git.New(t.TempDir()))
+			model, _ = model.Update(tea.WindowSizeMsg{Width: 80, Height: 40})
+			files := make([]git.FileStatus, len(paths))
+			for i, p := range paths {
+				files[i] = git.FileStatus{Path: p, Status: "M", WorkTree: true}
+			}
+			model, _ = model.Update(statusFilesLoadedMsg{unstaged: files, stashes: -1})
+			m := model.(StatusViewerModel)
+			m.currentTab = unstagedTab
+			model = m
+			for _, r := range tt.keys {
+				model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+			m = model.(StatusViewerModel)
+			if m.currentIndex != tt.want || m.statusFilter != "" {
+				t.Errorf("%s: cursor %d, filter %q; want cursor %d and no filter", tt.keys, m.currentIndex, m.statusFilter, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusViewerFilterKeys(t *testing.T) {
+	var model tea.Model = NewStatusViewerModel(git.New(t.TempDir()))
+	press := func(keys string) StatusViewerModel {
+		for _, r := range keys {
+			model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		return model.(StatusViewerModel)
+	}
+	for _, tt := range []struct{ keys, want string }{
+		{"F2", "A"},
+		{"F1", "M"},
+		{"F1", ""},
+		{"F3", "D"},
+		{"Fx", "D"},
+		{"F0", ""},
+	} {
+		if got := press(tt.keys).statusFilter; got != tt.want {
+			t.Errorf("after %s the filter is %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -2,39 +2,88 @@ package ui
 
 import "github.com/charmbracelet/lipgloss"
 
+// Themes selectable from the config file / first-run wizard.
+var Themes = []string{"default", "monochrome"}
+
 // Color palette shared across the UI package. New TUIs should pull
 // from these names rather than reaching for raw color codes.
 var (
-	colorPink     = lipgloss.Color("205")
-	colorPeach    = lipgloss.Color("#F1D3AB")
-	colorGreen    = lipgloss.Color("46")
-	colorRed      = lipgloss.Color("196")
-	colorCyan     = lipgloss.Color("39")
-	colorOrange   = lipgloss.Color("214")
-	colorGray     = lipgloss.Color("245")
-	colorDarkGray = lipgloss.Color("240")
+	colorPink     lipgloss.TerminalColor = lipgloss.Color("205")
+	colorPeach    lipgloss.TerminalColor = lipgloss.Color("#F1D3AB")
+	colorGreen    lipgloss.TerminalColor = lipgloss.Color("46")
+	colorRed      lipgloss.TerminalColor = lipgloss.Color("196")
+	colorCyan     lipgloss.TerminalColor = lipgloss.Color("39")
+	colorOrange   lipgloss.TerminalColor = lipgloss.Color("214")
+	colorGray     lipgloss.TerminalColor = lipgloss.Color("245")
+	colorDarkGray lipgloss.TerminalColor = lipgloss.Color("240")
 )
 
 // Common reusable styles. Two "title" variants exist because the
 // codebase historically used pink in some TUIs and peach in others;
 // unifying the palette is a future visual decision, not a dedup.
 var (
-	TitlePinkStyle  = lipgloss.NewStyle().Foreground(colorPink).Bold(true)
+	TitlePinkStyle  lipgloss.Style
+	TitlePeachStyle lipgloss.Style
+
+	SelectedPinkStyle  lipgloss.Style
+	SelectedPeachStyle lipgloss.Style
+
+	UnselectedStyle     lipgloss.Style
+	UnselectedBoldStyle lipgloss.Style
+	HelpStyle           lipgloss.Style
+	DimStyle            lipgloss.Style
+	SeparatorStyle      lipgloss.Style
+
+	SuccessStyle lipgloss.Style
+	ErrorStyle   lipgloss.Style
+	SearchStyle  lipgloss.Style
+
+	StagedStyle   lipgloss.Style
+	UnstagedStyle lipgloss.Style
+)
+
+func init() {
+	buildStyles()
+}
+
+// ApplyTheme swaps the palette for the named theme and rebuilds the shared
+// styles. Unknown names fall back to the default palette. It must be called
+// before any TUI model is constructed, since models copy styles on creation.
+func ApplyTheme(name string) {
+	if name == "monochrome" {
+		none := lipgloss.NoColor{}
+		colorPink, colorPeach, colorGreen, colorRed = none, none, none, none
+		colorCyan, colorOrange, colorGray, colorDarkGray = none, none, none, none
+	} else {
+		colorPink = lipgloss.Color("205")
+		colorPeach = lipgloss.Color("#F1D3AB")
+		colorGreen = lipgloss.Color("46")
+		colorRed = lipgloss.Color("196")
+		colorCyan = lipgloss.Color("39")
+		colorOrange = lipgloss.Color("214")
+		colorGray = lipgloss.Color("245")
+		colorDarkGray = lipgloss.Color("240")
+	}
+	buildStyles()
+}
+
+func buildStyles() {
+	TitlePinkStyle = lipgloss.NewStyle().Foreground(colorPink).Bold(true)
 	TitlePeachStyle = lipgloss.NewStyle().Foreground(colorPeach).Bold(true)
 
-	SelectedPinkStyle  = lipgloss.NewStyle().Foreground(colorPink).Bold(true)
+	SelectedPinkStyle = lipgloss.NewStyle().Foreground(colorPink).Bold(true)
 	SelectedPeachStyle = lipgloss.NewStyle().Foreground(colorPeach).Bold(true)
 
-	UnselectedStyle     = lipgloss.NewStyle().Foreground(colorGray)
+	UnselectedStyle = lipgloss.NewStyle().Foreground(colorGray)
 	UnselectedBoldStyle = lipgloss.NewStyle().Foreground(colorGray).Bold(true)
-	HelpStyle           = lipgloss.NewStyle().Foreground(colorGray)
-	DimStyle            = lipgloss.NewStyle().Foreground(colorDarkGray)
-	SeparatorStyle      = lipgloss.NewStyle().Foreground(colorDarkGray)
+	HelpStyle = lipgloss.NewStyle().Foreground(colorGray)
+	DimStyle = lipgloss.NewStyle().Foreground(colorDarkGray)
+	SeparatorStyle = lipgloss.NewStyle().Foreground(colorDarkGray)
 
 	SuccessStyle = lipgloss.NewStyle().Foreground(colorGreen).Bold(true)
-	ErrorStyle   = lipgloss.NewStyle().Foreground(colorRed).Bold(true)
-	SearchStyle  = lipgloss.NewStyle().Foreground(colorCyan).Bold(true)
+	ErrorStyle = lipgloss.NewStyle().Foreground(colorRed).Bold(true)
+	SearchStyle = lipgloss.NewStyle().Foreground(colorCyan).Bold(true)
 
-	StagedStyle   = lipgloss.NewStyle().Foreground(colorGreen)
+	StagedStyle = lipgloss.NewStyle().Foreground(colorGreen)
 	UnstagedStyle = lipgloss.NewStyle().Foreground(colorOrange)
-)
+}
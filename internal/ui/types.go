@@ -14,6 +14,7 @@ type GitOperationCompleteMsg struct {
 type StatusRefreshMsg struct {
 	stagedFiles   []git.FileStatus
 	unstagedFiles []git.FileStatus
+	bar           StatusBar // read by the same `git status` as the files
 	error         error
 }
 
@@ -27,4 +28,6 @@ const (
 	DiffMode
 	DetailMode
 	CommitMode
+	SearchResultsMode
+	PaletteMode
 )
@@ -1,16 +1,22 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/corpeningc/cgit/cmd"
+	"github.com/muesli/termenv"
 )
 
 func main() {
+	// Windows consoles only act on the escape sequences cgit prints
+	// (colours, clearing the screen) with virtual terminal processing
+	// turned on. Elsewhere this does nothing.
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		termenv.EnableVirtualTerminalProcessing(termenv.NewOutput(f))
+	}
+
+	// Execute has already reported the error.
 	if err := cmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
-